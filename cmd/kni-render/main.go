@@ -0,0 +1,119 @@
+// kni-render prints the objects the operator would manage for a KNICluster,
+// without talking to a cluster.
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	kniv1alpha1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1alpha1"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+func main() {
+	var filename, version, namespace string
+	pflag.StringVarP(&filename, "filename", "f", "", "KNICluster manifest to render, or - for stdin")
+	pflag.StringVar(&version, "cluster-version", "", "Cluster version to render the objects for")
+	pflag.StringVarP(&namespace, "namespace", "n", "", "Namespace of the KNICluster, if the manifest does not set one")
+	pflag.Parse()
+
+	if filename == "" || version == "" {
+		fmt.Fprintln(os.Stderr, "--filename and --cluster-version are required")
+		pflag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(filename, version, namespace); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(filename, version, namespace string) error {
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(filename)
+	}
+	if err != nil {
+		return err
+	}
+
+	instance, err := decode(data)
+	if err != nil {
+		return fmt.Errorf("failed to read KNICluster from %s: %v", filename, err)
+	}
+	if instance.Namespace == "" {
+		instance.Namespace = namespace
+	}
+
+	objects, err := render.Objects(instance, version, nil)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		// status is not applied, and unset fields only add noise to a review
+		delete(content, "status")
+		pruneNulls(content)
+
+		out, err := yaml.Marshal(content)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("---\n%s", out)
+	}
+	return nil
+}
+
+// pruneNulls removes null values from content, along with maps that only
+// contained null values
+func pruneNulls(content map[string]interface{}) {
+	for key, value := range content {
+		switch v := value.(type) {
+		case nil:
+			delete(content, key)
+		case map[string]interface{}:
+			pruneNulls(v)
+			if len(v) == 0 {
+				delete(content, key)
+			}
+		}
+	}
+}
+
+// decode returns the hub version of a KNICluster manifest in any served
+// version
+func decode(data []byte) (*kniv1beta1.KNICluster, error) {
+	typeMeta := metav1.TypeMeta{}
+	if err := yaml.Unmarshal(data, &typeMeta); err != nil {
+		return nil, err
+	}
+	if typeMeta.Kind != "KNICluster" {
+		return nil, fmt.Errorf("expected kind KNICluster, found %q", typeMeta.Kind)
+	}
+
+	instance := &kniv1beta1.KNICluster{}
+	switch typeMeta.APIVersion {
+	case kniv1beta1.SchemeGroupVersion.String():
+		return instance, yaml.UnmarshalStrict(data, instance)
+	case kniv1alpha1.SchemeGroupVersion.String():
+		alpha := &kniv1alpha1.KNICluster{}
+		if err := yaml.UnmarshalStrict(data, alpha); err != nil {
+			return nil, err
+		}
+		return instance, alpha.ConvertTo(instance)
+	}
+	return nil, fmt.Errorf("unsupported apiVersion %q", typeMeta.APIVersion)
+}
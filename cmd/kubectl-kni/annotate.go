@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// annotate sets annotation on the KNICluster to value, or removes it when
+// value is empty
+func annotate(c client.Client, kni types.NamespacedName, annotation, value string) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		instance := &kniv1beta1.KNICluster{}
+		if err := c.Get(context.TODO(), kni, instance); err != nil {
+			return err
+		}
+		if value == "" {
+			delete(instance.Annotations, annotation)
+		} else {
+			if instance.Annotations == nil {
+				instance.Annotations = map[string]string{}
+			}
+			instance.Annotations[annotation] = value
+		}
+		return c.Update(context.TODO(), instance)
+	})
+	if err != nil {
+		return err
+	}
+
+	if value == "" {
+		fmt.Printf("Removed %s from KNICluster %s\n", annotation, kni)
+	} else {
+		fmt.Printf("Set %s=%s on KNICluster %s\n", annotation, value, kni)
+	}
+	return nil
+}
+
+// forceReconcile makes the operator do a full resync of the KNICluster
+func forceReconcile(c client.Client, kni types.NamespacedName) error {
+	return annotate(c, kni, kniv1beta1.ReconcileAtAnnotation, time.Now().UTC().Format(time.RFC3339))
+}
+
+// approve approves switching the catalog to version, or to the version of
+// the pending upgrade if version is empty
+func approve(c client.Client, kni types.NamespacedName, version string) error {
+	if version == "" {
+		instance := &kniv1beta1.KNICluster{}
+		if err := c.Get(context.TODO(), kni, instance); err != nil {
+			return err
+		}
+		if instance.Status.PendingUpgrade == nil {
+			return fmt.Errorf("KNICluster %s has no upgrade waiting for approval", kni)
+		}
+		version = instance.Status.PendingUpgrade.Version
+	}
+	return annotate(c, kni, kniv1beta1.ApprovedVersionAnnotation, version)
+}
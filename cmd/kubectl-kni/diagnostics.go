@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mhrivnak/kni-operator/pkg/diagnostics"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+// collectDiagnostics writes a diagnostics bundle for the KNICluster to a
+// timestamped tarball in dir
+func collectDiagnostics(cfg *rest.Config, scheme *runtime.Scheme, kni types.NamespacedName, dir string, tailLines int64) error {
+	collector, err := diagnostics.NewCollector(cfg, scheme)
+	if err != nil {
+		return err
+	}
+	collector.TailLines = tailLines
+
+	at := time.Now()
+	filename := filepath.Join(dir, diagnostics.BundleName(at)+".tar.gz")
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = collector.Collect(context.TODO(), kni, at, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filename)
+		return err
+	}
+
+	fmt.Printf("Wrote %s\n", filename)
+	return nil
+}
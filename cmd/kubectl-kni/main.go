@@ -0,0 +1,120 @@
+// kubectl-kni is a kubectl plugin for inspecting and controlling the
+// KNICluster. Install it on the PATH and run "kubectl kni".
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/diagnostics"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const usage = `Inspect and control the KNICluster.
+
+Usage:
+  kubectl kni [status]      Show managed operators, catalog, conditions and upgrade history
+  kubectl kni pause         Pause reconciliation
+  kubectl kni resume        Resume reconciliation
+  kubectl kni reconcile     Force a full resync
+  kubectl kni approve       Approve the pending catalog upgrade, or the one given by --version
+  kubectl kni diagnostics   Collect a diagnostics bundle into the output directory
+
+Flags:
+`
+
+func main() {
+	kni := types.NamespacedName{Name: kniv1beta1.KNIClusterNameDefault}
+	pflag.StringVarP(&kni.Namespace, "namespace", "n", "", "Namespace of the KNICluster")
+	pflag.StringVar(&kni.Name, "name", kni.Name, "Name of the KNICluster")
+	outputDir := pflag.StringP("output-dir", "o", ".", "Directory the diagnostics bundle is written to")
+	version := pflag.String("version", "", "Cluster version to approve the catalog upgrade to")
+	tailLines := pflag.Int64("tail", diagnostics.TailLinesDefault, "Number of log lines collected per container in the diagnostics bundle")
+	// adds --kubeconfig
+	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
+	pflag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		pflag.PrintDefaults()
+	}
+	pflag.Parse()
+
+	command := "status"
+	switch pflag.NArg() {
+	case 0:
+	case 1:
+		command = pflag.Arg(0)
+	default:
+		pflag.Usage()
+		os.Exit(2)
+	}
+
+	if kni.Namespace == "" {
+		fmt.Fprintln(os.Stderr, "--namespace is required")
+		os.Exit(2)
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	scheme, err := newScheme()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	switch command {
+	case "status":
+		err = printStatus(os.Stdout, c, kni)
+	case "pause":
+		err = annotate(c, kni, kniv1beta1.PausedAnnotation, "true")
+	case "resume":
+		err = annotate(c, kni, kniv1beta1.PausedAnnotation, "")
+	case "reconcile":
+		err = forceReconcile(c, kni)
+	case "approve":
+		err = approve(c, kni, *version)
+	case "diagnostics":
+		err = collectDiagnostics(cfg, scheme, kni, *outputDir, *tailLines)
+	default:
+		pflag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func newScheme() (*runtime.Scheme, error) {
+	scheme := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		kniv1beta1.SchemeBuilder.AddToScheme,
+		olm.AddToScheme,
+		osconfigv1.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			return nil, err
+		}
+	}
+	return scheme, nil
+}
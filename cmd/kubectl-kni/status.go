@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// none is shown in place of missing values
+const none = "<none>"
+
+// printStatus writes a summary of the KNICluster and the OLM objects it
+// manages
+func printStatus(out io.Writer, c client.Client, kni types.NamespacedName) error {
+	ctx := context.TODO()
+	instance := &kniv1beta1.KNICluster{}
+	if err := c.Get(ctx, kni, instance); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "KNICluster %s/%s", instance.Namespace, instance.Name)
+	if instance.Annotations[kniv1beta1.PausedAnnotation] == "true" {
+		fmt.Fprint(out, " (paused)")
+	}
+	if instance.Spec.Mode == kniv1beta1.ModePlan {
+		fmt.Fprint(out, " (plan mode)")
+	}
+	fmt.Fprint(out, "\n\n")
+
+	if err := printCatalog(out, c, instance); err != nil {
+		return err
+	}
+	fmt.Fprintln(out)
+	if err := printOperators(out, c, instance); err != nil {
+		return err
+	}
+	fmt.Fprintln(out)
+	printOperatorGroups(out, instance)
+	fmt.Fprintln(out)
+	printConditions(out, instance)
+	fmt.Fprintln(out)
+	printUpgradeHistory(out, instance)
+	fmt.Fprintln(out)
+	printUpgradePreview(out, instance)
+	return nil
+}
+
+func printCatalog(out io.Writer, c client.Client, instance *kniv1beta1.KNICluster) error {
+	ctx := context.TODO()
+	catalog := instance.Spec.Catalog
+
+	image, catalogVersion := none, none
+	found := &olm.CatalogSource{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: catalog.Namespace, Name: catalog.Name}, found)
+	switch {
+	case err == nil:
+		image = orNone(found.Spec.Image)
+		catalogVersion = orNone(found.Labels[kniv1beta1.CatalogVersionLabel])
+	case !errors.IsNotFound(err):
+		return err
+	}
+
+	version := none
+	expected := none
+	cvs := &osconfigv1.ClusterVersionList{}
+	if err := c.List(ctx, &client.ListOptions{}, cvs); err != nil {
+		return err
+	}
+	if len(cvs.Items) == 1 && cvs.Items[0].Spec.DesiredUpdate != nil {
+		version = cvs.Items[0].Spec.DesiredUpdate.Version
+		expected = orNone(render.CatalogSource(catalog, version).Spec.Image)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "Catalog:\t%s/%s (%s)\n", catalog.Namespace, catalog.Name, catalog.Type)
+	fmt.Fprintf(w, "Catalog image:\t%s\n", image)
+	fmt.Fprintf(w, "Catalog version:\t%s\n", catalogVersion)
+	fmt.Fprintf(w, "Cluster version:\t%s\n", version)
+	if pending := instance.Status.PendingUpgrade; pending != nil {
+		fmt.Fprintf(w, "Pending upgrade:\t%s (waiting for approval since %s)\n", pending.Version,
+			pending.RequestedTime.UTC().Format("2006-01-02T15:04:05Z"))
+	}
+	switch {
+	case expected != none && expected != image:
+		fmt.Fprintf(w, "Expected image:\t%s (catalog is behind the cluster version)\n", expected)
+	case version != none && catalogVersion != none && catalogVersion != version:
+		fmt.Fprintf(w, "Expected version:\t%s (catalog is behind the cluster version)\n", version)
+	}
+	return w.Flush()
+}
+
+func printOperators(out io.Writer, c client.Client, instance *kniv1beta1.KNICluster) error {
+	ctx := context.TODO()
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "OPERATOR\tNAMESPACE\tPACKAGE\tCHANNEL\tSTATE\tINSTALLED CSV\tCURRENT CSV\tPHASE\tINSTALL PLAN")
+	for _, operator := range instance.Spec.Operators {
+		state, installed, current, phase, installPlan := none, none, none, none, none
+		namespace := render.OperatorNamespace(instance, operator)
+
+		subscription := &olm.Subscription{}
+		err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: operator.Name}, subscription)
+		switch {
+		case errors.IsNotFound(err):
+			state = "Missing"
+		case err != nil:
+			return err
+		default:
+			state = orNone(string(subscription.Status.State))
+			installed = orNone(subscription.Status.InstalledCSV)
+			current = orNone(subscription.Status.CurrentCSV)
+		}
+
+		if installed != none {
+			csv := &olm.ClusterServiceVersion{}
+			err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: installed}, csv)
+			switch {
+			case err == nil:
+				phase = orNone(string(csv.Status.Phase))
+			case !errors.IsNotFound(err):
+				return err
+			}
+		}
+
+		if ref := subscription.Status.InstallPlanRef; ref != nil {
+			plan := &olm.InstallPlan{}
+			err = c.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, plan)
+			switch {
+			case err == nil:
+				installPlan = fmt.Sprintf("%s (%s)", plan.Name, plan.Status.Phase)
+			case errors.IsNotFound(err):
+				installPlan = ref.Name
+			default:
+				return err
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", operator.Name, namespace, operator.Package, operator.Channel,
+			state, installed, current, phase, installPlan)
+	}
+	return w.Flush()
+}
+
+func printOperatorGroups(out io.Writer, instance *kniv1beta1.KNICluster) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "OPERATOR GROUP\tTARGET NAMESPACES\tINCOMPATIBLE")
+	for _, operatorGroup := range instance.Status.OperatorGroups {
+		targets := "<pending>"
+		if len(operatorGroup.TargetNamespaces) > 0 {
+			var names []string
+			for _, namespace := range operatorGroup.TargetNamespaces {
+				if namespace == "" {
+					namespace = "<all>"
+				}
+				names = append(names, namespace)
+			}
+			targets = strings.Join(names, ", ")
+		}
+		var incompatible []string
+		for _, operator := range operatorGroup.Incompatible {
+			incompatible = append(incompatible, fmt.Sprintf("%s (%s)", operator.Operator, operator.Message))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", operatorGroup.Namespace, targets, orNone(strings.Join(incompatible, "; ")))
+	}
+	w.Flush()
+}
+
+func printConditions(out io.Writer, instance *kniv1beta1.KNICluster) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CONDITION\tSTATUS\tREASON\tMESSAGE")
+	for _, condition := range instance.Status.Conditions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", condition.Type, condition.Status, orNone(condition.Reason),
+			strings.Replace(condition.Message, "\n", " ", -1))
+	}
+	w.Flush()
+}
+
+func printUpgradeHistory(out io.Writer, instance *kniv1beta1.KNICluster) {
+	if len(instance.Status.UpgradeHistory) == 0 {
+		fmt.Fprintln(out, "No upgrade history")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tCATALOG IMAGE\tCOMPLETED\tHOOKS")
+	for i := len(instance.Status.UpgradeHistory) - 1; i >= 0; i-- {
+		record := instance.Status.UpgradeHistory[i]
+		version := record.Version
+		if record.RolledBack {
+			version += " (rolled back)"
+		}
+		var hooks []string
+		for _, hook := range record.Hooks {
+			hooks = append(hooks, fmt.Sprintf("%s=%s", hook.Name, hook.Result))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", version, orNone(record.CatalogImage), record.CompletionTime.UTC().Format("2006-01-02T15:04:05Z"),
+			orNone(strings.Join(hooks, ", ")))
+	}
+	w.Flush()
+}
+
+func printUpgradePreview(out io.Writer, instance *kniv1beta1.KNICluster) {
+	if len(instance.Status.UpgradePreview) == 0 {
+		fmt.Fprintln(out, "No available updates")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "AVAILABLE UPDATE\tSTATE\tOPERATOR\tCURRENT CSV\tTARGET CSV")
+	for _, preview := range instance.Status.UpgradePreview {
+		if len(preview.Operators) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", preview.Version, preview.State, none, none, none)
+		}
+		for _, operator := range preview.Operators {
+			target := operator.TargetCSV
+			if !operator.Changed {
+				target += " (unchanged)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", preview.Version, preview.State, operator.Name, orNone(operator.CurrentCSV), target)
+		}
+		if preview.Message != "" {
+			fmt.Fprintf(w, "%s\t%s\t%s\t\t\n", preview.Version, preview.State, strings.Replace(preview.Message, "\n", " ", -1))
+		}
+	}
+	w.Flush()
+
+	if prePull := instance.Status.PrePull; prePull != nil {
+		fmt.Fprintf(out, "\nPre-pull: %s, %d/%d nodes (%s)\n", prePull.State, prePull.NodesReady, prePull.NodesDesired,
+			strings.Join(prePull.Images, ", "))
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return none
+	}
+	return s
+}
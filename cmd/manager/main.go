@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -13,19 +14,25 @@ import (
 	"github.com/mhrivnak/kni-operator/pkg/apis"
 	"github.com/mhrivnak/kni-operator/pkg/controller"
 	"github.com/mhrivnak/kni-operator/pkg/controller/knicluster"
+	"github.com/mhrivnak/kni-operator/pkg/webhook"
 
-	kniv1alpha1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1alpha1"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
 	osconfigv1 "github.com/openshift/api/config/v1"
 	olmv1 "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1"
 	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	"github.com/operator-framework/operator-sdk/pkg/leader"
 	"github.com/operator-framework/operator-sdk/pkg/log/zap"
 	"github.com/operator-framework/operator-sdk/pkg/metrics"
 	"github.com/operator-framework/operator-sdk/pkg/restmapper"
 	sdkVersion "github.com/operator-framework/operator-sdk/version"
 	"github.com/spf13/pflag"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
@@ -39,6 +46,16 @@ var (
 )
 var log = logf.Log.WithName("cmd")
 
+// Defaults for serving the admission webhooks. The first four can be
+// overridden with flags.
+var (
+	enableWebhooks           = true
+	webhookPort        int32 = 9443
+	webhookCertDir           = "/tmp/k8s-webhook-server/serving-certs"
+	webhookCertSecret        = "kni-operator-webhook-cert"
+	webhookServiceName       = "kni-operator-webhook"
+)
+
 func printVersion() {
 	log.Info(fmt.Sprintf("Go Version: %s", runtime.Version()))
 	log.Info(fmt.Sprintf("Go OS/Arch: %s/%s", runtime.GOOS, runtime.GOARCH))
@@ -54,6 +71,11 @@ func main() {
 	// controller-runtime)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
+	pflag.BoolVar(&enableWebhooks, "enable-webhooks", enableWebhooks, "Serve the admission webhooks for KNICluster")
+	pflag.Int32Var(&webhookPort, "webhook-port", webhookPort, "Port the admission webhooks are served on")
+	pflag.StringVar(&webhookCertDir, "webhook-cert-dir", webhookCertDir, "Directory containing the webhook serving certificate")
+	pflag.StringVar(&webhookCertSecret, "webhook-cert-secret", webhookCertSecret, "Secret keeping the self-signed webhook serving certificate when none is mounted")
+
 	pflag.Parse()
 
 	// Use a zap logr.Logger implementation. If none of the zap
@@ -68,10 +90,10 @@ func main() {
 
 	printVersion()
 
-	// Check for namespace in env
-	kniNamespacedName, err := knicluster.GetKNINamespacedName()
+	// Check for a KNICluster to create in env
+	kniNamespacedName, createDefault, err := knicluster.GetDefaultKNINamespacedName()
 	if err != nil {
-		log.Error(fmt.Errorf("%s must be defined and not empty", knicluster.KNIClusterNamespaceEnv), "")
+		log.Error(fmt.Errorf("%s must be defined and not empty when %s is set", knicluster.KNIClusterNamespaceEnv, knicluster.KNIClusterNameEnv), "")
 		os.Exit(1)
 	}
 
@@ -127,24 +149,76 @@ func main() {
 		os.Exit(1)
 	}
 
+	err = apiextensionsv1beta1.AddToScheme(mgr.GetScheme())
+	if err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	// Setup all Controllers
 	if err := controller.AddToManager(mgr); err != nil {
 		log.Error(err, "")
 		os.Exit(1)
 	}
 
+	// Setup all Webhooks
+	if enableWebhooks {
+		operatorNamespace, err := k8sutil.GetOperatorNamespace()
+		if err != nil {
+			log.Error(err, "The webhook Service namespace is unknown")
+			os.Exit(1)
+		}
+		err = webhook.AddToManager(mgr, webhook.ServerOptions{
+			Port:              webhookPort,
+			CertDir:           webhookCertDir,
+			CertSecretName:    webhookCertSecret,
+			ServiceName:       webhookServiceName,
+			ServiceNamespace:  operatorNamespace,
+			ConfigurationName: "kni-operator",
+		})
+		if err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
 	// Create Service object to expose the metrics port.
 	_, err = metrics.ExposeMetricsPort(ctx, metricsPort)
 	if err != nil {
 		log.Info(err.Error())
 	}
 
-	// Create CR if it's not there
-	client := mgr.GetClient()
-	err = client.Create(context.TODO(), &kniv1alpha1.KNICluster{
+	// Create CR if it's not there and one is requested. This happens once the
+	// manager has started, because creating it requires the validating
+	// webhook to be served.
+	if createDefault {
+		err = mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+			return wait.PollImmediateUntil(5*time.Second, func() (bool, error) {
+				return createKNICluster(mgr.GetClient(), kniNamespacedName), nil
+			}, stop)
+		}))
+		if err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
+	log.Info("Starting the Cmd.")
+
+	// Start the Cmd
+	if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
+		log.Error(err, "Manager exited non-zero")
+		os.Exit(1)
+	}
+}
+
+// createKNICluster creates the KNICluster resource and reports whether it
+// exists afterward
+func createKNICluster(c client.Client, kni types.NamespacedName) bool {
+	err := c.Create(context.TODO(), &kniv1beta1.KNICluster{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      kniNamespacedName.Name,
-			Namespace: kniNamespacedName.Namespace,
+			Name:      kni.Name,
+			Namespace: kni.Namespace,
 		},
 	})
 	switch {
@@ -153,15 +227,8 @@ func main() {
 	case errors.IsAlreadyExists(err):
 		log.Info("KNICluster resource already exists")
 	default:
-		log.Error(err, "Failed to create KNICluster resource")
-		os.Exit(1)
-	}
-
-	log.Info("Starting the Cmd.")
-
-	// Start the Cmd
-	if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
-		log.Error(err, "Manager exited non-zero")
-		os.Exit(1)
+		log.Error(err, "Failed to create KNICluster resource, will retry")
+		return false
 	}
+	return true
 }
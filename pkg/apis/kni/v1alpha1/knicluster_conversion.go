@@ -0,0 +1,131 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+)
+
+// v1beta1Annotation stores the spec fields of a v1beta1 KNICluster that
+// cannot be represented in v1alpha1, so that converting to v1alpha1 and back
+// keeps the spec. The status is not stored: it is only written through
+// v1beta1, and a v1alpha1 update cannot change it.
+const v1beta1Annotation = "kni.openshift.com/v1beta1"
+
+// v1beta1Fields is the content of the v1beta1Annotation
+type v1beta1Fields struct {
+	Spec v1beta1.KNIClusterSpec `json:"spec"`
+}
+
+// ConvertTo converts this KNICluster to the hub version
+func (src *KNICluster) ConvertTo(dst *v1beta1.KNICluster) error {
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.SetGroupVersionKind(v1beta1.SchemeGroupVersion.WithKind("KNICluster"))
+
+	// start from the fields that v1alpha1 cannot represent, if any
+	if data, ok := dst.Annotations[v1beta1Annotation]; ok {
+		fields := v1beta1Fields{}
+		if err := json.Unmarshal([]byte(data), &fields); err != nil {
+			return err
+		}
+		dst.Spec = fields.Spec
+		delete(dst.Annotations, v1beta1Annotation)
+		if len(dst.Annotations) == 0 {
+			dst.Annotations = nil
+		}
+	}
+
+	convertSpecTo(&src.Spec, &dst.Spec)
+	dst.Status.Conditions = src.Status.Conditions
+	dst.Status.RelatedObjects = src.Status.RelatedObjects
+	return nil
+}
+
+// ConvertFrom converts the hub version to this KNICluster
+func (dst *KNICluster) ConvertFrom(src *v1beta1.KNICluster) error {
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.SetGroupVersionKind(SchemeGroupVersion.WithKind("KNICluster"))
+
+	dst.Spec = KNIClusterSpec{
+		Catalog: CatalogSpec{
+			Name:            src.Spec.Catalog.Name,
+			Namespace:       src.Spec.Catalog.Namespace,
+			ImageRepository: src.Spec.Catalog.Image.Repository,
+		},
+	}
+	for _, operator := range src.Spec.Operators {
+		dst.Spec.Operators = append(dst.Spec.Operators, OperatorSpec{
+			Name:     operator.Name,
+			Package:  operator.Package,
+			Channel:  operator.Channel,
+			Catalog:  operator.Catalog,
+			Operands: operator.Operands,
+		})
+	}
+	dst.Status = KNIClusterStatus{
+		Conditions:     src.Status.Conditions,
+		RelatedObjects: src.Status.RelatedObjects,
+	}
+
+	// preserve the spec fields that would get lost when converting back
+	roundTrip := &v1beta1.KNICluster{}
+	convertSpecTo(&dst.Spec, &roundTrip.Spec)
+	if !reflect.DeepEqual(roundTrip.Spec, src.Spec) {
+		data, err := json.Marshal(v1beta1Fields{Spec: v1beta1OnlySpec(&src.Spec)})
+		if err != nil {
+			return err
+		}
+		if dst.Annotations == nil {
+			dst.Annotations = map[string]string{}
+		}
+		dst.Annotations[v1beta1Annotation] = string(data)
+	}
+	return nil
+}
+
+// v1beta1OnlySpec returns spec without the fields that v1alpha1 represents.
+// Operators keep their name, by which convertSpecTo matches them, and are
+// left out if they have nothing else.
+func v1beta1OnlySpec(spec *v1beta1.KNIClusterSpec) v1beta1.KNIClusterSpec {
+	only := *spec.DeepCopy()
+	only.Catalog.Name = ""
+	only.Catalog.Namespace = ""
+	only.Catalog.Image.Repository = ""
+	only.Operators = nil
+	for _, operator := range spec.Operators {
+		extra := *operator.DeepCopy()
+		extra.Package = ""
+		extra.Channel = ""
+		extra.Catalog = ""
+		extra.Operands = nil
+		if !reflect.DeepEqual(extra, v1beta1.OperatorSpec{Name: operator.Name}) {
+			only.Operators = append(only.Operators, extra)
+		}
+	}
+	return only
+}
+
+// convertSpecTo sets the fields of dst that have a v1alpha1 equivalent,
+// leaving the others untouched. Operators are matched by name.
+func convertSpecTo(src *KNIClusterSpec, dst *v1beta1.KNIClusterSpec) {
+	dst.Catalog.Name = src.Catalog.Name
+	dst.Catalog.Namespace = src.Catalog.Namespace
+	dst.Catalog.Image.Repository = src.Catalog.ImageRepository
+
+	existing := map[string]v1beta1.OperatorSpec{}
+	for _, operator := range dst.Operators {
+		existing[operator.Name] = operator
+	}
+	var operators []v1beta1.OperatorSpec
+	for _, operator := range src.Operators {
+		converted := existing[operator.Name]
+		converted.Name = operator.Name
+		converted.Package = operator.Package
+		converted.Channel = operator.Channel
+		converted.Catalog = operator.Catalog
+		converted.Operands = operator.Operands
+		operators = append(operators, converted)
+	}
+	dst.Operators = operators
+}
@@ -0,0 +1,176 @@
+package v1alpha1
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
+	"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var testOperand = runtime.RawExtension{Raw: []byte(`{"apiVersion": "etcd.database.coreos.com/v1beta2", "kind": "EtcdCluster", "metadata": {"name": "example"}}`)}
+
+// newTestHub returns a v1beta1 KNICluster with fields that v1alpha1 cannot
+// represent
+func newTestHub() *v1beta1.KNICluster {
+	return &v1beta1.KNICluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "example-knicluster",
+			Namespace:   "kniops",
+			Annotations: map[string]string{"example.com/note": "kept"},
+		},
+		Spec: v1beta1.KNIClusterSpec{
+			Mode: v1beta1.ModePlan,
+			Catalog: v1beta1.CatalogSpec{
+				Name:      "demo-catalog",
+				Namespace: "olm",
+				Image: v1beta1.CatalogImageSpec{
+					Repository: "quay.io/mhrivnak/demo-operator-registry",
+					PinDigest:  true,
+				},
+			},
+			Operators: []v1beta1.OperatorSpec{
+				{
+					Name:      "etcd",
+					Namespace: "kni-operators",
+					Package:   "etcd",
+					Channel:   "singlenamespace-alpha",
+					Catalog:   "demo-catalog",
+					Operands:  []runtime.RawExtension{testOperand},
+				},
+				{Name: "prometheus", Package: "prometheus", Channel: "preview", Catalog: "demo-catalog"},
+			},
+		},
+		Status: v1beta1.KNIClusterStatus{
+			Conditions: []conditionsv1.Condition{
+				{Type: conditionsv1.ConditionAvailable, Status: corev1.ConditionTrue, Reason: "ReconcileCompleted"},
+			},
+			RelatedObjects: []corev1.ObjectReference{{Kind: "CatalogSource", Namespace: "olm", Name: "demo-catalog"}},
+			CatalogImage:   "quay.io/mhrivnak/demo-operator-registry:4.1.0",
+		},
+	}
+}
+
+func TestConvertRoundTripFromHub(t *testing.T) {
+	hub := newTestHub()
+
+	spoke := &KNICluster{}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if spoke.Spec.Catalog.ImageRepository != hub.Spec.Catalog.Image.Repository {
+		t.Errorf("image repository is %q", spoke.Spec.Catalog.ImageRepository)
+	}
+	if !reflect.DeepEqual(spoke.Status.Conditions, hub.Status.Conditions) {
+		t.Errorf("conditions are %v", spoke.Status.Conditions)
+	}
+
+	converted := &v1beta1.KNICluster{}
+	if err := spoke.ConvertTo(converted); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(converted.Spec, hub.Spec) {
+		t.Errorf("spec after a round trip is\n%+v\nwant\n%+v", converted.Spec, hub.Spec)
+	}
+	if !reflect.DeepEqual(converted.Annotations, hub.Annotations) {
+		t.Errorf("annotations after a round trip are %v, want %v", converted.Annotations, hub.Annotations)
+	}
+	if !reflect.DeepEqual(converted.Status.Conditions, hub.Status.Conditions) || !reflect.DeepEqual(converted.Status.RelatedObjects, hub.Status.RelatedObjects) {
+		t.Errorf("status after a round trip is %+v", converted.Status)
+	}
+}
+
+func TestConvertAnnotationHoldsOnlyV1beta1Fields(t *testing.T) {
+	spoke := &KNICluster{}
+	if err := spoke.ConvertFrom(newTestHub()); err != nil {
+		t.Fatal(err)
+	}
+	annotation, ok := spoke.Annotations[v1beta1Annotation]
+	if !ok {
+		t.Fatal("no annotation for the v1beta1-only fields")
+	}
+	for _, represented := range []string{"status", "catalogImage", "quay.io/mhrivnak/demo-operator-registry", "singlenamespace-alpha", "EtcdCluster", "prometheus"} {
+		if strings.Contains(annotation, represented) {
+			t.Errorf("annotation %s holds %q, which v1alpha1 represents", annotation, represented)
+		}
+	}
+	for _, extra := range []string{`"mode":"Plan"`, `"pinDigest":true`, `"namespace":"kni-operators"`} {
+		if !strings.Contains(annotation, extra) {
+			t.Errorf("annotation %s does not hold %s", annotation, extra)
+		}
+	}
+}
+
+func TestConvertWithoutV1beta1Fields(t *testing.T) {
+	hub := &v1beta1.KNICluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "example-knicluster", Namespace: "kniops"},
+		Spec: v1beta1.KNIClusterSpec{
+			Catalog:   v1beta1.CatalogSpec{Name: "demo-catalog", Namespace: "olm"},
+			Operators: []v1beta1.OperatorSpec{{Name: "etcd", Package: "etcd", Channel: "alpha", Catalog: "demo-catalog"}},
+		},
+	}
+	spoke := &KNICluster{}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if spoke.Annotations != nil {
+		t.Errorf("annotations are %v, want none", spoke.Annotations)
+	}
+}
+
+func TestConvertSpokeUpdate(t *testing.T) {
+	spoke := &KNICluster{}
+	if err := spoke.ConvertFrom(newTestHub()); err != nil {
+		t.Fatal(err)
+	}
+
+	// a v1alpha1 client changes a channel, drops an operator and adds one
+	spoke.Spec.Operators = []OperatorSpec{
+		{Name: "etcd", Package: "etcd", Channel: "clusterwide-alpha", Catalog: "demo-catalog"},
+		{Name: "vault", Package: "vault", Channel: "alpha", Catalog: "demo-catalog"},
+	}
+
+	converted := &v1beta1.KNICluster{}
+	if err := spoke.ConvertTo(converted); err != nil {
+		t.Fatal(err)
+	}
+	want := []v1beta1.OperatorSpec{
+		// v1beta1-only fields are kept for operators that remain
+		{Name: "etcd", Namespace: "kni-operators", Package: "etcd", Channel: "clusterwide-alpha", Catalog: "demo-catalog"},
+		{Name: "vault", Package: "vault", Channel: "alpha", Catalog: "demo-catalog"},
+	}
+	if !reflect.DeepEqual(converted.Spec.Operators, want) {
+		t.Errorf("operators are\n%+v\nwant\n%+v", converted.Spec.Operators, want)
+	}
+	if converted.Spec.Mode != v1beta1.ModePlan || !converted.Spec.Catalog.Image.PinDigest {
+		t.Errorf("v1beta1-only fields were lost: %+v", converted.Spec)
+	}
+}
+
+func TestConvertRoundTripFromSpoke(t *testing.T) {
+	spoke := &KNICluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "example-knicluster", Namespace: "kniops"},
+		Spec: KNIClusterSpec{
+			Catalog: CatalogSpec{Name: "demo-catalog", Namespace: "olm", ImageRepository: "quay.io/mhrivnak/demo-operator-registry"},
+			Operators: []OperatorSpec{
+				{Name: "etcd", Package: "etcd", Channel: "alpha", Catalog: "demo-catalog", Operands: []runtime.RawExtension{testOperand}},
+			},
+		},
+	}
+
+	hub := &v1beta1.KNICluster{}
+	if err := spoke.ConvertTo(hub); err != nil {
+		t.Fatal(err)
+	}
+	converted := &KNICluster{}
+	if err := converted.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(converted.Spec, spoke.Spec) || converted.Annotations != nil {
+		t.Errorf("round trip gave %+v with annotations %v, want %+v", converted.Spec, converted.Annotations, spoke.Spec)
+	}
+}
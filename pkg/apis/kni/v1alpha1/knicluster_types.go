@@ -4,14 +4,68 @@ import (
 	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// ConditionCatalogReady indicates whether the CatalogSource is in place
+	ConditionCatalogReady conditionsv1.ConditionType = "CatalogReady"
+	// ConditionOperatorGroupReady indicates whether the OperatorGroup is in place
+	ConditionOperatorGroupReady conditionsv1.ConditionType = "OperatorGroupReady"
+	// ConditionSubscriptionsReady indicates whether all Subscriptions are in place
+	ConditionSubscriptionsReady conditionsv1.ConditionType = "SubscriptionsReady"
+	// ConditionOperandsReady indicates whether all operand objects are in place
+	ConditionOperandsReady conditionsv1.ConditionType = "OperandsReady"
 )
 
 // KNIClusterSpec defines the desired state of KNICluster
 // +k8s:openapi-gen=true
 type KNIClusterSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book.kubebuilder.io/beyond_basics/generating_crd.html
+
+	// Catalog describes the CatalogSource that operators are installed from
+	// +optional
+	Catalog CatalogSpec `json:"catalog,omitempty"`
+
+	// Operators is the list of operators to subscribe to. When empty, a default
+	// set of operators is used.
+	// +optional
+	Operators []OperatorSpec `json:"operators,omitempty"`
+}
+
+// CatalogSpec describes the CatalogSource managed for a KNICluster
+// +k8s:openapi-gen=true
+type CatalogSpec struct {
+	// Name of the CatalogSource
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Namespace of the CatalogSource. It cannot be changed after creation.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// ImageRepository is the operator-registry image repository. The image tag
+	// is the version of the cluster.
+	// +optional
+	ImageRepository string `json:"imageRepository,omitempty"`
+}
+
+// OperatorSpec describes one operator to install from the KNI catalog
+// +k8s:openapi-gen=true
+type OperatorSpec struct {
+	// Name of the operator, which is also used as the name of its Subscription
+	Name string `json:"name"`
+	// Package is the name of the package in the catalog
+	Package string `json:"package"`
+	// Channel is the catalog channel to subscribe to
+	Channel string `json:"channel"`
+	// Catalog is the name of the catalog providing the package. It must match
+	// the name of the KNICluster's catalog.
+	// +optional
+	Catalog string `json:"catalog,omitempty"`
+	// Operands is a list of objects, usually custom resources, that get
+	// created once the operator is available so that it deploys its operand.
+	// +optional
+	Operands []runtime.RawExtension `json:"operands,omitempty"`
 }
 
 // KNIClusterStatus defines the observed state of KNICluster
@@ -19,12 +73,11 @@ type KNIClusterSpec struct {
 type KNIClusterStatus struct {
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book.kubebuilder.io/beyond_basics/generating_crd.html
-	// conditions describes the state of the operator's reconciliation functionality.
+
+	// Conditions is a list of conditions related to operator reconciliation
 	// +patchMergeKey=type
 	// +patchStrategy=merge
 	// +optional
-
-	// Conditions is a list of conditions related to operator reconciliation
 	Conditions []conditionsv1.Condition `json:"conditions,omitempty"  patchStrategy:"merge" patchMergeKey:"type"`
 	// RelatedObjects is a list of objects that are "interesting" or related to this operator.
 	RelatedObjects []corev1.ObjectReference `json:"relatedObjects,omitempty"`
@@ -13,9 +13,45 @@ import (
 
 func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
 	return map[string]common.OpenAPIDefinition{
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1alpha1.CatalogSpec":      schema_pkg_apis_kni_v1alpha1_CatalogSpec(ref),
 		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1alpha1.KNICluster":       schema_pkg_apis_kni_v1alpha1_KNICluster(ref),
 		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1alpha1.KNIClusterSpec":   schema_pkg_apis_kni_v1alpha1_KNIClusterSpec(ref),
 		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1alpha1.KNIClusterStatus": schema_pkg_apis_kni_v1alpha1_KNIClusterStatus(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1alpha1.OperatorSpec":     schema_pkg_apis_kni_v1alpha1_OperatorSpec(ref),
+	}
+}
+
+func schema_pkg_apis_kni_v1alpha1_CatalogSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "CatalogSpec describes the CatalogSource managed for a KNICluster",
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the CatalogSource",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"namespace": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespace of the CatalogSource. It cannot be changed after creation.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"imageRepository": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ImageRepository is the operator-registry image repository. The image tag is the version of the cluster.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{},
 	}
 }
 
@@ -67,10 +103,31 @@ func schema_pkg_apis_kni_v1alpha1_KNIClusterSpec(ref common.ReferenceCallback) c
 		Schema: spec.Schema{
 			SchemaProps: spec.SchemaProps{
 				Description: "KNIClusterSpec defines the desired state of KNICluster",
-				Properties:  map[string]spec.Schema{},
+				Properties: map[string]spec.Schema{
+					"catalog": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Catalog describes the CatalogSource that operators are installed from",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1alpha1.CatalogSpec"),
+						},
+					},
+					"operators": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Operators is the list of operators to subscribe to. When empty, a default set of operators is used.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1alpha1.OperatorSpec"),
+									},
+								},
+							},
+						},
+					},
+				},
 			},
 		},
-		Dependencies: []string{},
+		Dependencies: []string{
+			"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1alpha1.CatalogSpec", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1alpha1.OperatorSpec"},
 	}
 }
 
@@ -79,9 +136,99 @@ func schema_pkg_apis_kni_v1alpha1_KNIClusterStatus(ref common.ReferenceCallback)
 		Schema: spec.Schema{
 			SchemaProps: spec.SchemaProps{
 				Description: "KNIClusterStatus defines the observed state of KNICluster",
-				Properties:  map[string]spec.Schema{},
+				Properties: map[string]spec.Schema{
+					"conditions": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-patch-merge-key": "type",
+								"x-kubernetes-patch-strategy":  "merge",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Description: "Conditions is a list of conditions related to operator reconciliation",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/djzager/custom-resource-status/conditions/v1.Condition"),
+									},
+								},
+							},
+						},
+					},
+					"relatedObjects": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RelatedObjects is a list of objects that are \"interesting\" or related to this operator.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/api/core/v1.ObjectReference"),
+									},
+								},
+							},
+						},
+					},
+				},
 			},
 		},
-		Dependencies: []string{},
+		Dependencies: []string{
+			"github.com/djzager/custom-resource-status/conditions/v1.Condition", "k8s.io/api/core/v1.ObjectReference"},
+	}
+}
+
+func schema_pkg_apis_kni_v1alpha1_OperatorSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "OperatorSpec describes one operator to install from the KNI catalog",
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the operator, which is also used as the name of its Subscription",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"package": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Package is the name of the package in the catalog",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"channel": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Channel is the catalog channel to subscribe to",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"catalog": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Catalog is the name of the catalog providing the package. It must match the name of the KNICluster's catalog.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"operands": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Operands is a list of objects, usually custom resources, that get created once the operator is available so that it deploys its operand.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/apimachinery/pkg/runtime.RawExtension"),
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"name", "package", "channel"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/runtime.RawExtension"},
 	}
 }
@@ -0,0 +1,5 @@
+package v1beta1
+
+// Hub marks v1beta1 as the version that all other versions of KNICluster
+// convert to and from.
+func (*KNICluster) Hub() {}
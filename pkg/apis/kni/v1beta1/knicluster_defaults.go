@@ -0,0 +1,100 @@
+package v1beta1
+
+const (
+	// KNIClusterNameDefault is the name of the KNICluster the operator creates
+	// in deploy/operator.yaml, and the one kubectl-kni uses by default
+	KNIClusterNameDefault = "kni-cluster"
+	// CatalogNameDefault is the name of the CatalogSource
+	CatalogNameDefault = "demo-catalog"
+	// CatalogNamespaceDefault is the namespace of the CatalogSource
+	CatalogNamespaceDefault = "olm"
+	// ImageRepositoryDefault is the catalog image repository
+	ImageRepositoryDefault = "quay.io/mhrivnak/demo-operator-registry"
+	// CatalogDirectoryDefault is where the operator image ships the manifests
+	// of ConfigMap catalogs
+	CatalogDirectoryDefault = "/usr/share/kni-operator/manifests"
+	// ChannelDefault is the channel of the default operators
+	ChannelDefault = "singlenamespace-alpha"
+)
+
+// DefaultOperators returns the operators installed when a KNICluster does not
+// list any
+func DefaultOperators() []OperatorSpec {
+	return []OperatorSpec{
+		{
+			Name:    "kni",
+			Package: "etcd",
+			Channel: ChannelDefault,
+		},
+	}
+}
+
+// SetDefaults fills in omitted spec fields with their defaults. Defaults are
+// stored when a KNICluster is created, so that changing them in a later
+// release does not change the behavior of existing clusters.
+func (k *KNICluster) SetDefaults() {
+	catalog := &k.Spec.Catalog
+	if catalog.Name == "" {
+		catalog.Name = CatalogNameDefault
+	}
+	if catalog.Namespace == "" {
+		catalog.Namespace = CatalogNamespaceDefault
+	}
+	if catalog.Type == "" {
+		catalog.Type = CatalogTypeImage
+	}
+	switch catalog.Type {
+	case CatalogTypeImage:
+		if catalog.Image.Repository == "" {
+			catalog.Image.Repository = ImageRepositoryDefault
+		}
+	case CatalogTypeConfigMap:
+		if catalog.ConfigMap.Directory == "" {
+			catalog.ConfigMap.Directory = CatalogDirectoryDefault
+		}
+	}
+
+	if len(k.Spec.Operators) == 0 {
+		k.Spec.Operators = DefaultOperators()
+	}
+	for i := range k.Spec.Operators {
+		if k.Spec.Operators[i].Catalog == "" {
+			k.Spec.Operators[i].Catalog = catalog.Name
+		}
+	}
+
+	if k.Spec.NamespaceDeletionPolicy == "" {
+		k.Spec.NamespaceDeletionPolicy = NamespaceDeletionRetain
+	}
+
+	if k.Spec.Adoption == nil {
+		k.Spec.Adoption = &AdoptionSpec{}
+	}
+	for _, policy := range []*AdoptionPolicy{
+		&k.Spec.Adoption.Subscriptions,
+		&k.Spec.Adoption.OperatorGroups,
+		&k.Spec.Adoption.CatalogSources,
+	} {
+		if *policy == "" {
+			*policy = AdoptionFail
+		}
+	}
+
+	if k.Spec.Mode == "" {
+		k.Spec.Mode = ModeApply
+	}
+
+	if k.Spec.Approval == "" {
+		k.Spec.Approval = ApprovalAutomatic
+	}
+
+	for i := range k.Spec.Notifications {
+		if k.Spec.Notifications[i].Format == "" {
+			k.Spec.Notifications[i].Format = NotificationFormatWebhook
+		}
+	}
+
+	if k.Spec.Hooks != nil && k.Spec.Hooks.PostSwitchFailurePolicy == "" {
+		k.Spec.Hooks.PostSwitchFailurePolicy = HookFailureDegrade
+	}
+}
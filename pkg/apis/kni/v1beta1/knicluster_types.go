@@ -0,0 +1,1028 @@
+package v1beta1
+
+import (
+	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// ConditionNamespacesReady indicates whether the managed namespaces are in place
+	ConditionNamespacesReady conditionsv1.ConditionType = "NamespacesReady"
+	// ConditionCatalogReady indicates whether the CatalogSource is in place
+	ConditionCatalogReady conditionsv1.ConditionType = "CatalogReady"
+	// ConditionOperatorGroupReady indicates whether the OperatorGroup is in place
+	ConditionOperatorGroupReady conditionsv1.ConditionType = "OperatorGroupReady"
+	// ConditionSubscriptionsReady indicates whether all Subscriptions are in place
+	ConditionSubscriptionsReady conditionsv1.ConditionType = "SubscriptionsReady"
+	// ConditionInstallPlansApproved indicates whether the InstallPlans waiting for approval were decided on
+	ConditionInstallPlansApproved conditionsv1.ConditionType = "InstallPlansApproved"
+	// ConditionOperandsReady indicates whether all operand objects are in place
+	ConditionOperandsReady conditionsv1.ConditionType = "OperandsReady"
+	// ConditionHooksSucceeded indicates whether the post-switch hooks of the latest catalog switch succeeded
+	ConditionHooksSucceeded conditionsv1.ConditionType = "HooksSucceeded"
+	// ConditionRolledBack indicates whether the catalog is rolled back because a post-switch hook failed
+	ConditionRolledBack conditionsv1.ConditionType = "RolledBack"
+	// ConditionConflict indicates whether objects the operator would manage
+	// exist but cannot be taken over
+	ConditionConflict conditionsv1.ConditionType = "Conflict"
+	// ConditionPaused indicates whether reconciliation is paused
+	ConditionPaused conditionsv1.ConditionType = "Paused"
+)
+
+const (
+	// PausedAnnotation pauses reconciliation when set to "true". While paused,
+	// the operator makes no changes to the cluster but keeps the status up to
+	// date.
+	PausedAnnotation = "kni.openshift.com/paused"
+	// ReconcileAtAnnotation forces a full resync whenever its value, usually
+	// a timestamp, changes.
+	ReconcileAtAnnotation = "kni.openshift.com/reconcile-at"
+	// CatalogVersionLabel is set on the CatalogSource, and the ConfigMap of a
+	// ConfigMap catalog, to the cluster version they serve
+	CatalogVersionLabel = "kni.openshift.com/catalog-version"
+	// CollectDiagnosticsAnnotation makes the operator collect a diagnostics
+	// bundle whenever its value, usually a timestamp, changes
+	CollectDiagnosticsAnnotation = "kni.openshift.com/collect-diagnostics"
+	// ApprovedVersionAnnotation approves switching the catalog to the cluster
+	// version it names, like spec.approvedVersion
+	ApprovedVersionAnnotation = "kni.openshift.com/approved-version"
+	// OwnerNameLabel and OwnerNamespaceLabel identify the KNICluster that
+	// manages an object which cannot have an owner reference to it, because
+	// it is cluster-scoped or in another namespace
+	OwnerNameLabel      = "kni.openshift.com/owner-name"
+	OwnerNamespaceLabel = "kni.openshift.com/owner-namespace"
+	// TemporaryCatalogLabel marks the CatalogSources that only serve a
+	// catalog image while the operator queries it, with the value staging or
+	// preview. They are not meant to install operators from.
+	TemporaryCatalogLabel = "kni.openshift.com/temporary-catalog"
+)
+
+// AdoptionPolicy determines what happens to an existing object the operator
+// would manage, but did not create
+type AdoptionPolicy string
+
+const (
+	// AdoptionAdopt takes over objects that match the spec, and reports a
+	// conflict for the others
+	AdoptionAdopt AdoptionPolicy = "Adopt"
+	// AdoptionIgnore leaves the objects alone
+	AdoptionIgnore AdoptionPolicy = "Ignore"
+	// AdoptionFail reports a conflict for every such object
+	AdoptionFail AdoptionPolicy = "Fail"
+)
+
+// NamespaceDeletionPolicy determines what happens to the namespaces the
+// operator created once they are no longer managed
+type NamespaceDeletionPolicy string
+
+const (
+	// NamespaceDeletionRetain keeps the namespaces, and stops managing them
+	NamespaceDeletionRetain NamespaceDeletionPolicy = "Retain"
+	// NamespaceDeletionDelete deletes the namespaces along with everything
+	// in them
+	NamespaceDeletionDelete NamespaceDeletionPolicy = "Delete"
+)
+
+// Approval determines whether catalog version changes need sign-off
+type Approval string
+
+const (
+	// ApprovalAutomatic switches the catalog as soon as the cluster version
+	// changes
+	ApprovalAutomatic Approval = "Automatic"
+	// ApprovalManual switches the catalog to a new cluster version only once
+	// the version is approved
+	ApprovalManual Approval = "Manual"
+)
+
+// Mode determines whether the operator changes the cluster
+type Mode string
+
+const (
+	// ModeApply makes the cluster match the spec
+	ModeApply Mode = "Apply"
+	// ModePlan only reports the changes needed to make the cluster match the
+	// spec in status.plan
+	ModePlan Mode = "Plan"
+)
+
+// Actions of a PlannedChange
+const (
+	PlanActionCreate = "Create"
+	PlanActionUpdate = "Update"
+	PlanActionDelete = "Delete"
+)
+
+// KNIClusterSpec defines the desired state of KNICluster
+// +k8s:openapi-gen=true
+type KNIClusterSpec struct {
+	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
+	// Add custom validation using kubebuilder tags: https://book.kubebuilder.io/beyond_basics/generating_crd.html
+
+	// Catalog describes the CatalogSource that operators are installed from
+	Catalog CatalogSpec `json:"catalog"`
+
+	// Operators is the list of operators to subscribe to
+	Operators []OperatorSpec `json:"operators"`
+
+	// Namespaces are created by the operator, with the given labels and
+	// annotations, so that operators can be installed in them
+	// +optional
+	Namespaces []NamespaceSpec `json:"namespaces,omitempty"`
+
+	// NamespaceDeletionPolicy is Retain or Delete, and applies to the
+	// namespaces the operator created when they are removed from Namespaces
+	// or the KNICluster is deleted. Defaults to Retain.
+	// +optional
+	NamespaceDeletionPolicy NamespaceDeletionPolicy `json:"namespaceDeletionPolicy,omitempty"`
+
+	// Adoption determines what happens to existing objects the operator would
+	// manage, but did not create
+	// +optional
+	Adoption *AdoptionSpec `json:"adoption,omitempty"`
+
+	// OperatorGroups configure the namespaces targeted by the OperatorGroups
+	// of the namespaces operators are installed in. OperatorGroups without
+	// an entry target only their own namespace.
+	// +optional
+	OperatorGroups []OperatorGroupSpec `json:"operatorGroups,omitempty"`
+
+	// ImageMirrors rewrite the catalog image and operand image references to
+	// pull from mirrors, for disconnected clusters
+	// +optional
+	ImageMirrors []ImageMirror `json:"imageMirrors,omitempty"`
+
+	// UseImageContentSourcePolicies adds the mirrors of the cluster's
+	// ImageContentSourcePolicies to ImageMirrors. Mirrors in ImageMirrors take
+	// precedence.
+	// +optional
+	UseImageContentSourcePolicies bool `json:"useImageContentSourcePolicies,omitempty"`
+
+	// Mode is Apply to make changes to the cluster, or Plan to only report
+	// them in status.plan. Defaults to Apply.
+	// +optional
+	Mode Mode `json:"mode,omitempty"`
+
+	// Approval is Automatic, or Manual to wait for each new catalog version
+	// to be approved through ApprovedVersion or the
+	// kni.openshift.com/approved-version annotation. Defaults to Automatic.
+	// +optional
+	Approval Approval `json:"approval,omitempty"`
+
+	// ApprovedVersion is the cluster version the catalog may be switched to
+	// when Approval is Manual. Approvals of other versions are ignored.
+	// +optional
+	ApprovedVersion string `json:"approvedVersion,omitempty"`
+
+	// Policy is an external HTTP endpoint that authorizes catalog switches
+	// and, optionally, InstallPlan approvals
+	// +optional
+	Policy *PolicySpec `json:"policy,omitempty"`
+
+	// Notifications send lifecycle events to chat or webhook endpoints
+	// +optional
+	Notifications []NotificationSpec `json:"notifications,omitempty"`
+
+	// Hooks are Jobs run around each switch of the catalog to a new cluster
+	// version
+	// +optional
+	Hooks *HooksSpec `json:"hooks,omitempty"`
+}
+
+// NotificationFormat is the payload format of a notification endpoint
+type NotificationFormat string
+
+const (
+	// NotificationFormatWebhook posts a generic JSON event
+	NotificationFormatWebhook NotificationFormat = "Webhook"
+	// NotificationFormatSlack posts a Slack-compatible message
+	NotificationFormatSlack NotificationFormat = "Slack"
+)
+
+// NotificationEvent is a lifecycle event of a KNICluster
+type NotificationEvent string
+
+const (
+	// EventUpgradeStarted is sent when the catalog starts serving a new
+	// version
+	EventUpgradeStarted NotificationEvent = "UpgradeStarted"
+	// EventUpgradeCompleted is sent when an upgrade is added to the history
+	EventUpgradeCompleted NotificationEvent = "UpgradeCompleted"
+	// EventRolledBack is sent when the catalog is rolled back
+	EventRolledBack NotificationEvent = "RolledBack"
+	// EventDegraded is sent when the Degraded condition becomes True
+	EventDegraded NotificationEvent = "Degraded"
+)
+
+// NotificationSpec describes an endpoint notified of lifecycle events
+// +k8s:openapi-gen=true
+type NotificationSpec struct {
+	// Name of the notification, unique in the KNICluster
+	Name string `json:"name"`
+	// Format is Webhook or Slack. Defaults to Webhook.
+	// +optional
+	Format NotificationFormat `json:"format,omitempty"`
+	// URLSecret is the name of a Secret, in the KNICluster's namespace, whose
+	// "url" key is the URL notifications are posted to
+	URLSecret string `json:"urlSecret"`
+	// Events to send. All events are sent when it is empty.
+	// +optional
+	Events []NotificationEvent `json:"events,omitempty"`
+}
+
+// PolicySpec describes an HTTP decision endpoint. The operator POSTs a JSON
+// description of each proposed change, and the endpoint answers with a
+// decision of allow, deny or defer and a reason.
+// +k8s:openapi-gen=true
+type PolicySpec struct {
+	// URL of the decision endpoint
+	URL string `json:"url"`
+	// CABundle is the PEM-encoded CA bundle that verifies the endpoint's
+	// certificate. The system roots are used when it is empty.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+	// AuthSecret is the name of a Secret, in the KNICluster's namespace, with
+	// either a "token" key, sent as a bearer token, or "username" and
+	// "password" keys, sent as basic authentication
+	// +optional
+	AuthSecret string `json:"authSecret,omitempty"`
+	// Timeout limits each call to the endpoint. Defaults to 10s.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// DecisionTTL is how long an allow or deny decision is kept before the
+	// endpoint is asked about the same change again. Defaults to 1h.
+	// +optional
+	DecisionTTL *metav1.Duration `json:"decisionTTL,omitempty"`
+	// InstallPlans makes the Subscriptions require InstallPlan approval, and
+	// asks the endpoint before approving each InstallPlan
+	// +optional
+	InstallPlans bool `json:"installPlans,omitempty"`
+}
+
+// PolicyDecision is the answer of a policy endpoint
+type PolicyDecision string
+
+const (
+	// PolicyAllow lets the change proceed
+	PolicyAllow PolicyDecision = "allow"
+	// PolicyDeny refuses the change
+	PolicyDeny PolicyDecision = "deny"
+	// PolicyDefer postpones the decision. The endpoint is asked again later.
+	PolicyDefer PolicyDecision = "defer"
+)
+
+// PolicyAction is a kind of change a policy endpoint decides on
+type PolicyAction string
+
+const (
+	// PolicyActionCatalogSwitch is a switch of the catalog to a new version
+	PolicyActionCatalogSwitch PolicyAction = "CatalogSwitch"
+	// PolicyActionInstallPlanApproval is the approval of an InstallPlan
+	PolicyActionInstallPlanApproval PolicyAction = "InstallPlanApproval"
+)
+
+// NamespaceSpec describes a namespace managed by the operator. Labels and
+// annotations are added to those the namespace already has.
+// +k8s:openapi-gen=true
+type NamespaceSpec struct {
+	// Name of the namespace
+	Name string `json:"name"`
+	// Labels to set on the namespace
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations to set on the namespace
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// AdoptionSpec sets the adoption policy of each kind. Each defaults to Fail,
+// so that existing objects are only taken over when asked for.
+// +k8s:openapi-gen=true
+type AdoptionSpec struct {
+	// Subscriptions are adopted when they subscribe to the package of the
+	// operator
+	// +optional
+	Subscriptions AdoptionPolicy `json:"subscriptions,omitempty"`
+	// OperatorGroups are adopted when they have the name the operator uses
+	// +optional
+	OperatorGroups AdoptionPolicy `json:"operatorGroups,omitempty"`
+	// CatalogSources are adopted when they have the source type of the
+	// catalog
+	// +optional
+	CatalogSources AdoptionPolicy `json:"catalogSources,omitempty"`
+}
+
+// OperatorGroupSpec configures the OperatorGroup of a namespace. At most one
+// of AllNamespaces, TargetNamespaces and Selector may be set. Without any of
+// them, the OperatorGroup targets its own namespace.
+// +k8s:openapi-gen=true
+type OperatorGroupSpec struct {
+	// Namespace of the OperatorGroup
+	Namespace string `json:"namespace"`
+	// AllNamespaces targets every namespace of the cluster
+	// +optional
+	AllNamespaces bool `json:"allNamespaces,omitempty"`
+	// TargetNamespaces is an explicit list of namespaces to target
+	// +optional
+	TargetNamespaces []string `json:"targetNamespaces,omitempty"`
+	// Selector targets the namespaces with matching labels
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// HookFailurePolicy is what happens when a post-switch hook fails
+type HookFailurePolicy string
+
+const (
+	// HookFailureDegrade reports the failure in the Degraded condition
+	HookFailureDegrade HookFailurePolicy = "Degrade"
+	// HookFailureRollback switches the catalog back to the previous version,
+	// and reports the failure in the Degraded condition
+	HookFailureRollback HookFailurePolicy = "Rollback"
+)
+
+// HooksSpec lists the Jobs run around each catalog switch
+// +k8s:openapi-gen=true
+type HooksSpec struct {
+	// PreSwitch hooks must all succeed before the catalog is switched to a new
+	// version
+	// +optional
+	PreSwitch []HookSpec `json:"preSwitch,omitempty"`
+	// PostSwitch hooks run once the CSV of every managed operator has
+	// succeeded after a switch
+	// +optional
+	PostSwitch []HookSpec `json:"postSwitch,omitempty"`
+	// PostSwitchFailurePolicy is Degrade or Rollback. Defaults to Degrade.
+	// +optional
+	PostSwitchFailurePolicy HookFailurePolicy `json:"postSwitchFailurePolicy,omitempty"`
+}
+
+// HookSpec describes a hook Job. The Job runs in the KNICluster's namespace
+// with the environment variables KNI_HOOK_PHASE, KNI_CATALOG_VERSION,
+// KNI_CATALOG_IMAGE and KNI_PREVIOUS_VERSION.
+// +k8s:openapi-gen=true
+type HookSpec struct {
+	// Name of the hook, unique among the hooks of its phase
+	Name string `json:"name"`
+	// Image the hook runs
+	Image string `json:"image"`
+	// Command overrides the entrypoint of the image
+	// +optional
+	Command []string `json:"command,omitempty"`
+	// Args are the arguments of the command
+	// +optional
+	Args []string `json:"args,omitempty"`
+	// ServiceAccountName is the service account the hook runs as
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// BackoffLimit is the number of retries before the hook fails
+	// +optional
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+	// ActiveDeadlineSeconds limits how long the hook runs
+	// +optional
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+}
+
+// CatalogSpec describes the CatalogSource managed for a KNICluster
+// +k8s:openapi-gen=true
+type CatalogSpec struct {
+	// Name of the CatalogSource
+	Name string `json:"name"`
+	// Namespace of the CatalogSource. It cannot be changed after creation.
+	Namespace string `json:"namespace"`
+	// Type is Image to serve the catalog from an operator-registry image, or
+	// ConfigMap to build it from manifests on the operator's filesystem, for
+	// clusters that cannot pull the image. Defaults to Image.
+	// +optional
+	Type CatalogType `json:"type,omitempty"`
+	// Image describes the operator-registry image of the catalog
+	// +optional
+	Image CatalogImageSpec `json:"image,omitempty"`
+	// ConfigMap describes where the manifests of a ConfigMap catalog are found
+	// +optional
+	ConfigMap CatalogConfigMapSpec `json:"configMap,omitempty"`
+}
+
+// CatalogType is the kind of catalog managed for a KNICluster
+type CatalogType string
+
+const (
+	// CatalogTypeImage serves the catalog from an operator-registry image
+	CatalogTypeImage CatalogType = "Image"
+	// CatalogTypeConfigMap serves the catalog from a ConfigMap built from
+	// manifests
+	CatalogTypeConfigMap CatalogType = "ConfigMap"
+)
+
+// CatalogImageSpec describes how the catalog image is chosen
+// +k8s:openapi-gen=true
+type CatalogImageSpec struct {
+	// Repository is the operator-registry image repository. The image tag is
+	// the version of the cluster.
+	Repository string `json:"repository"`
+	// PinDigest resolves the image tag to a digest through the registry, and
+	// points the CatalogSource at the digest, so that a repushed tag cannot
+	// change the catalog unnoticed
+	// +optional
+	PinDigest bool `json:"pinDigest,omitempty"`
+	// ResolveInterval is how often a pinned tag is resolved again. When the
+	// tag has moved, the catalog is updated to the new digest. Without it, a
+	// tag is resolved once.
+	// +optional
+	ResolveInterval *metav1.Duration `json:"resolveInterval,omitempty"`
+	// InsecureRegistry allows resolving against a registry that serves plain
+	// HTTP or an unverifiable certificate, such as a local one
+	// +optional
+	InsecureRegistry bool `json:"insecureRegistry,omitempty"`
+	// PullSecret is the name of a Secret in the KNICluster's namespace, of
+	// type kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg, with
+	// credentials for the registry. Its credentials take precedence over
+	// those of the cluster's global pull secret.
+	// +optional
+	PullSecret string `json:"pullSecret,omitempty"`
+	// SkipContentValidation switches the CatalogSource to a new image without
+	// first checking that the catalog has every managed package and channel.
+	// Validation serves the new image from a staging CatalogSource and queries
+	// it through the operator-registry API, which requires the operator to
+	// reach services in the catalog namespace.
+	// +optional
+	SkipContentValidation bool `json:"skipContentValidation,omitempty"`
+	// PrePull pulls the catalog images of the updates available to the
+	// cluster onto every node ahead of time, so that switching the catalog
+	// does not wait for the pull
+	// +optional
+	PrePull bool `json:"prePull,omitempty"`
+	// PrePullNodeSelector limits pre-pulling to the nodes with these labels.
+	// Images are pulled onto every schedulable node when it is empty.
+	// +optional
+	PrePullNodeSelector map[string]string `json:"prePullNodeSelector,omitempty"`
+	// PrePullTimeout is how long pre-pulling may take before it is given up.
+	// Defaults to 30m.
+	// +optional
+	PrePullTimeout *metav1.Duration `json:"prePullTimeout,omitempty"`
+	// Verification requires a valid signature before the CatalogSource is
+	// switched to a new image. It implies PinDigest, so that the
+	// CatalogSource runs the digest that was verified.
+	// +optional
+	Verification *SignatureVerificationSpec `json:"verification,omitempty"`
+}
+
+// SignatureVerificationSpec describes how catalog image signatures are
+// verified. Signatures are cosign-style: stored in the image's repository
+// under a tag derived from the image digest, and signing a simple signing
+// payload that names the digest.
+// +k8s:openapi-gen=true
+type SignatureVerificationSpec struct {
+	// PublicKeysSecret is the name of a Secret, in the KNICluster's
+	// namespace, whose values are PEM-encoded ECDSA or RSA public keys. A
+	// signature by any of them is accepted.
+	PublicKeysSecret string `json:"publicKeysSecret"`
+}
+
+// CatalogConfigMapSpec describes where the manifests of a ConfigMap catalog
+// are found
+// +k8s:openapi-gen=true
+type CatalogConfigMapSpec struct {
+	// Directory on the operator's filesystem, baked into its image or mounted
+	// from a volume, that holds a subdirectory of manifests for each cluster
+	// version. Each subdirectory contains the CSV, CRD and package manifests
+	// of the catalog.
+	// +optional
+	Directory string `json:"directory,omitempty"`
+}
+
+// ImageMirror maps images from a source to a mirror
+// +k8s:openapi-gen=true
+type ImageMirror struct {
+	// Source is a repository, such as quay.io/mhrivnak/demo-operator-registry,
+	// or a prefix of repositories, such as quay.io/mhrivnak or quay.io
+	Source string `json:"source"`
+	// Mirror replaces Source in image references
+	Mirror string `json:"mirror"`
+}
+
+// OperatorSpec describes one operator to install from the KNI catalog
+// +k8s:openapi-gen=true
+type OperatorSpec struct {
+	// Name of the operator, which is also used as the name of its Subscription
+	Name string `json:"name"`
+	// Package is the name of the package in the catalog
+	Package string `json:"package"`
+	// Channel is the catalog channel to subscribe to
+	Channel string `json:"channel"`
+	// Catalog is the name of the catalog providing the package. It must match
+	// the name of the KNICluster's catalog.
+	Catalog string `json:"catalog"`
+	// Namespace the operator is installed in. Operators in the same
+	// namespace share its OperatorGroup. Defaults to the KNICluster's
+	// namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Operands is a list of objects, usually custom resources, that get
+	// created once the operator is available so that it deploys its operand.
+	// +optional
+	Operands []runtime.RawExtension `json:"operands,omitempty"`
+}
+
+// KNIClusterStatus defines the observed state of KNICluster
+// +k8s:openapi-gen=true
+type KNIClusterStatus struct {
+	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
+	// Add custom validation using kubebuilder tags: https://book.kubebuilder.io/beyond_basics/generating_crd.html
+
+	// ObservedGeneration is the most recent generation of the spec that was
+	// reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// CatalogImage is the image currently used by the CatalogSource
+	// +optional
+	CatalogImage string `json:"catalogImage,omitempty"`
+	// CatalogDigest is the latest resolution of the catalog image tag to a
+	// digest
+	// +optional
+	CatalogDigest *CatalogDigestStatus `json:"catalogDigest,omitempty"`
+	// CatalogValidation is the result of checking the content of the latest
+	// catalog image before switching to it
+	// +optional
+	CatalogValidation *CatalogValidationStatus `json:"catalogValidation,omitempty"`
+	// CatalogVersion is the cluster version the CatalogSource currently serves
+	// +optional
+	CatalogVersion string `json:"catalogVersion,omitempty"`
+	// LastForcedReconcile is the value of the reconcile-at annotation that
+	// last forced a full resync
+	// +optional
+	LastForcedReconcile string `json:"lastForcedReconcile,omitempty"`
+	// Images lists the images the cluster is pointed at, after mirror
+	// rewriting
+	// +optional
+	Images []ImageStatus `json:"images,omitempty"`
+	// Diagnostics describes the latest diagnostics bundle collected through
+	// the collect-diagnostics annotation
+	// +optional
+	Diagnostics *DiagnosticsStatus `json:"diagnostics,omitempty"`
+	// UpgradeHistory lists the catalog versions the cluster has been
+	// reconciled to, oldest first
+	// +optional
+	UpgradeHistory []UpgradeRecord `json:"upgradeHistory,omitempty"`
+	// PolicyDecisions are the latest decisions of the policy endpoint, one
+	// per proposed change
+	// +optional
+	PolicyDecisions []PolicyDecisionStatus `json:"policyDecisions,omitempty"`
+	// Notifications reports the delivery of notifications, per notification
+	// in the spec
+	// +optional
+	Notifications []NotificationStatus `json:"notifications,omitempty"`
+	// PendingUpgrade is the catalog switch waiting for approval
+	// +optional
+	PendingUpgrade *PendingUpgradeStatus `json:"pendingUpgrade,omitempty"`
+	// Hooks are the results of the hooks run for the latest catalog switch
+	// +optional
+	Hooks []HookStatus `json:"hooks,omitempty"`
+	// Rollback is set while the catalog is rolled back because a post-switch
+	// hook failed
+	// +optional
+	Rollback *RollbackStatus `json:"rollback,omitempty"`
+	// UpgradePreview describes how the managed operators would change for
+	// each update available to the cluster
+	// +optional
+	UpgradePreview []UpgradePreview `json:"upgradePreview,omitempty"`
+	// PrePull reports the pre-pulling of the catalog images of available
+	// updates
+	// +optional
+	PrePull *PrePullStatus `json:"prePull,omitempty"`
+	// OperatorGroups reports the namespaces targeted by each OperatorGroup,
+	// and the operators that do not support them
+	// +optional
+	OperatorGroups []OperatorGroupStatus `json:"operatorGroups,omitempty"`
+	// Conflicts lists the existing objects the operator would manage, but
+	// cannot take over
+	// +optional
+	Conflicts []ObjectConflict `json:"conflicts,omitempty"`
+	// Plan lists the changes that would be made to the cluster. It is only
+	// set in Plan mode.
+	// +optional
+	Plan *PlanStatus `json:"plan,omitempty"`
+	// Conditions is a list of conditions related to operator reconciliation
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +optional
+	Conditions []conditionsv1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	// RelatedObjects is a list of objects that are "interesting" or related to this operator.
+	// +optional
+	RelatedObjects []corev1.ObjectReference `json:"relatedObjects,omitempty"`
+}
+
+// CatalogDigestStatus describes the resolution of a catalog image tag
+// +k8s:openapi-gen=true
+type CatalogDigestStatus struct {
+	// Image is the tagged image that was resolved
+	Image string `json:"image"`
+	// Digest the tag pointed to
+	Digest string `json:"digest"`
+	// ResolvedTime is when the tag was last resolved
+	ResolvedTime metav1.Time `json:"resolvedTime"`
+}
+
+// CatalogValidationStatus describes the content check of a catalog image
+// +k8s:openapi-gen=true
+type CatalogValidationStatus struct {
+	// Image is the catalog image that was checked
+	Image string `json:"image"`
+	// Valid is whether the catalog has every managed package and channel
+	Valid bool `json:"valid"`
+	// Message describes what the catalog is missing
+	// +optional
+	Message string `json:"message,omitempty"`
+	// ChannelHeads lists the CSV at the head of each managed channel
+	// +optional
+	ChannelHeads []ChannelHead `json:"channelHeads,omitempty"`
+	// ValidationTime is when the catalog was checked
+	ValidationTime metav1.Time `json:"validationTime"`
+}
+
+// ChannelHead is the CSV at the head of a channel in a catalog
+// +k8s:openapi-gen=true
+type ChannelHead struct {
+	// Package name
+	Package string `json:"package"`
+	// Channel name
+	Channel string `json:"channel"`
+	// CSV is the name of the ClusterServiceVersion at the head of the channel
+	CSV string `json:"csv"`
+}
+
+// ImageStatus describes an image the operator points the cluster at
+// +k8s:openapi-gen=true
+type ImageStatus struct {
+	// Consumer is what uses the image, such as the catalog or a field of an
+	// operand
+	Consumer string `json:"consumer"`
+	// Image is the effective image reference
+	Image string `json:"image"`
+	// Source is the image reference before it was rewritten to use a mirror
+	// +optional
+	Source string `json:"source,omitempty"`
+}
+
+// DiagnosticsStatus describes a diagnostics bundle collected by the operator
+// +k8s:openapi-gen=true
+type DiagnosticsStatus struct {
+	// Request is the value of the collect-diagnostics annotation that was
+	// handled
+	Request string `json:"request"`
+	// ConfigMap is the name of the ConfigMap, in the KNICluster's namespace,
+	// that holds the bundle
+	// +optional
+	ConfigMap string `json:"configMap,omitempty"`
+	// Bundle is the key of the gzipped tarball in the ConfigMap's binaryData
+	// +optional
+	Bundle string `json:"bundle,omitempty"`
+	// CompletionTime is when collection finished
+	CompletionTime metav1.Time `json:"completionTime"`
+	// Error describes why the bundle could not be collected or stored
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// UpgradeRecord describes a catalog version that the cluster was reconciled to
+// +k8s:openapi-gen=true
+type UpgradeRecord struct {
+	// Version of the cluster
+	Version string `json:"version"`
+	// CatalogImage is the image of the CatalogSource, if it is served from an
+	// image
+	// +optional
+	CatalogImage string `json:"catalogImage,omitempty"`
+	// CompletionTime is when all components were first reconciled with the
+	// catalog image
+	CompletionTime metav1.Time `json:"completionTime"`
+	// Hooks are the results of the hooks run for the switch
+	// +optional
+	Hooks []HookStatus `json:"hooks,omitempty"`
+	// RolledBack is set when the catalog was switched back to the previous
+	// version because a post-switch hook failed
+	// +optional
+	RolledBack bool `json:"rolledBack,omitempty"`
+}
+
+// HookPhase is when a hook runs
+type HookPhase string
+
+const (
+	// HookPhasePreSwitch hooks run before the catalog is switched
+	HookPhasePreSwitch HookPhase = "PreSwitch"
+	// HookPhasePostSwitch hooks run after the catalog is switched
+	HookPhasePostSwitch HookPhase = "PostSwitch"
+)
+
+// HookResult is the result of a hook Job
+type HookResult string
+
+const (
+	// HookRunning means the Job has not finished
+	HookRunning HookResult = "Running"
+	// HookSucceeded means the Job succeeded
+	HookSucceeded HookResult = "Succeeded"
+	// HookFailed means the Job failed
+	HookFailed HookResult = "Failed"
+)
+
+// HookStatus is the result of a hook run for a catalog switch
+// +k8s:openapi-gen=true
+type HookStatus struct {
+	// Name of the hook
+	Name string `json:"name"`
+	// Phase the hook ran in
+	Phase HookPhase `json:"phase"`
+	// Version the catalog was switched to
+	Version string `json:"version"`
+	// Job that runs the hook
+	Job string `json:"job"`
+	// Result of the Job
+	Result HookResult `json:"result"`
+	// Message explains a failure
+	// +optional
+	Message string `json:"message,omitempty"`
+	// CompletionTime is when the Job finished
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// PolicyDecisionStatus records a decision of the policy endpoint
+// +k8s:openapi-gen=true
+type PolicyDecisionStatus struct {
+	// Action the decision is about
+	Action PolicyAction `json:"action"`
+	// Subject identifies the change: the target version of a catalog switch,
+	// or the namespace and name of an InstallPlan
+	Subject string `json:"subject"`
+	// Decision of the endpoint
+	Decision PolicyDecision `json:"decision"`
+	// Reason given by the endpoint
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Time of the decision
+	Time metav1.Time `json:"time"`
+}
+
+// NotificationStatus reports the delivery of a notification
+// +k8s:openapi-gen=true
+type NotificationStatus struct {
+	// Name of the notification in the spec
+	Name string `json:"name"`
+	// Delivered lists the IDs of the latest events delivered, so that no
+	// event is sent twice
+	// +optional
+	Delivered []string `json:"delivered,omitempty"`
+	// Pending lists the events whose delivery failed and is retried
+	// +optional
+	Pending []PendingNotification `json:"pending,omitempty"`
+	// LastError is the latest delivery failure
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// PendingNotification is an event waiting to be delivered
+// +k8s:openapi-gen=true
+type PendingNotification struct {
+	// ID of the event
+	ID string `json:"id"`
+	// Event type
+	Event NotificationEvent `json:"event"`
+	// Version the event is about, if any
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Message describing the event
+	Message string `json:"message"`
+	// Time of the event
+	Time metav1.Time `json:"time"`
+	// Attempts is the number of failed deliveries
+	Attempts int32 `json:"attempts"`
+}
+
+// PendingUpgradeStatus describes a catalog switch waiting for approval
+// +k8s:openapi-gen=true
+type PendingUpgradeStatus struct {
+	// Version the catalog would be switched to. Approving it means setting
+	// spec.approvedVersion or the kni.openshift.com/approved-version
+	// annotation to this value.
+	Version string `json:"version"`
+	// FromVersion is the version the catalog serves now
+	// +optional
+	FromVersion string `json:"fromVersion,omitempty"`
+	// CatalogImage is the image the catalog would be switched to, if it is
+	// served from an image
+	// +optional
+	CatalogImage string `json:"catalogImage,omitempty"`
+	// RequestedTime is when the switch was first found waiting
+	RequestedTime metav1.Time `json:"requestedTime"`
+}
+
+// RollbackStatus describes a catalog switched back to the previous version
+// +k8s:openapi-gen=true
+type RollbackStatus struct {
+	// FromVersion is the version the catalog was switched away from. The
+	// rollback lasts until the cluster's desired version changes.
+	FromVersion string `json:"fromVersion"`
+	// ToVersion is the version the catalog serves instead
+	ToVersion string `json:"toVersion"`
+	// Reason the catalog was rolled back
+	Reason string `json:"reason"`
+	// Time of the rollback
+	Time metav1.Time `json:"time"`
+}
+
+// UpgradePreviewState is the state of the preview of a cluster update
+type UpgradePreviewState string
+
+const (
+	// UpgradePreviewPending means the catalog for the update is being queried
+	UpgradePreviewPending UpgradePreviewState = "Pending"
+	// UpgradePreviewReady means the operator changes are known
+	UpgradePreviewReady UpgradePreviewState = "Ready"
+	// UpgradePreviewFailed means the catalog for the update could not be
+	// queried
+	UpgradePreviewFailed UpgradePreviewState = "Failed"
+	// UpgradePreviewCatalogMissing means there is no catalog for the update
+	UpgradePreviewCatalogMissing UpgradePreviewState = "CatalogMissing"
+)
+
+// UpgradePreview describes how the managed operators would change if the
+// cluster were updated to a version
+// +k8s:openapi-gen=true
+type UpgradePreview struct {
+	// Version the cluster can be updated to
+	Version string `json:"version"`
+	// CatalogImage is the catalog image for the version, if the catalog is
+	// served from an image
+	// +optional
+	CatalogImage string `json:"catalogImage,omitempty"`
+	// State of the preview
+	State UpgradePreviewState `json:"state"`
+	// Message explains a failed preview, or lists the managed packages and
+	// channels the catalog lacks
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Operators lists the version change of each managed operator found in
+	// the catalog
+	// +optional
+	Operators []OperatorPreview `json:"operators,omitempty"`
+	// PreviewTime is when the catalog was queried
+	// +optional
+	PreviewTime *metav1.Time `json:"previewTime,omitempty"`
+}
+
+// OperatorPreview describes how a managed operator would change
+// +k8s:openapi-gen=true
+type OperatorPreview struct {
+	// Name of the operator in the spec
+	Name string `json:"name"`
+	// CurrentCSV is the CSV installed now
+	// +optional
+	CurrentCSV string `json:"currentCSV,omitempty"`
+	// TargetCSV is the head of the subscribed channel in the catalog for the
+	// version
+	TargetCSV string `json:"targetCSV"`
+	// Changed is whether the operator would be upgraded
+	Changed bool `json:"changed"`
+}
+
+// PrePullState is the state of pre-pulling catalog images
+type PrePullState string
+
+const (
+	// PrePullPulling means the images are being pulled onto the nodes
+	PrePullPulling PrePullState = "Pulling"
+	// PrePullCompleted means every node has pulled the images
+	PrePullCompleted PrePullState = "Completed"
+	// PrePullTimedOut means some nodes had not pulled the images within the
+	// timeout. They are not pulled again until they change.
+	PrePullTimedOut PrePullState = "TimedOut"
+)
+
+// PrePullStatus reports the pre-pulling of catalog images onto the nodes
+// +k8s:openapi-gen=true
+type PrePullStatus struct {
+	// Images being or having been pre-pulled
+	Images []string `json:"images"`
+	// State of the pre-pull
+	State PrePullState `json:"state"`
+	// DaemonSet that pulls the images. It is deleted once they are pulled.
+	// +optional
+	DaemonSet string `json:"daemonSet,omitempty"`
+	// NodesDesired is the number of nodes the images are pulled onto
+	// +optional
+	NodesDesired int32 `json:"nodesDesired,omitempty"`
+	// NodesReady is the number of nodes that have pulled the images
+	// +optional
+	NodesReady int32 `json:"nodesReady,omitempty"`
+	// StartTime is when pulling the images started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is when every node had pulled the images, or when
+	// pulling them timed out
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// ObjectConflict is an existing object the operator cannot take over
+// +k8s:openapi-gen=true
+type ObjectConflict struct {
+	// Kind of the object
+	Kind string `json:"kind"`
+	// Namespace of the object
+	Namespace string `json:"namespace"`
+	// Name of the object
+	Name string `json:"name"`
+	// Message explains the conflict
+	Message string `json:"message"`
+}
+
+// OperatorGroupStatus describes the OperatorGroup of a namespace
+// +k8s:openapi-gen=true
+type OperatorGroupStatus struct {
+	// Namespace of the OperatorGroup
+	Namespace string `json:"namespace"`
+	// TargetNamespaces are the namespaces the OperatorGroup targets. An empty
+	// string stands for all namespaces.
+	// +optional
+	TargetNamespaces []string `json:"targetNamespaces,omitempty"`
+	// Incompatible lists the operators whose CSV does not support the
+	// targeted namespaces
+	// +optional
+	Incompatible []InstallModeIncompatibility `json:"incompatible,omitempty"`
+}
+
+// InstallModeIncompatibility is an operator whose CSV does not support the
+// namespaces its OperatorGroup targets
+// +k8s:openapi-gen=true
+type InstallModeIncompatibility struct {
+	// Operator is the name of the operator
+	Operator string `json:"operator"`
+	// CSV is the name of the ClusterServiceVersion
+	CSV string `json:"csv"`
+	// Message explains which install mode is not supported
+	Message string `json:"message"`
+}
+
+// PlanStatus describes the changes needed to make the cluster match a
+// generation of the spec
+// +k8s:openapi-gen=true
+type PlanStatus struct {
+	// Generation of the spec the plan was computed for
+	Generation int64 `json:"generation"`
+	// Changes is the list of planned changes, in the order they would be made
+	// +optional
+	Changes []PlannedChange `json:"changes,omitempty"`
+}
+
+// PlannedChange is a change the operator would make to one object
+// +k8s:openapi-gen=true
+type PlannedChange struct {
+	// Action is one of Create, Update or Delete
+	Action string `json:"action"`
+	// Kind of the object
+	Kind string `json:"kind"`
+	// Namespace of the object, if it is namespaced
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Name of the object
+	Name string `json:"name"`
+	// Diff lists the fields that would change in an update, as
+	// "path: live -> desired"
+	// +optional
+	Diff []string `json:"diff,omitempty"`
+	// DependsOn lists what the change would wait for before it is made,
+	// such as a decision of the policy endpoint
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// KNICluster is the Schema for the kniclusters API
+// +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+type KNICluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KNIClusterSpec   `json:"spec,omitempty"`
+	Status KNIClusterStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// KNIClusterList contains a list of KNICluster
+type KNIClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KNICluster `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KNICluster{}, &KNIClusterList{})
+}
@@ -0,0 +1,2060 @@
+// +build !ignore_autogenerated
+
+// Code generated by openapi-gen. DO NOT EDIT.
+
+// This file was autogenerated by openapi-gen. Do not edit it manually!
+
+package v1beta1
+
+import (
+	spec "github.com/go-openapi/spec"
+	common "k8s.io/kube-openapi/pkg/common"
+)
+
+func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+	return map[string]common.OpenAPIDefinition{
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.AdoptionSpec":               schema_pkg_apis_kni_v1beta1_AdoptionSpec(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogConfigMapSpec":       schema_pkg_apis_kni_v1beta1_CatalogConfigMapSpec(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogDigestStatus":        schema_pkg_apis_kni_v1beta1_CatalogDigestStatus(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogImageSpec":           schema_pkg_apis_kni_v1beta1_CatalogImageSpec(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogSpec":                schema_pkg_apis_kni_v1beta1_CatalogSpec(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogValidationStatus":    schema_pkg_apis_kni_v1beta1_CatalogValidationStatus(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.ChannelHead":                schema_pkg_apis_kni_v1beta1_ChannelHead(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.DiagnosticsStatus":          schema_pkg_apis_kni_v1beta1_DiagnosticsStatus(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.HookSpec":                   schema_pkg_apis_kni_v1beta1_HookSpec(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.HookStatus":                 schema_pkg_apis_kni_v1beta1_HookStatus(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.HooksSpec":                  schema_pkg_apis_kni_v1beta1_HooksSpec(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.ImageMirror":                schema_pkg_apis_kni_v1beta1_ImageMirror(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.ImageStatus":                schema_pkg_apis_kni_v1beta1_ImageStatus(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.InstallModeIncompatibility": schema_pkg_apis_kni_v1beta1_InstallModeIncompatibility(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.KNICluster":                 schema_pkg_apis_kni_v1beta1_KNICluster(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.KNIClusterSpec":             schema_pkg_apis_kni_v1beta1_KNIClusterSpec(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.KNIClusterStatus":           schema_pkg_apis_kni_v1beta1_KNIClusterStatus(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.NamespaceSpec":              schema_pkg_apis_kni_v1beta1_NamespaceSpec(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.NotificationSpec":           schema_pkg_apis_kni_v1beta1_NotificationSpec(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.NotificationStatus":         schema_pkg_apis_kni_v1beta1_NotificationStatus(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.ObjectConflict":             schema_pkg_apis_kni_v1beta1_ObjectConflict(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.OperatorGroupSpec":          schema_pkg_apis_kni_v1beta1_OperatorGroupSpec(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.OperatorGroupStatus":        schema_pkg_apis_kni_v1beta1_OperatorGroupStatus(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.OperatorPreview":            schema_pkg_apis_kni_v1beta1_OperatorPreview(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.OperatorSpec":               schema_pkg_apis_kni_v1beta1_OperatorSpec(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PendingNotification":        schema_pkg_apis_kni_v1beta1_PendingNotification(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PendingUpgradeStatus":       schema_pkg_apis_kni_v1beta1_PendingUpgradeStatus(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PlanStatus":                 schema_pkg_apis_kni_v1beta1_PlanStatus(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PlannedChange":              schema_pkg_apis_kni_v1beta1_PlannedChange(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PolicyDecisionStatus":       schema_pkg_apis_kni_v1beta1_PolicyDecisionStatus(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PolicySpec":                 schema_pkg_apis_kni_v1beta1_PolicySpec(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PrePullStatus":              schema_pkg_apis_kni_v1beta1_PrePullStatus(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.RollbackStatus":             schema_pkg_apis_kni_v1beta1_RollbackStatus(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.SignatureVerificationSpec":  schema_pkg_apis_kni_v1beta1_SignatureVerificationSpec(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.UpgradePreview":             schema_pkg_apis_kni_v1beta1_UpgradePreview(ref),
+		"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.UpgradeRecord":              schema_pkg_apis_kni_v1beta1_UpgradeRecord(ref),
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_AdoptionSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "AdoptionSpec sets the adoption policy of each kind. Each defaults to Fail, so that existing objects are only taken over when asked for.",
+				Properties: map[string]spec.Schema{
+					"subscriptions": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Subscriptions are adopted when they subscribe to the package of the operator",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"operatorGroups": {
+						SchemaProps: spec.SchemaProps{
+							Description: "OperatorGroups are adopted when they have the name the operator uses",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"catalogSources": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CatalogSources are adopted when they have the source type of the catalog",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_CatalogConfigMapSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "CatalogConfigMapSpec describes where the manifests of a ConfigMap catalog are found",
+				Properties: map[string]spec.Schema{
+					"directory": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Directory on the operator's filesystem, baked into its image or mounted from a volume, that holds a subdirectory of manifests for each cluster version. Each subdirectory contains the CSV, CRD and package manifests of the catalog.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_CatalogDigestStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "CatalogDigestStatus describes the resolution of a catalog image tag",
+				Properties: map[string]spec.Schema{
+					"image": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Image is the tagged image that was resolved",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"digest": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Digest the tag pointed to",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"resolvedTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ResolvedTime is when the tag was last resolved",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+				},
+				Required: []string{"image", "digest", "resolvedTime"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_CatalogImageSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "CatalogImageSpec describes how the catalog image is chosen",
+				Properties: map[string]spec.Schema{
+					"repository": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Repository is the operator-registry image repository. The image tag is the version of the cluster.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"pinDigest": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PinDigest resolves the image tag to a digest through the registry, and points the CatalogSource at the digest, so that a repushed tag cannot change the catalog unnoticed",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"resolveInterval": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ResolveInterval is how often a pinned tag is resolved again. When the tag has moved, the catalog is updated to the new digest. Without it, a tag is resolved once.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Duration"),
+						},
+					},
+					"insecureRegistry": {
+						SchemaProps: spec.SchemaProps{
+							Description: "InsecureRegistry allows resolving against a registry that serves plain HTTP or an unverifiable certificate, such as a local one",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"pullSecret": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PullSecret is the name of a Secret in the KNICluster's namespace, of type kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg, with credentials for the registry. Its credentials take precedence over those of the cluster's global pull secret.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"skipContentValidation": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SkipContentValidation switches the CatalogSource to a new image without first checking that the catalog has every managed package and channel. Validation serves the new image from a staging CatalogSource and queries it through the operator-registry API, which requires the operator to reach services in the catalog namespace.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"prePull": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrePull pulls the catalog images of the updates available to the cluster onto every node ahead of time, so that switching the catalog does not wait for the pull",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"prePullNodeSelector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrePullNodeSelector limits pre-pulling to the nodes with these labels. Images are pulled onto every schedulable node when it is empty.",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"prePullTimeout": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrePullTimeout is how long pre-pulling may take before it is given up. Defaults to 30m.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Duration"),
+						},
+					},
+					"verification": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Verification requires a valid signature before the CatalogSource is switched to a new image. It implies PinDigest, so that the CatalogSource runs the digest that was verified.",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.SignatureVerificationSpec"),
+						},
+					},
+				},
+				Required: []string{"repository"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.SignatureVerificationSpec", "k8s.io/apimachinery/pkg/apis/meta/v1.Duration"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_CatalogSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "CatalogSpec describes the CatalogSource managed for a KNICluster",
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the CatalogSource",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"namespace": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespace of the CatalogSource. It cannot be changed after creation.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"type": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Type is Image to serve the catalog from an operator-registry image, or ConfigMap to build it from manifests on the operator's filesystem, for clusters that cannot pull the image. Defaults to Image.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"image": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Image describes the operator-registry image of the catalog",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogImageSpec"),
+						},
+					},
+					"configMap": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ConfigMap describes where the manifests of a ConfigMap catalog are found",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogConfigMapSpec"),
+						},
+					},
+				},
+				Required: []string{"name", "namespace"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogConfigMapSpec", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogImageSpec"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_CatalogValidationStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "CatalogValidationStatus describes the content check of a catalog image",
+				Properties: map[string]spec.Schema{
+					"image": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Image is the catalog image that was checked",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"valid": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Valid is whether the catalog has every managed package and channel",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"message": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Message describes what the catalog is missing",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"channelHeads": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ChannelHeads lists the CSV at the head of each managed channel",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.ChannelHead"),
+									},
+								},
+							},
+						},
+					},
+					"validationTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ValidationTime is when the catalog was checked",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+				},
+				Required: []string{"image", "valid", "validationTime"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.ChannelHead", "k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_ChannelHead(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ChannelHead is the CSV at the head of a channel in a catalog",
+				Properties: map[string]spec.Schema{
+					"package": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Package name",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"channel": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Channel name",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"csv": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CSV is the name of the ClusterServiceVersion at the head of the channel",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"package", "channel", "csv"},
+			},
+		},
+		Dependencies: []string{},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_DiagnosticsStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "DiagnosticsStatus describes a diagnostics bundle collected by the operator",
+				Properties: map[string]spec.Schema{
+					"request": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Request is the value of the collect-diagnostics annotation that was handled",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"configMap": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ConfigMap is the name of the ConfigMap, in the KNICluster's namespace, that holds the bundle",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"bundle": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Bundle is the key of the gzipped tarball in the ConfigMap's binaryData",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"completionTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CompletionTime is when collection finished",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"error": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Error describes why the bundle could not be collected or stored",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"request", "completionTime"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_HookSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "HookSpec describes a hook Job. The Job runs in the KNICluster's namespace with the environment variables KNI_HOOK_PHASE, KNI_CATALOG_VERSION, KNI_CATALOG_IMAGE and KNI_PREVIOUS_VERSION.",
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the hook, unique among the hooks of its phase",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"image": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Image the hook runs",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"command": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Command overrides the entrypoint of the image",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"args": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Args are the arguments of the command",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"serviceAccountName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ServiceAccountName is the service account the hook runs as",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"backoffLimit": {
+						SchemaProps: spec.SchemaProps{
+							Description: "BackoffLimit is the number of retries before the hook fails",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"activeDeadlineSeconds": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ActiveDeadlineSeconds limits how long the hook runs",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+				},
+				Required: []string{"name", "image"},
+			},
+		},
+		Dependencies: []string{},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_HookStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "HookStatus is the result of a hook run for a catalog switch",
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the hook",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"phase": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Phase the hook ran in",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"version": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Version the catalog was switched to",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"job": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Job that runs the hook",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"result": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Result of the Job",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"message": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Message explains a failure",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"completionTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CompletionTime is when the Job finished",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+				},
+				Required: []string{"name", "phase", "version", "job", "result"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_HooksSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "HooksSpec lists the Jobs run around each catalog switch",
+				Properties: map[string]spec.Schema{
+					"preSwitch": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PreSwitch hooks must all succeed before the catalog is switched to a new version",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.HookSpec"),
+									},
+								},
+							},
+						},
+					},
+					"postSwitch": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PostSwitch hooks run once the CSV of every managed operator has succeeded after a switch",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.HookSpec"),
+									},
+								},
+							},
+						},
+					},
+					"postSwitchFailurePolicy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PostSwitchFailurePolicy is Degrade or Rollback. Defaults to Degrade.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.HookSpec"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_ImageMirror(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ImageMirror maps images from a source to a mirror",
+				Properties: map[string]spec.Schema{
+					"source": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Source is a repository, such as quay.io/mhrivnak/demo-operator-registry, or a prefix of repositories, such as quay.io/mhrivnak or quay.io",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"mirror": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Mirror replaces Source in image references",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"source", "mirror"},
+			},
+		},
+		Dependencies: []string{},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_ImageStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ImageStatus describes an image the operator points the cluster at",
+				Properties: map[string]spec.Schema{
+					"consumer": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Consumer is what uses the image, such as the catalog or a field of an operand",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"image": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Image is the effective image reference",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"source": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Source is the image reference before it was rewritten to use a mirror",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"consumer", "image"},
+			},
+		},
+		Dependencies: []string{},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_InstallModeIncompatibility(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "InstallModeIncompatibility is an operator whose CSV does not support the namespaces its OperatorGroup targets",
+				Properties: map[string]spec.Schema{
+					"operator": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Operator is the name of the operator",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"csv": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CSV is the name of the ClusterServiceVersion",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"message": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Message explains which install mode is not supported",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"operator", "csv", "message"},
+			},
+		},
+		Dependencies: []string{},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_KNICluster(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "KNICluster is the Schema for the kniclusters API",
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind is a string value representing the REST resource this object represents. Servers may infer this from the endpoint the client submits requests to. Cannot be updated. In CamelCase. More info: https://git.k8s.io/community/contributors/devel/api-conventions.md#types-kinds",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"apiVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "APIVersion defines the versioned schema of this representation of an object. Servers should convert recognized schemas to the latest internal value, and may reject unrecognized values. More info: https://git.k8s.io/community/contributors/devel/api-conventions.md#resources",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"metadata": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"),
+						},
+					},
+					"spec": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.KNIClusterSpec"),
+						},
+					},
+					"status": {
+						SchemaProps: spec.SchemaProps{
+							Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.KNIClusterStatus"),
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.KNIClusterSpec", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.KNIClusterStatus", "k8s.io/apimachinery/pkg/apis/meta/v1.ObjectMeta"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_KNIClusterSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "KNIClusterSpec defines the desired state of KNICluster",
+				Properties: map[string]spec.Schema{
+					"catalog": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Catalog describes the CatalogSource that operators are installed from",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogSpec"),
+						},
+					},
+					"operators": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Operators is the list of operators to subscribe to",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.OperatorSpec"),
+									},
+								},
+							},
+						},
+					},
+					"namespaces": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespaces are created by the operator, with the given labels and annotations, so that operators can be installed in them",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.NamespaceSpec"),
+									},
+								},
+							},
+						},
+					},
+					"namespaceDeletionPolicy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NamespaceDeletionPolicy is Retain or Delete, and applies to the namespaces the operator created when they are removed from Namespaces or the KNICluster is deleted. Defaults to Retain.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"adoption": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Adoption determines what happens to existing objects the operator would manage, but did not create",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.AdoptionSpec"),
+						},
+					},
+					"operatorGroups": {
+						SchemaProps: spec.SchemaProps{
+							Description: "OperatorGroups configure the namespaces targeted by the OperatorGroups of the namespaces operators are installed in. OperatorGroups without an entry target only their own namespace.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.OperatorGroupSpec"),
+									},
+								},
+							},
+						},
+					},
+					"imageMirrors": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ImageMirrors rewrite the catalog image and operand image references to pull from mirrors, for disconnected clusters",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.ImageMirror"),
+									},
+								},
+							},
+						},
+					},
+					"useImageContentSourcePolicies": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UseImageContentSourcePolicies adds the mirrors of the cluster's ImageContentSourcePolicies to ImageMirrors. Mirrors in ImageMirrors take precedence.",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"mode": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Mode is Apply to make changes to the cluster, or Plan to only report them in status.plan. Defaults to Apply.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"approval": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Approval is Automatic, or Manual to wait for each new catalog version to be approved through ApprovedVersion or the kni.openshift.com/approved-version annotation. Defaults to Automatic.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"approvedVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ApprovedVersion is the cluster version the catalog may be switched to when Approval is Manual. Approvals of other versions are ignored.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"policy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Policy is an external HTTP endpoint that authorizes catalog switches and, optionally, InstallPlan approvals",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PolicySpec"),
+						},
+					},
+					"notifications": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Notifications send lifecycle events to chat or webhook endpoints",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.NotificationSpec"),
+									},
+								},
+							},
+						},
+					},
+					"hooks": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Hooks are Jobs run around each switch of the catalog to a new cluster version",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.HooksSpec"),
+						},
+					},
+				},
+				Required: []string{"catalog", "operators"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.AdoptionSpec", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogSpec", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.HooksSpec", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.ImageMirror", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.NamespaceSpec", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.NotificationSpec", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.OperatorGroupSpec", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.OperatorSpec", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PolicySpec"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_KNIClusterStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "KNIClusterStatus defines the observed state of KNICluster",
+				Properties: map[string]spec.Schema{
+					"observedGeneration": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ObservedGeneration is the most recent generation of the spec that was reconciled",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"catalogImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CatalogImage is the image currently used by the CatalogSource",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"catalogDigest": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CatalogDigest is the latest resolution of the catalog image tag to a digest",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogDigestStatus"),
+						},
+					},
+					"catalogValidation": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CatalogValidation is the result of checking the content of the latest catalog image before switching to it",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogValidationStatus"),
+						},
+					},
+					"catalogVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CatalogVersion is the cluster version the CatalogSource currently serves",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"lastForcedReconcile": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastForcedReconcile is the value of the reconcile-at annotation that last forced a full resync",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"images": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Images lists the images the cluster is pointed at, after mirror rewriting",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.ImageStatus"),
+									},
+								},
+							},
+						},
+					},
+					"diagnostics": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Diagnostics describes the latest diagnostics bundle collected through the collect-diagnostics annotation",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.DiagnosticsStatus"),
+						},
+					},
+					"upgradeHistory": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UpgradeHistory lists the catalog versions the cluster has been reconciled to, oldest first",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.UpgradeRecord"),
+									},
+								},
+							},
+						},
+					},
+					"policyDecisions": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PolicyDecisions are the latest decisions of the policy endpoint, one per proposed change",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PolicyDecisionStatus"),
+									},
+								},
+							},
+						},
+					},
+					"notifications": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Notifications reports the delivery of notifications, per notification in the spec",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.NotificationStatus"),
+									},
+								},
+							},
+						},
+					},
+					"pendingUpgrade": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PendingUpgrade is the catalog switch waiting for approval",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PendingUpgradeStatus"),
+						},
+					},
+					"hooks": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Hooks are the results of the hooks run for the latest catalog switch",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.HookStatus"),
+									},
+								},
+							},
+						},
+					},
+					"rollback": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Rollback is set while the catalog is rolled back because a post-switch hook failed",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.RollbackStatus"),
+						},
+					},
+					"upgradePreview": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UpgradePreview describes how the managed operators would change for each update available to the cluster",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.UpgradePreview"),
+									},
+								},
+							},
+						},
+					},
+					"prePull": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PrePull reports the pre-pulling of the catalog images of available updates",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PrePullStatus"),
+						},
+					},
+					"operatorGroups": {
+						SchemaProps: spec.SchemaProps{
+							Description: "OperatorGroups reports the namespaces targeted by each OperatorGroup, and the operators that do not support them",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.OperatorGroupStatus"),
+									},
+								},
+							},
+						},
+					},
+					"conflicts": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Conflicts lists the existing objects the operator would manage, but cannot take over",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.ObjectConflict"),
+									},
+								},
+							},
+						},
+					},
+					"plan": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Plan lists the changes that would be made to the cluster. It is only set in Plan mode.",
+							Ref:         ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PlanStatus"),
+						},
+					},
+					"conditions": {
+						VendorExtensible: spec.VendorExtensible{
+							Extensions: spec.Extensions{
+								"x-kubernetes-patch-merge-key": "type",
+								"x-kubernetes-patch-strategy":  "merge",
+							},
+						},
+						SchemaProps: spec.SchemaProps{
+							Description: "Conditions is a list of conditions related to operator reconciliation",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/djzager/custom-resource-status/conditions/v1.Condition"),
+									},
+								},
+							},
+						},
+					},
+					"relatedObjects": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RelatedObjects is a list of objects that are \"interesting\" or related to this operator.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/api/core/v1.ObjectReference"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Dependencies: []string{
+			"github.com/djzager/custom-resource-status/conditions/v1.Condition", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogDigestStatus", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.CatalogValidationStatus", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.DiagnosticsStatus", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.HookStatus", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.ImageStatus", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.NotificationStatus", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.ObjectConflict", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.OperatorGroupStatus", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PendingUpgradeStatus", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PlanStatus", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PolicyDecisionStatus", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PrePullStatus", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.RollbackStatus", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.UpgradePreview", "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.UpgradeRecord", "k8s.io/api/core/v1.ObjectReference"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_NamespaceSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "NamespaceSpec describes a namespace managed by the operator. Labels and annotations are added to those the namespace already has.",
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the namespace",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"labels": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Labels to set on the namespace",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"annotations": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Annotations to set on the namespace",
+							Type:        []string{"object"},
+							AdditionalProperties: &spec.SchemaOrBool{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+		Dependencies: []string{},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_NotificationSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "NotificationSpec describes an endpoint notified of lifecycle events",
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the notification, unique in the KNICluster",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"format": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Format is Webhook or Slack. Defaults to Webhook.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"urlSecret": {
+						SchemaProps: spec.SchemaProps{
+							Description: "URLSecret is the name of a Secret, in the KNICluster's namespace, whose \"url\" key is the URL notifications are posted to",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"events": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Events to send. All events are sent when it is empty.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"name", "urlSecret"},
+			},
+		},
+		Dependencies: []string{},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_NotificationStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "NotificationStatus reports the delivery of a notification",
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the notification in the spec",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"delivered": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Delivered lists the IDs of the latest events delivered, so that no event is sent twice",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"pending": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Pending lists the events whose delivery failed and is retried",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PendingNotification"),
+									},
+								},
+							},
+						},
+					},
+					"lastError": {
+						SchemaProps: spec.SchemaProps{
+							Description: "LastError is the latest delivery failure",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PendingNotification"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_ObjectConflict(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "ObjectConflict is an existing object the operator cannot take over",
+				Properties: map[string]spec.Schema{
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind of the object",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"namespace": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespace of the object",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the object",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"message": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Message explains the conflict",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"kind", "namespace", "name", "message"},
+			},
+		},
+		Dependencies: []string{},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_OperatorGroupSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "OperatorGroupSpec configures the OperatorGroup of a namespace. At most one of AllNamespaces, TargetNamespaces and Selector may be set. Without any of them, the OperatorGroup targets its own namespace.",
+				Properties: map[string]spec.Schema{
+					"namespace": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespace of the OperatorGroup",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"allNamespaces": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AllNamespaces targets every namespace of the cluster",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"targetNamespaces": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TargetNamespaces is an explicit list of namespaces to target",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"selector": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Selector targets the namespaces with matching labels",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.LabelSelector"),
+						},
+					},
+				},
+				Required: []string{"namespace"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.LabelSelector"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_OperatorGroupStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "OperatorGroupStatus describes the OperatorGroup of a namespace",
+				Properties: map[string]spec.Schema{
+					"namespace": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespace of the OperatorGroup",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"targetNamespaces": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TargetNamespaces are the namespaces the OperatorGroup targets. An empty string stands for all namespaces.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"incompatible": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Incompatible lists the operators whose CSV does not support the targeted namespaces",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.InstallModeIncompatibility"),
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"namespace"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.InstallModeIncompatibility"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_OperatorPreview(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "OperatorPreview describes how a managed operator would change",
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the operator in the spec",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"currentCSV": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CurrentCSV is the CSV installed now",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"targetCSV": {
+						SchemaProps: spec.SchemaProps{
+							Description: "TargetCSV is the head of the subscribed channel in the catalog for the version",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"changed": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Changed is whether the operator would be upgraded",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"name", "targetCSV", "changed"},
+			},
+		},
+		Dependencies: []string{},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_OperatorSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "OperatorSpec describes one operator to install from the KNI catalog",
+				Properties: map[string]spec.Schema{
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the operator, which is also used as the name of its Subscription",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"package": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Package is the name of the package in the catalog",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"channel": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Channel is the catalog channel to subscribe to",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"catalog": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Catalog is the name of the catalog providing the package. It must match the name of the KNICluster's catalog.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"namespace": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespace the operator is installed in. Operators in the same namespace share its OperatorGroup. Defaults to the KNICluster's namespace.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"operands": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Operands is a list of objects, usually custom resources, that get created once the operator is available so that it deploys its operand.",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("k8s.io/apimachinery/pkg/runtime.RawExtension"),
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"name", "package", "channel", "catalog"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/runtime.RawExtension"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_PendingNotification(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "PendingNotification is an event waiting to be delivered",
+				Properties: map[string]spec.Schema{
+					"id": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ID of the event",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"event": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Event type",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"version": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Version the event is about, if any",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"message": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Message describing the event",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"time": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Time of the event",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"attempts": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Attempts is the number of failed deliveries",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+				},
+				Required: []string{"id", "event", "message", "time", "attempts"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_PendingUpgradeStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "PendingUpgradeStatus describes a catalog switch waiting for approval",
+				Properties: map[string]spec.Schema{
+					"version": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Version the catalog would be switched to. Approving it means setting spec.approvedVersion or the kni.openshift.com/approved-version annotation to this value.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"fromVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "FromVersion is the version the catalog serves now",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"catalogImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CatalogImage is the image the catalog would be switched to, if it is served from an image",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"requestedTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RequestedTime is when the switch was first found waiting",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+				},
+				Required: []string{"version", "requestedTime"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_PlanStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "PlanStatus describes the changes needed to make the cluster match a generation of the spec",
+				Properties: map[string]spec.Schema{
+					"generation": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Generation of the spec the plan was computed for",
+							Type:        []string{"integer"},
+							Format:      "int64",
+						},
+					},
+					"changes": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Changes is the list of planned changes, in the order they would be made",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PlannedChange"),
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"generation"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.PlannedChange"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_PlannedChange(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "PlannedChange is a change the operator would make to one object",
+				Properties: map[string]spec.Schema{
+					"action": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Action is one of Create, Update or Delete",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"kind": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Kind of the object",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"namespace": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Namespace of the object, if it is namespaced",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"name": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Name of the object",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"diff": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Diff lists the fields that would change in an update, as \"path: live -> desired\"",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"dependsOn": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DependsOn lists what the change would wait for before it is made, such as a decision of the policy endpoint",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+				},
+				Required: []string{"action", "kind", "name"},
+			},
+		},
+		Dependencies: []string{},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_PolicyDecisionStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "PolicyDecisionStatus records a decision of the policy endpoint",
+				Properties: map[string]spec.Schema{
+					"action": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Action the decision is about",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"subject": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Subject identifies the change: the target version of a catalog switch, or the namespace and name of an InstallPlan",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"decision": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Decision of the endpoint",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"reason": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Reason given by the endpoint",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"time": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Time of the decision",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+				},
+				Required: []string{"action", "subject", "decision", "time"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_PolicySpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "PolicySpec describes an HTTP decision endpoint. The operator POSTs a JSON description of each proposed change, and the endpoint answers with a decision of allow, deny or defer and a reason.",
+				Properties: map[string]spec.Schema{
+					"url": {
+						SchemaProps: spec.SchemaProps{
+							Description: "URL of the decision endpoint",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"caBundle": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CABundle is the PEM-encoded CA bundle that verifies the endpoint's certificate. The system roots are used when it is empty.",
+							Type:        []string{"string"},
+							Format:      "byte",
+						},
+					},
+					"authSecret": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AuthSecret is the name of a Secret, in the KNICluster's namespace, with either a \"token\" key, sent as a bearer token, or \"username\" and \"password\" keys, sent as basic authentication",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"timeout": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Timeout limits each call to the endpoint. Defaults to 10s.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Duration"),
+						},
+					},
+					"decisionTTL": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DecisionTTL is how long an allow or deny decision is kept before the endpoint is asked about the same change again. Defaults to 1h.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Duration"),
+						},
+					},
+					"installPlans": {
+						SchemaProps: spec.SchemaProps{
+							Description: "InstallPlans makes the Subscriptions require InstallPlan approval, and asks the endpoint before approving each InstallPlan",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"url"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Duration"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_PrePullStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "PrePullStatus reports the pre-pulling of catalog images onto the nodes",
+				Properties: map[string]spec.Schema{
+					"images": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Images being or having been pre-pulled",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Type:   []string{"string"},
+										Format: "",
+									},
+								},
+							},
+						},
+					},
+					"state": {
+						SchemaProps: spec.SchemaProps{
+							Description: "State of the pre-pull",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"daemonSet": {
+						SchemaProps: spec.SchemaProps{
+							Description: "DaemonSet that pulls the images. It is deleted once they are pulled.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"nodesDesired": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NodesDesired is the number of nodes the images are pulled onto",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"nodesReady": {
+						SchemaProps: spec.SchemaProps{
+							Description: "NodesReady is the number of nodes that have pulled the images",
+							Type:        []string{"integer"},
+							Format:      "int32",
+						},
+					},
+					"startTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "StartTime is when pulling the images started",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"completionTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CompletionTime is when every node had pulled the images, or when pulling them timed out",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+				},
+				Required: []string{"images", "state"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_RollbackStatus(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "RollbackStatus describes a catalog switched back to the previous version",
+				Properties: map[string]spec.Schema{
+					"fromVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "FromVersion is the version the catalog was switched away from. The rollback lasts until the cluster's desired version changes.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"toVersion": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ToVersion is the version the catalog serves instead",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"reason": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Reason the catalog was rolled back",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"time": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Time of the rollback",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+				},
+				Required: []string{"fromVersion", "toVersion", "reason", "time"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_SignatureVerificationSpec(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "SignatureVerificationSpec describes how catalog image signatures are verified. Signatures are cosign-style: stored in the image's repository under a tag derived from the image digest, and signing a simple signing payload that names the digest.",
+				Properties: map[string]spec.Schema{
+					"publicKeysSecret": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PublicKeysSecret is the name of a Secret, in the KNICluster's namespace, whose values are PEM-encoded ECDSA or RSA public keys. A signature by any of them is accepted.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"publicKeysSecret"},
+			},
+		},
+		Dependencies: []string{},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_UpgradePreview(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "UpgradePreview describes how the managed operators would change if the cluster were updated to a version",
+				Properties: map[string]spec.Schema{
+					"version": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Version the cluster can be updated to",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"catalogImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CatalogImage is the catalog image for the version, if the catalog is served from an image",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"state": {
+						SchemaProps: spec.SchemaProps{
+							Description: "State of the preview",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"message": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Message explains a failed preview, or lists the managed packages and channels the catalog lacks",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"operators": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Operators lists the version change of each managed operator found in the catalog",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.OperatorPreview"),
+									},
+								},
+							},
+						},
+					},
+					"previewTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "PreviewTime is when the catalog was queried",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+				},
+				Required: []string{"version", "state"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.OperatorPreview", "k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
+func schema_pkg_apis_kni_v1beta1_UpgradeRecord(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "UpgradeRecord describes a catalog version that the cluster was reconciled to",
+				Properties: map[string]spec.Schema{
+					"version": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Version of the cluster",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"catalogImage": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CatalogImage is the image of the CatalogSource, if it is served from an image",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"completionTime": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CompletionTime is when all components were first reconciled with the catalog image",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"hooks": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Hooks are the results of the hooks run for the switch",
+							Type:        []string{"array"},
+							Items: &spec.SchemaOrArray{
+								Schema: &spec.Schema{
+									SchemaProps: spec.SchemaProps{
+										Ref: ref("github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.HookStatus"),
+									},
+								},
+							},
+						},
+					},
+					"rolledBack": {
+						SchemaProps: spec.SchemaProps{
+							Description: "RolledBack is set when the catalog was switched back to the previous version because a post-switch hook failed",
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"version", "completionTime"},
+			},
+		},
+		Dependencies: []string{
+			"github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1.HookStatus", "k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
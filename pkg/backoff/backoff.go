@@ -0,0 +1,28 @@
+// Package backoff retries calls to external endpoints with an exponential
+// backoff.
+package backoff
+
+import (
+	"context"
+	"time"
+)
+
+// Retry calls fn up to attempts times, until it succeeds or reports that its
+// failure is not worth retrying. It waits interval before the first retry,
+// and twice as long before each following one. The last error of fn is
+// returned.
+func Retry(ctx context.Context, attempts int, interval time.Duration, fn func() (bool, error)) error {
+	wait := interval
+	for attempt := 1; ; attempt++ {
+		retry, err := fn()
+		if err == nil || !retry || attempt >= attempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
@@ -0,0 +1,158 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+
+	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
+	objectreferencesv1 "github.com/djzager/custom-resource-status/objectreferences/v1"
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	olmv1 "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/reference"
+)
+
+// ReasonConflict is the condition reason when an existing object cannot be
+// taken over
+const ReasonConflict = "Conflict"
+
+// claim decides whether the controller manages found, an existing object of
+// kind, according to the adoption policy. Objects owned by instance are always
+// managed. Others are adopted when policy is Adopt, or when they are legacy
+// objects, and mismatch is empty, in which case instance becomes their owner. It returns false without an error
+// for objects that are ignored, and records a conflict for the others.
+func (r *ReconcileKNICluster) claim(instance *kniv1beta1.KNICluster, found runtime.Object, kind string, policy kniv1beta1.AdoptionPolicy, mismatch string, reqLogger logr.Logger) (bool, error) {
+	accessor, err := meta.Accessor(found)
+	if err != nil {
+		return false, err
+	}
+	if isOwner(instance, accessor) {
+		return true, nil
+	}
+
+	conflict := ""
+	switch {
+	case policy == kniv1beta1.AdoptionIgnore:
+		reqLogger.Info("Ignoring an existing object", "Kind", kind, "Namespace", accessor.GetNamespace(), "Name", accessor.GetName())
+		return false, nil
+	case otherOwner(accessor) != "":
+		conflict = fmt.Sprintf("it is managed by %s", otherOwner(accessor))
+	case policy == kniv1beta1.AdoptionFail && !isLegacyObject(instance, kind, accessor):
+		conflict = "it was not created by this KNICluster, and the adoption policy is Fail"
+	case mismatch != "":
+		conflict = mismatch
+	}
+	if conflict != "" {
+		instance.Status.Conflicts = append(instance.Status.Conflicts, kniv1beta1.ObjectConflict{
+			Kind:      kind,
+			Namespace: accessor.GetNamespace(),
+			Name:      accessor.GetName(),
+			Message:   conflict,
+		})
+		return false, &reasonError{reason: ReasonConflict, err: fmt.Errorf("%s %s/%s: %s", kind, accessor.GetNamespace(), accessor.GetName(), conflict)}
+	}
+
+	reqLogger.Info("Adopting an existing object", "Kind", kind, "Namespace", accessor.GetNamespace(), "Name", accessor.GetName())
+	if err := r.setOwner(instance, accessor); err != nil {
+		return false, err
+	}
+	return true, r.client.Update(context.TODO(), found)
+}
+
+// otherOwner describes the controller of obj, from its controller reference
+// or its owner labels, or returns an empty string if it has none
+func otherOwner(obj metav1.Object) string {
+	if owner := metav1.GetControllerOf(obj); owner != nil {
+		return fmt.Sprintf("%s %s", owner.Kind, owner.Name)
+	}
+	labels := obj.GetLabels()
+	if name := labels[kniv1beta1.OwnerNameLabel]; name != "" {
+		return fmt.Sprintf("KNICluster %s/%s", labels[kniv1beta1.OwnerNamespaceLabel], name)
+	}
+	return ""
+}
+
+// addRelatedObject adds obj to the RelatedObjects of instance
+func (r *ReconcileKNICluster) addRelatedObject(instance *kniv1beta1.KNICluster, obj runtime.Object) error {
+	objectRef, err := reference.GetReference(r.scheme, obj)
+	if err != nil {
+		return err
+	}
+	objectreferencesv1.SetObjectReference(&instance.Status.RelatedObjects, *objectRef)
+	return nil
+}
+
+// setConflictCondition reflects the conflicts found by the components
+func setConflictCondition(instance *kniv1beta1.KNICluster) {
+	if len(instance.Status.Conflicts) == 0 {
+		conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
+			Type:    kniv1beta1.ConditionConflict,
+			Status:  corev1.ConditionFalse,
+			Reason:  "NoConflicts",
+			Message: "No existing objects conflict with the spec",
+		})
+		return
+	}
+	message := ""
+	for i, conflict := range instance.Status.Conflicts {
+		if i > 0 {
+			message += "; "
+		}
+		message += fmt.Sprintf("%s %s/%s: %s", conflict.Kind, conflict.Namespace, conflict.Name, conflict.Message)
+	}
+	conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
+		Type:    kniv1beta1.ConditionConflict,
+		Status:  corev1.ConditionTrue,
+		Reason:  ReasonConflict,
+		Message: message,
+	})
+}
+
+// adoptionPolicies returns the adoption policies of instance, which are all
+// Fail when the spec has none
+func adoptionPolicies(instance *kniv1beta1.KNICluster) kniv1beta1.AdoptionSpec {
+	if instance.Spec.Adoption == nil {
+		return kniv1beta1.AdoptionSpec{
+			Subscriptions:  kniv1beta1.AdoptionFail,
+			OperatorGroups: kniv1beta1.AdoptionFail,
+			CatalogSources: kniv1beta1.AdoptionFail,
+		}
+	}
+	return *instance.Spec.Adoption
+}
+
+// legacyCatalogSource is the CatalogSource the operator created for the
+// default KNICluster before it kept track of what it owns
+var legacyCatalogSource = types.NamespacedName{Namespace: "olm", Name: "demo-catalog"}
+
+// legacySubscriptionName is the name of the Subscription and the
+// OperatorGroup the operator created in the namespace of the default
+// KNICluster before it kept track of what it owns
+const legacySubscriptionName = "kni"
+
+// isLegacyObject returns whether obj, of kind, has no owner and is one of the
+// objects the operator created for the default KNICluster before it kept
+// track of what it owns. They are taken over whatever the adoption policy, so
+// that upgrading the operator does not turn them into conflicts.
+func isLegacyObject(instance *kniv1beta1.KNICluster, kind string, obj metav1.Object) bool {
+	if otherOwner(obj) != "" {
+		return false
+	}
+	key, ok, err := GetDefaultKNINamespacedName()
+	if err != nil || !ok || key != (types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}) {
+		return false
+	}
+	switch kind {
+	case olm.CatalogSourceKind:
+		return obj.GetNamespace() == legacyCatalogSource.Namespace && obj.GetName() == legacyCatalogSource.Name
+	case olm.SubscriptionKind, olmv1.OperatorGroupKind:
+		return obj.GetNamespace() == instance.Namespace && obj.GetName() == legacySubscriptionName
+	}
+	return false
+}
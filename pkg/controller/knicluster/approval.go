@@ -0,0 +1,47 @@
+package knicluster
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReasonApprovalPending is the condition reason while a catalog switch waits
+// for approval
+const ReasonApprovalPending = "ApprovalPending"
+
+// requireApproval returns nil if the catalog may be switched from previous to
+// version. With manual approval, the switch waits in status.pendingUpgrade
+// until exactly that version is approved.
+func requireApproval(instance *kniv1beta1.KNICluster, version, image, previous string, reqLogger logr.Logger) error {
+	if instance.Spec.Approval != kniv1beta1.ApprovalManual || approvedVersion(instance, version) {
+		instance.Status.PendingUpgrade = nil
+		return nil
+	}
+
+	pending := instance.Status.PendingUpgrade
+	if pending == nil || pending.Version != version || pending.CatalogImage != image {
+		reqLogger.Info("Catalog switch is waiting for approval", "Version", version, "FromVersion", previous)
+		pending = &kniv1beta1.PendingUpgradeStatus{
+			Version:       version,
+			FromVersion:   previous,
+			CatalogImage:  image,
+			RequestedTime: metav1.Now(),
+		}
+		instance.Status.PendingUpgrade = pending
+	}
+	return &pendingError{
+		reason: ReasonApprovalPending,
+		err: fmt.Errorf("switching the catalog from %s to %s requires approval; set spec.approvedVersion or the %s annotation to %s",
+			previous, version, kniv1beta1.ApprovedVersionAnnotation, version),
+		waitForChange: true,
+	}
+}
+
+// approvedVersion returns whether version is approved by the spec or the
+// annotation
+func approvedVersion(instance *kniv1beta1.KNICluster, version string) bool {
+	return instance.Spec.ApprovedVersion == version || instance.Annotations[kniv1beta1.ApprovedVersionAnnotation] == version
+}
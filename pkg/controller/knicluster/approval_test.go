@@ -0,0 +1,220 @@
+package knicluster
+
+import (
+	"context"
+	"testing"
+
+	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// newOwnedCatalogSource returns the CatalogSource of instance as it is when
+// it serves version
+func newOwnedCatalogSource(instance *kniv1beta1.KNICluster, version string) *olm.CatalogSource {
+	labels := render.OwnerLabels(instance)
+	labels[kniv1beta1.CatalogVersionLabel] = version
+	return &olm.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      instance.Spec.Catalog.Name,
+			Namespace: instance.Spec.Catalog.Namespace,
+			Labels:    labels,
+		},
+		Spec: olm.CatalogSourceSpec{
+			SourceType: olm.SourceTypeGrpc,
+			Image:      testUpdateImage(version),
+		},
+	}
+}
+
+// newApprovalTestReconciler returns a reconciler of a cluster moving to 4.1.0
+// whose catalog, which needs manual approval, serves 4.0.0
+func newApprovalTestReconciler(t *testing.T) (*ReconcileKNICluster, *fakeClient) {
+	instance := newTestKNICluster()
+	instance.Spec.Approval = kniv1beta1.ApprovalManual
+	instance.Spec.Catalog.Image.SkipContentValidation = true
+	clusterOperator := &osconfigv1.ClusterOperator{ObjectMeta: metav1.ObjectMeta{Name: ClusterOperatorName}}
+	return newTestReconciler(t, instance, newOwnedCatalogSource(instance, "4.0.0"), testClusterVersion("4.1.0"), clusterOperator)
+}
+
+// catalogSourceVersion returns the version the test CatalogSource serves
+func catalogSourceVersion(t *testing.T, c *fakeClient) string {
+	t.Helper()
+	found := &olm.CatalogSource{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "olm", Name: "demo-catalog"}, found); err != nil {
+		t.Fatal(err)
+	}
+	if found.Spec.Image != testUpdateImage(found.Labels[kniv1beta1.CatalogVersionLabel]) {
+		t.Errorf("CatalogSource image %s does not match its version label %s", found.Spec.Image, found.Labels[kniv1beta1.CatalogVersionLabel])
+	}
+	return found.Labels[kniv1beta1.CatalogVersionLabel]
+}
+
+// updateTestKNICluster changes the stored test KNICluster with mutate
+func updateTestKNICluster(t *testing.T, c *fakeClient, mutate func(*kniv1beta1.KNICluster)) {
+	t.Helper()
+	instance := &kniv1beta1.KNICluster{}
+	if err := c.Get(context.TODO(), testKey, instance); err != nil {
+		t.Fatal(err)
+	}
+	mutate(instance)
+	if err := c.Update(context.TODO(), instance); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApprovalWaitBlocksOnlyTheSwitch(t *testing.T) {
+	r, c := newApprovalTestReconciler(t)
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if version := catalogSourceVersion(t, c); version != "4.0.0" {
+		t.Errorf("catalog switched to %s before approval", version)
+	}
+	pending := reconciled.Status.PendingUpgrade
+	if pending == nil || pending.Version != "4.1.0" || pending.FromVersion != "4.0.0" || pending.CatalogImage != testUpdateImage("4.1.0") {
+		t.Errorf("pending upgrade is %v, want 4.0.0 to 4.1.0", pending)
+	}
+	catalog := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, kniv1beta1.ConditionCatalogReady)
+	if catalog == nil || catalog.Reason != ReasonApprovalPending {
+		t.Errorf("CatalogReady condition is %v, want reason %s", catalog, ReasonApprovalPending)
+	}
+	degraded := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, conditionsv1.ConditionDegraded)
+	if degraded != nil && degraded.Status == corev1.ConditionTrue {
+		t.Errorf("waiting for approval degraded the KNICluster: %v", degraded)
+	}
+
+	// the other components and the cluster-wide reporting carry on
+	subscriptions := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, kniv1beta1.ConditionSubscriptionsReady)
+	if subscriptions == nil || subscriptions.Status != corev1.ConditionTrue {
+		t.Errorf("SubscriptionsReady condition is %v, want True", subscriptions)
+	}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: testKey.Namespace, Name: "kni"}, &olm.Subscription{}); err != nil {
+		t.Errorf("getting the Subscription: %v", err)
+	}
+	co := &osconfigv1.ClusterOperator{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: ClusterOperatorName}, co); err != nil {
+		t.Fatal(err)
+	}
+	if !clusterOperatorUpgradeable(co, osconfigv1.ConditionTrue) {
+		t.Errorf("ClusterOperator conditions are %v, want Upgradeable True", co.Status.Conditions)
+	}
+	if !hasRelatedObject(reconciled, "CatalogSource", "demo-catalog") {
+		t.Errorf("related objects %v lack the CatalogSource", reconciled.Status.RelatedObjects)
+	}
+}
+
+func TestApprovalApproved(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		approve func(*kniv1beta1.KNICluster)
+	}{
+		{
+			name:    "spec",
+			approve: func(instance *kniv1beta1.KNICluster) { instance.Spec.ApprovedVersion = "4.1.0" },
+		},
+		{
+			name: "annotation",
+			approve: func(instance *kniv1beta1.KNICluster) {
+				instance.Annotations = map[string]string{kniv1beta1.ApprovedVersionAnnotation: "4.1.0"}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r, c := newApprovalTestReconciler(t)
+			if _, _, err := reconcileTestKey(t, r); err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+
+			updateTestKNICluster(t, c, tc.approve)
+			reconciled, _, err := reconcileTestKey(t, r)
+			if err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+
+			if version := catalogSourceVersion(t, c); version != "4.1.0" {
+				t.Errorf("catalog serves %s after approval, want 4.1.0", version)
+			}
+			if reconciled.Status.PendingUpgrade != nil {
+				t.Errorf("pending upgrade is %v after the switch", reconciled.Status.PendingUpgrade)
+			}
+		})
+	}
+}
+
+func TestApprovalOfAnotherVersion(t *testing.T) {
+	r, c := newApprovalTestReconciler(t)
+	if _, _, err := reconcileTestKey(t, r); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	updateTestKNICluster(t, c, func(instance *kniv1beta1.KNICluster) { instance.Spec.ApprovedVersion = "4.0.5" })
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if version := catalogSourceVersion(t, c); version != "4.0.0" {
+		t.Errorf("catalog switched to %s, which was not approved", version)
+	}
+	if pending := reconciled.Status.PendingUpgrade; pending == nil || pending.Version != "4.1.0" {
+		t.Errorf("pending upgrade is %v, want 4.1.0", pending)
+	}
+}
+
+func TestApprovalVersionChangesWhileWaiting(t *testing.T) {
+	r, c := newApprovalTestReconciler(t)
+	if _, _, err := reconcileTestKey(t, r); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	// the cluster moves on to 4.2.0 as 4.1.0 gets approved
+	cv := &osconfigv1.ClusterVersion{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "version"}, cv); err != nil {
+		t.Fatal(err)
+	}
+	cv.Spec.DesiredUpdate.Version = "4.2.0"
+	if err := c.Update(context.TODO(), cv); err != nil {
+		t.Fatal(err)
+	}
+	updateTestKNICluster(t, c, func(instance *kniv1beta1.KNICluster) { instance.Spec.ApprovedVersion = "4.1.0" })
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if version := catalogSourceVersion(t, c); version != "4.0.0" {
+		t.Errorf("catalog switched to %s, which was not approved", version)
+	}
+	pending := reconciled.Status.PendingUpgrade
+	if pending == nil || pending.Version != "4.2.0" || pending.CatalogImage != testUpdateImage("4.2.0") {
+		t.Errorf("pending upgrade is %v, want 4.2.0", pending)
+	}
+
+	updateTestKNICluster(t, c, func(instance *kniv1beta1.KNICluster) { instance.Spec.ApprovedVersion = "4.2.0" })
+	if _, _, err := reconcileTestKey(t, r); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if version := catalogSourceVersion(t, c); version != "4.2.0" {
+		t.Errorf("catalog serves %s after approval, want 4.2.0", version)
+	}
+}
+
+// hasRelatedObject returns whether the related objects of instance include
+// the object of kind named name
+func hasRelatedObject(instance *kniv1beta1.KNICluster, kind, name string) bool {
+	for _, ref := range instance.Status.RelatedObjects {
+		if ref.Kind == kind && ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
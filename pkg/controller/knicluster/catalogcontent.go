@@ -0,0 +1,225 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/registry"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// ReasonCatalogContentInvalid is the condition reason when a new catalog
+	// image is refused because it lacks a managed package or channel
+	ReasonCatalogContentInvalid = "CatalogContentInvalid"
+	// ReasonCatalogValidationPending is the condition reason while the
+	// content of a new catalog image is being checked
+	ReasonCatalogValidationPending = "CatalogValidationPending"
+
+	// stagingCatalogSuffix is appended to the CatalogSource name to name the
+	// CatalogSource that serves a new image while it is checked
+	stagingCatalogSuffix = "-staging"
+	// catalogValidationTimeout limits how long checking a catalog takes
+	catalogValidationTimeout = 30 * time.Second
+)
+
+// validateCatalogContent checks that the catalog served by the image of
+// catalogsource has every managed package and channel, before the
+// CatalogSource is switched to it. While it is checked, the image is served by
+// a staging CatalogSource. The result is kept in the status, so that each
+// image is checked once.
+func (r *ReconcileKNICluster) validateCatalogContent(instance *kniv1beta1.KNICluster, catalogsource *olm.CatalogSource, reqLogger logr.Logger) error {
+	if instance.Spec.Catalog.Image.SkipContentValidation {
+		return nil
+	}
+	if _, dryRun := r.client.(*recordingClient); dryRun {
+		// checking requires running the staging catalog
+		return nil
+	}
+
+	image := catalogsource.Spec.Image
+	if validation := instance.Status.CatalogValidation; validation != nil && validation.Image == image {
+		if validation.Valid {
+			return nil
+		}
+		return &reasonError{reason: ReasonCatalogContentInvalid, err: fmt.Errorf("catalog image %s: %s", image, validation.Message)}
+	}
+
+	address, err := r.serveCatalog(instance, stagingCatalogSource(catalogsource), reqLogger)
+	if err != nil {
+		return err
+	}
+	if address == "" {
+		return &pendingError{reason: ReasonCatalogValidationPending, err: fmt.Errorf("waiting for the staging catalog for %s to be served", image)}
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), catalogValidationTimeout)
+	defer cancel()
+	packages, err := r.catalogPackages(ctx, address, instance.Spec.Operators)
+	if err != nil {
+		return &pendingError{reason: ReasonCatalogValidationPending, err: fmt.Errorf("querying the staging catalog for %s: %v", image, err)}
+	}
+	heads, missing := channelHeads(packages, instance.Spec.Operators)
+	validation := &kniv1beta1.CatalogValidationStatus{
+		Image:          image,
+		Valid:          len(missing) == 0,
+		Message:        strings.Join(missing, "; "),
+		ChannelHeads:   heads,
+		ValidationTime: metav1.Now(),
+	}
+	instance.Status.CatalogValidation = validation
+	reqLogger.Info("Checked the new catalog image", "Image", image, "Valid", validation.Valid)
+
+	if err := r.ensureStagingCatalogSourceDeleted(catalogsource); err != nil {
+		return err
+	}
+	if !validation.Valid {
+		return &reasonError{reason: ReasonCatalogContentInvalid, err: fmt.Errorf("catalog image %s: %s", image, validation.Message)}
+	}
+	return nil
+}
+
+// validateConfigMapContent checks that the manifests of a ConfigMap catalog
+// for version have every managed package and channel. They are read from
+// local files, so unlike images they are checked each time they are written.
+func validateConfigMapContent(instance *kniv1beta1.KNICluster, version string) error {
+	packages, err := render.CatalogPackages(instance.Spec.Catalog, version)
+	if err != nil {
+		return err
+	}
+	if _, missing := channelHeads(packages, instance.Spec.Operators); len(missing) > 0 {
+		return &reasonError{reason: ReasonCatalogContentInvalid, err: fmt.Errorf("catalog manifests for %s: %s", version, strings.Join(missing, "; "))}
+	}
+	return nil
+}
+
+// serveCatalog ensures that source exists and serves its image, and returns
+// the address of its registry service, or "" while it is starting. source is
+// owned by instance, so that it does not outlive it.
+func (r *ReconcileKNICluster) serveCatalog(instance *kniv1beta1.KNICluster, source *olm.CatalogSource, reqLogger logr.Logger) (string, error) {
+	found := &olm.CatalogSource{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: source.Name, Namespace: source.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		if err := r.setOwner(instance, source); err != nil {
+			return "", err
+		}
+		reqLogger.Info("Creating a CatalogSource to query a catalog image", "CatalogSource.Namespace", source.Namespace, "CatalogSource.Name", source.Name, "Image", source.Spec.Image)
+		return "", r.client.Create(context.TODO(), source)
+	} else if err != nil {
+		return "", err
+	}
+	if found.Spec.Image != source.Spec.Image || found.Spec.SourceType != source.Spec.SourceType {
+		reqLogger.Info("Updating a CatalogSource to query a catalog image", "CatalogSource.Namespace", source.Namespace, "CatalogSource.Name", source.Name, "Image", source.Spec.Image)
+		found.Spec.SourceType = source.Spec.SourceType
+		found.Spec.Image = source.Spec.Image
+		return "", r.client.Update(context.TODO(), found)
+	}
+	if found.Status.RegistryServiceStatus == nil {
+		return "", nil
+	}
+	return found.Status.RegistryServiceStatus.Address(), nil
+}
+
+// catalogPackages queries the registry service at address for the packages
+// of operators. Packages the catalog lacks are left out.
+func (r *ReconcileKNICluster) catalogPackages(ctx context.Context, address string, operators []kniv1beta1.OperatorSpec) (map[string]*registry.Package, error) {
+	client, err := r.dialCatalog(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	names, err := client.ListPackages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	available := map[string]bool{}
+	for _, name := range names {
+		available[name] = true
+	}
+
+	packages := map[string]*registry.Package{}
+	for _, operator := range operators {
+		if !available[operator.Package] || packages[operator.Package] != nil {
+			continue
+		}
+		pkg, err := client.GetPackage(ctx, operator.Package)
+		if err != nil {
+			return nil, err
+		}
+		packages[operator.Package] = pkg
+	}
+	return packages, nil
+}
+
+// channelHeads returns the head CSV of the channel each operator subscribes
+// to, and a message for each package or channel that is missing
+func channelHeads(packages map[string]*registry.Package, operators []kniv1beta1.OperatorSpec) ([]kniv1beta1.ChannelHead, []string) {
+	var heads []kniv1beta1.ChannelHead
+	var missing []string
+	for _, operator := range operators {
+		pkg := packages[operator.Package]
+		if pkg == nil {
+			missing = append(missing, fmt.Sprintf("package %s of operator %s is missing", operator.Package, operator.Name))
+			continue
+		}
+
+		var channels []string
+		var head *registry.Channel
+		for _, channel := range pkg.Channels {
+			channels = append(channels, channel.Name)
+			if channel.Name == operator.Channel {
+				head = channel
+			}
+		}
+		if head == nil {
+			missing = append(missing, fmt.Sprintf("channel %s of package %s is missing, found %s",
+				operator.Channel, operator.Package, strings.Join(channels, ", ")))
+			continue
+		}
+		heads = append(heads, kniv1beta1.ChannelHead{
+			Package: operator.Package,
+			Channel: operator.Channel,
+			CSV:     head.CsvName,
+		})
+	}
+	return heads, missing
+}
+
+// stagingCatalogSource returns the CatalogSource that serves the image of
+// catalogsource while it is checked
+func stagingCatalogSource(catalogsource *olm.CatalogSource) *olm.CatalogSource {
+	return temporaryCatalogSource(catalogsource, stagingCatalogSuffix, "staging")
+}
+
+// temporaryCatalogSource returns a copy of catalogsource, named with suffix,
+// that serves its catalog while the operator queries it. It is labelled and
+// described as temporary, so that it is not mistaken for a catalog to install
+// operators from.
+func temporaryCatalogSource(catalogsource *olm.CatalogSource, suffix, role string) *olm.CatalogSource {
+	temporary := catalogsource.DeepCopy()
+	temporary.ObjectMeta = metav1.ObjectMeta{
+		Name:      catalogsource.Name + suffix,
+		Namespace: catalogsource.Namespace,
+		Labels:    map[string]string{kniv1beta1.TemporaryCatalogLabel: role},
+	}
+	temporary.Spec.DisplayName = fmt.Sprintf("%s (%s, temporary)", catalogsource.Spec.DisplayName, role)
+	temporary.Spec.Description = fmt.Sprintf("Temporary %s copy of %s, used by the KNI operator to query the catalog. Do not install operators from it.", role, catalogsource.Name)
+	return temporary
+}
+
+func (r *ReconcileKNICluster) ensureStagingCatalogSourceDeleted(catalogsource *olm.CatalogSource) error {
+	err := r.client.Delete(context.TODO(), stagingCatalogSource(catalogsource))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
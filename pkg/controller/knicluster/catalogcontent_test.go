@@ -0,0 +1,203 @@
+package knicluster
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/registry"
+	"github.com/mhrivnak/kni-operator/pkg/registry/registrytest"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var stagingKey = types.NamespacedName{Namespace: "olm", Name: "demo-catalog" + stagingCatalogSuffix}
+
+// testCatalog is a catalog served to the reconciler, whatever address it
+// dials
+type testCatalog struct {
+	mu sync.Mutex
+	// dialed lists the addresses dialed
+	dialed []string
+}
+
+// serveTestCatalog serves packages to r
+func serveTestCatalog(t *testing.T, r *ReconcileKNICluster, packages ...*registry.Package) *testCatalog {
+	server, err := registrytest.NewServer(packages...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+
+	catalog := &testCatalog{}
+	r.dialCatalog = func(ctx context.Context, address string) (*registry.CatalogClient, error) {
+		catalog.mu.Lock()
+		catalog.dialed = append(catalog.dialed, address)
+		catalog.mu.Unlock()
+		return registry.DialCatalog(ctx, server.Address)
+	}
+	return catalog
+}
+
+func (c *testCatalog) dialedAddresses() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.dialed...)
+}
+
+// testEtcdPackage returns the etcd package of the test catalogs, whose
+// channel is channel
+func testEtcdPackage(channel, csv string) *registry.Package {
+	return &registry.Package{
+		Name:               "etcd",
+		DefaultChannelName: channel,
+		Channels:           []*registry.Channel{{Name: channel, CsvName: csv}},
+	}
+}
+
+// newStagingTestReconciler returns a reconciler of a cluster moving to 4.1.0
+// whose catalog serves 4.0.0, and whose new catalog image gets checked
+func newStagingTestReconciler(t *testing.T) (*ReconcileKNICluster, *fakeClient) {
+	instance := newTestKNICluster()
+	clusterOperator := &osconfigv1.ClusterOperator{ObjectMeta: metav1.ObjectMeta{Name: ClusterOperatorName}}
+	return newTestReconciler(t, instance, newOwnedCatalogSource(instance, "4.0.0"), testClusterVersion("4.1.0"), clusterOperator)
+}
+
+// serveStagingCatalog sets the registry service of the staging CatalogSource,
+// as OLM does once it serves the catalog
+func serveStagingCatalog(t *testing.T, c *fakeClient) {
+	t.Helper()
+	staging := &olm.CatalogSource{}
+	if err := c.Get(context.TODO(), stagingKey, staging); err != nil {
+		t.Fatal(err)
+	}
+	staging.Status.RegistryServiceStatus = &olm.RegistryServiceStatus{
+		Protocol:         "grpc",
+		ServiceName:      stagingKey.Name,
+		ServiceNamespace: stagingKey.Namespace,
+		Port:             "50051",
+	}
+	if err := c.Update(context.TODO(), staging); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCatalogContentValidatedOnStaging(t *testing.T) {
+	r, c := newStagingTestReconciler(t)
+	catalog := serveTestCatalog(t, r, testEtcdPackage("singlenamespace-alpha", "etcdoperator.v4.1.0"))
+
+	// the new image is served by a staging CatalogSource first
+	for i := 0; i < 2; i++ {
+		reconciled, _, err := reconcileTestKey(t, r)
+		if err != nil {
+			t.Fatalf("reconcile %d: %v", i, err)
+		}
+		condition := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, kniv1beta1.ConditionCatalogReady)
+		if condition == nil || condition.Reason != ReasonCatalogValidationPending {
+			t.Errorf("CatalogReady condition is %v, want reason %s", condition, ReasonCatalogValidationPending)
+		}
+	}
+	staging := &olm.CatalogSource{}
+	if err := c.Get(context.TODO(), stagingKey, staging); err != nil {
+		t.Fatalf("getting the staging CatalogSource: %v", err)
+	}
+	if staging.Spec.Image != testUpdateImage("4.1.0") || staging.Labels[kniv1beta1.TemporaryCatalogLabel] != "staging" {
+		t.Errorf("staging CatalogSource serves %s with labels %v, want the new image", staging.Spec.Image, staging.Labels)
+	}
+	if version := catalogSourceVersion(t, c); version != "4.0.0" {
+		t.Errorf("catalog switched to %s before the new image was checked", version)
+	}
+	if dialed := catalog.dialedAddresses(); len(dialed) != 0 {
+		t.Errorf("dialed %v before the staging catalog was served", dialed)
+	}
+
+	serveStagingCatalog(t, c)
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if dialed := catalog.dialedAddresses(); len(dialed) != 1 || dialed[0] != "demo-catalog-staging.olm.svc.cluster.local:50051" {
+		t.Errorf("dialed %v, want the staging registry service", dialed)
+	}
+	validation := reconciled.Status.CatalogValidation
+	if validation == nil || !validation.Valid || validation.Image != testUpdateImage("4.1.0") {
+		t.Fatalf("catalog validation is %v, want the new image valid", validation)
+	}
+	if heads := validation.ChannelHeads; len(heads) != 1 || heads[0].CSV != "etcdoperator.v4.1.0" {
+		t.Errorf("channel heads are %v, want etcdoperator.v4.1.0", heads)
+	}
+	if err := c.Get(context.TODO(), stagingKey, &olm.CatalogSource{}); !errors.IsNotFound(err) {
+		t.Errorf("getting the staging CatalogSource returned %v, want it deleted", err)
+	}
+	if version := catalogSourceVersion(t, c); version != "4.1.0" {
+		t.Errorf("catalog serves %s after the check, want 4.1.0", version)
+	}
+}
+
+func TestCatalogContentInvalid(t *testing.T) {
+	r, c := newStagingTestReconciler(t)
+	catalog := serveTestCatalog(t, r, testEtcdPackage("alpha", "etcdoperator.v4.1.0"))
+	if _, _, err := reconcileTestKey(t, r); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	serveStagingCatalog(t, c)
+
+	for i := 0; i < 2; i++ {
+		reconciled, _, err := reconcileTestKey(t, r)
+		if err == nil {
+			t.Fatalf("reconcile %d succeeded, want the missing channel", i)
+		}
+		condition := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, kniv1beta1.ConditionCatalogReady)
+		if condition == nil || condition.Reason != ReasonCatalogContentInvalid {
+			t.Errorf("CatalogReady condition is %v, want reason %s", condition, ReasonCatalogContentInvalid)
+		}
+		if validation := reconciled.Status.CatalogValidation; validation == nil || validation.Valid {
+			t.Errorf("catalog validation is %v, want the new image invalid", validation)
+		}
+	}
+
+	// the result is kept, so the image is checked once
+	if dialed := catalog.dialedAddresses(); len(dialed) != 1 {
+		t.Errorf("dialed %v, want the staging catalog queried once", dialed)
+	}
+	if err := c.Get(context.TODO(), stagingKey, &olm.CatalogSource{}); !errors.IsNotFound(err) {
+		t.Errorf("getting the staging CatalogSource returned %v, want it deleted", err)
+	}
+	if version := catalogSourceVersion(t, c); version != "4.0.0" {
+		t.Errorf("catalog switched to %s, which lacks the channel", version)
+	}
+}
+
+func TestChannelHeads(t *testing.T) {
+	operators := []kniv1beta1.OperatorSpec{
+		{Name: "kni", Package: "etcd", Channel: "singlenamespace-alpha"},
+		{Name: "monitoring", Package: "prometheus", Channel: "beta"},
+		{Name: "storage", Package: "rook", Channel: "stable"},
+	}
+	packages := map[string]*registry.Package{
+		"etcd": testEtcdPackage("singlenamespace-alpha", "etcdoperator.v4.1.0"),
+		"prometheus": {Name: "prometheus", Channels: []*registry.Channel{
+			{Name: "alpha", CsvName: "prometheusoperator.0.27.0"},
+			{Name: "preview", CsvName: "prometheusoperator.0.22.2"},
+		}},
+	}
+
+	heads, missing := channelHeads(packages, operators)
+
+	if len(heads) != 1 || heads[0] != (kniv1beta1.ChannelHead{Package: "etcd", Channel: "singlenamespace-alpha", CSV: "etcdoperator.v4.1.0"}) {
+		t.Errorf("channel heads are %v, want the etcd head", heads)
+	}
+	want := []string{
+		"channel beta of package prometheus is missing, found alpha, preview",
+		"package rook of operator storage is missing",
+	}
+	if len(missing) != len(want) || missing[0] != want[0] || missing[1] != want[1] {
+		t.Errorf("missing is %q, want %q", missing, want)
+	}
+}
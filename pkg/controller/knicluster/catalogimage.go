@@ -0,0 +1,188 @@
+package knicluster
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/registry"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// registryTimeout limits each request to a registry
+const registryTimeout = 30 * time.Second
+
+// globalPullSecret is the pull secret the nodes of an OpenShift cluster use
+var globalPullSecret = types.NamespacedName{Namespace: "openshift-config", Name: "pull-secret"}
+
+// registryClient returns a client for the catalog image's registry, with the
+// credentials of the global pull secret, if there is one, and of the catalog's
+// pull secret, which take precedence
+func (r *ReconcileKNICluster) registryClient(instance *kniv1beta1.KNICluster) (*registry.Client, error) {
+	spec := instance.Spec.Catalog.Image
+	client := &registry.Client{Insecure: spec.InsecureRegistry, Timeout: registryTimeout, Credentials: registry.Credentials{}}
+
+	secrets := []types.NamespacedName{globalPullSecret}
+	if spec.PullSecret != "" {
+		secrets = append(secrets, types.NamespacedName{Namespace: instance.Namespace, Name: spec.PullSecret})
+	}
+	for _, name := range secrets {
+		secret := &corev1.Secret{}
+		err := r.apiReader.Get(context.TODO(), name, secret)
+		if err != nil {
+			if name == globalPullSecret && (errors.IsNotFound(err) || errors.IsForbidden(err)) {
+				// not an OpenShift cluster, or not allowed to use its credentials
+				continue
+			}
+			return nil, fmt.Errorf("reading pull secret %s: %v", name, err)
+		}
+		data, ok := secret.Data[corev1.DockerConfigJsonKey]
+		if !ok {
+			data, ok = secret.Data[corev1.DockerConfigKey]
+		}
+		if !ok {
+			return nil, fmt.Errorf("pull secret %s has neither a %s nor a %s key", name, corev1.DockerConfigJsonKey, corev1.DockerConfigKey)
+		}
+		credentials, err := registry.ParseDockerConfig(data)
+		if err != nil {
+			return nil, fmt.Errorf("pull secret %s: %v", name, err)
+		}
+		for host, credential := range credentials {
+			client.Credentials[host] = credential
+		}
+	}
+	return client, nil
+}
+
+// catalogImage returns the image the CatalogSource should use instead of
+// image, after rewriting it to use a mirror and pinning its digest
+func (r *ReconcileKNICluster) catalogImage(instance *kniv1beta1.KNICluster, image string, reqLogger logr.Logger) (kniv1beta1.ImageStatus, error) {
+	status := kniv1beta1.ImageStatus{Consumer: render.ConsumerCatalog, Image: image}
+
+	mirrors, err := r.imageMirrors(instance)
+	if err != nil {
+		return status, err
+	}
+	if mirrored, ok := render.MirrorImage(mirrors, image); ok {
+		status.Image = mirrored
+		status.Source = image
+	}
+
+	pinned, err := r.pinCatalogImage(instance, status.Image, reqLogger)
+	if err != nil {
+		return status, err
+	}
+	if pinned != status.Image {
+		status.Source = image
+		status.Image = pinned
+	}
+	return status, nil
+}
+
+// pinsDigest returns whether the catalog image is pinned to a digest. Verified
+// images always are, so that the CatalogSource runs the exact image whose
+// signature was checked.
+func pinsDigest(spec kniv1beta1.CatalogImageSpec) bool {
+	return spec.PinDigest || spec.Verification != nil
+}
+
+// pinCatalogImage returns image qualified by the digest its tag points to, if
+// the spec asks for digest pinning. The tag is resolved again when the image
+// changes or the resolve interval has passed.
+func (r *ReconcileKNICluster) pinCatalogImage(instance *kniv1beta1.KNICluster, image string, reqLogger logr.Logger) (string, error) {
+	spec := instance.Spec.Catalog.Image
+	if !pinsDigest(spec) {
+		instance.Status.CatalogDigest = nil
+		return image, nil
+	}
+
+	resolved := instance.Status.CatalogDigest
+	if resolved == nil || resolved.Image != image || resolveDue(spec.ResolveInterval, resolved.ResolvedTime) {
+		client, err := r.registryClient(instance)
+		if err != nil {
+			return "", err
+		}
+		digest, err := client.ResolveDigest(context.TODO(), image)
+		if err != nil {
+			return "", err
+		}
+		if resolved != nil && resolved.Image == image && resolved.Digest != digest {
+			reqLogger.Info("Catalog image tag has moved", "Image", image, "OldDigest", resolved.Digest, "NewDigest", digest)
+		}
+		resolved = &kniv1beta1.CatalogDigestStatus{
+			Image:        image,
+			Digest:       digest,
+			ResolvedTime: metav1.Now(),
+		}
+		instance.Status.CatalogDigest = resolved
+	}
+	return registry.PinDigest(image, resolved.Digest), nil
+}
+
+// resolveDue returns whether a tag resolved at the given time should be
+// resolved again
+func resolveDue(interval *metav1.Duration, resolvedTime metav1.Time) bool {
+	return interval != nil && interval.Duration > 0 && time.Since(resolvedTime.Time) >= interval.Duration
+}
+
+// resolveRequeueAfter returns when the KNICluster should be reconciled again
+// to re-resolve its catalog image tag, or zero if it need not be
+func resolveRequeueAfter(instance *kniv1beta1.KNICluster) time.Duration {
+	spec := instance.Spec.Catalog.Image
+	if !pinsDigest(spec) || spec.ResolveInterval == nil || instance.Status.CatalogDigest == nil {
+		return 0
+	}
+	after := spec.ResolveInterval.Duration - time.Since(instance.Status.CatalogDigest.ResolvedTime.Time)
+	if after <= 0 {
+		// already due, for example when the registry was unavailable
+		return time.Second
+	}
+	return after
+}
+
+// verifyCatalogImage checks that image is signed by one of the keys the spec
+// trusts, if the spec asks for verification. image must be pinned to a
+// digest, since a tag could be pushed again after the check.
+func (r *ReconcileKNICluster) verifyCatalogImage(instance *kniv1beta1.KNICluster, image string) error {
+	spec := instance.Spec.Catalog.Image
+	if spec.Verification == nil {
+		return nil
+	}
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return &reasonError{reason: ReasonSignatureVerificationFailed, err: err}
+	}
+	if ref.Digest == "" {
+		return &reasonError{reason: ReasonSignatureVerificationFailed, err: fmt.Errorf("image %s is not pinned to a digest", image)}
+	}
+
+	secret := &corev1.Secret{}
+	err = r.apiReader.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: spec.Verification.PublicKeysSecret}, secret)
+	if err != nil {
+		return &reasonError{reason: ReasonSignatureVerificationFailed, err: fmt.Errorf("reading public keys: %v", err)}
+	}
+	var keys []crypto.PublicKey
+	for name, data := range secret.Data {
+		parsed, err := registry.ParsePublicKeys(data)
+		if err != nil {
+			return &reasonError{reason: ReasonSignatureVerificationFailed, err: fmt.Errorf("public key %s: %v", name, err)}
+		}
+		keys = append(keys, parsed...)
+	}
+
+	client, err := r.registryClient(instance)
+	if err != nil {
+		return &reasonError{reason: ReasonSignatureVerificationFailed, err: err}
+	}
+	if err := client.VerifySignature(context.TODO(), image, ref.Digest, keys); err != nil {
+		return &reasonError{reason: ReasonSignatureVerificationFailed, err: err}
+	}
+	return nil
+}
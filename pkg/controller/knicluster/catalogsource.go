@@ -0,0 +1,266 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	objectreferencesv1 "github.com/djzager/custom-resource-status/objectreferences/v1"
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/reference"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (r *ReconcileKNICluster) ensureCatalogSource(instance *kniv1beta1.KNICluster, reqLogger logr.Logger) error {
+	cv, err := r.clusterVersion()
+	if err != nil {
+		return err
+	}
+	if cv.Spec.DesiredUpdate == nil {
+		return fmt.Errorf("ClusterVersion %s has no desired update", cv.Name)
+	}
+
+	version := cv.Spec.DesiredUpdate.Version
+	rollback := instance.Status.Rollback
+	if rollback != nil && rollback.FromVersion != version {
+		// the cluster is moving on, so the rollback is over
+		rollback = nil
+		instance.Status.Rollback = nil
+	}
+	if rollback != nil {
+		version = rollback.ToVersion
+	}
+
+	// Check if this CatalogSource already exists. One that the operator did
+	// not create is claimed before anything backing it changes.
+	catalog := instance.Spec.Catalog
+	found := &olm.CatalogSource{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: catalog.Name, Namespace: catalog.Namespace}, found)
+	exists := err == nil
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if exists {
+		mismatch := ""
+		if sourceType := render.CatalogSource(catalog, version).Spec.SourceType; found.Spec.SourceType != sourceType {
+			mismatch = fmt.Sprintf("its source type is %s instead of %s", found.Spec.SourceType, sourceType)
+		}
+		managed, err := r.claim(instance, found, olm.CatalogSourceKind, adoptionPolicies(instance).CatalogSources, mismatch, reqLogger)
+		if err != nil {
+			return err
+		}
+		if !managed {
+			return r.addRelatedObject(instance, found)
+		}
+	}
+
+	// ensure CatalogSource exists
+	catalogsource := render.CatalogSource(instance.Spec.Catalog, version)
+	if catalogsource.Spec.Image != "" {
+		image, err := r.catalogImage(instance, catalogsource.Spec.Image, reqLogger)
+		if err != nil {
+			return err
+		}
+		instance.Status.Images = append(instance.Status.Images, image)
+		catalogsource.Spec.Image = image.Image
+	}
+
+	if !exists {
+		if err := r.ensureCatalogConfigMap(instance, version, reqLogger); err != nil {
+			return err
+		}
+		if catalogsource.Spec.Image != "" {
+			if err := r.verifyCatalogImage(instance, catalogsource.Spec.Image); err != nil {
+				return err
+			}
+		}
+		if err := r.setOwner(instance, catalogsource); err != nil {
+			return err
+		}
+		reqLogger.Info("Creating a new CatalogSource", "CatalogSource.Namespace", catalogsource.Namespace, "CatalogSource.Name", catalogsource.Name)
+		err = r.client.Create(context.TODO(), catalogsource)
+		if err != nil {
+			return err
+		}
+
+		// created successfully - don't requeue
+		return nil
+	}
+
+	// no switch waits for approval once the CatalogSource serves the version
+	if found.Labels[kniv1beta1.CatalogVersionLabel] == version {
+		instance.Status.PendingUpgrade = nil
+	}
+
+	// already exists - don't requeue
+	reqLogger.Info("CatalogSource already exists", "CatalogSource.Namespace", found.Namespace, "CatalogSource.Name", found.Name)
+
+	instance.Status.CatalogImage = found.Spec.Image
+	instance.Status.CatalogVersion = found.Labels[kniv1beta1.CatalogVersionLabel]
+
+	// update the source if necessary. A switch is authorized once, before
+	// anything that backs the catalog changes.
+	update := found.Spec.SourceType != catalogsource.Spec.SourceType ||
+		found.Spec.Image != catalogsource.Spec.Image ||
+		found.Spec.ConfigMap != catalogsource.Spec.ConfigMap ||
+		found.Labels[kniv1beta1.CatalogVersionLabel] != version
+	if update {
+		if found.Labels[kniv1beta1.CatalogVersionLabel] != version && rollback == nil {
+			if err := r.authorizeSwitch(instance, version, catalogsource.Spec.Image, found.Labels[kniv1beta1.CatalogVersionLabel], reqLogger); err != nil {
+				// the CatalogSource keeps serving the current version
+				if refErr := r.addRelatedObject(instance, found); refErr != nil {
+					return refErr
+				}
+				return err
+			}
+		}
+		if catalogsource.Spec.Image != "" && catalogsource.Spec.Image != found.Spec.Image {
+			if err := r.verifyCatalogImage(instance, catalogsource.Spec.Image); err != nil {
+				return err
+			}
+			if err := r.validateCatalogContent(instance, catalogsource, reqLogger); err != nil {
+				return err
+			}
+		}
+		switching := found.Spec.Image != catalogsource.Spec.Image || found.Labels[kniv1beta1.CatalogVersionLabel] != version
+		if switching && rollback == nil {
+			if err := r.runPreSwitchHooks(instance, version, catalogsource.Spec.Image, found.Labels[kniv1beta1.CatalogVersionLabel], reqLogger); err != nil {
+				return err
+			}
+		}
+	}
+	// OLM serves the content of a ConfigMap as soon as it changes
+	if err := r.ensureCatalogConfigMap(instance, version, reqLogger); err != nil {
+		return err
+	}
+	if update {
+		reqLogger.Info("Updating the CatalogSource", "CatalogSource.Namespace", found.Namespace, "CatalogSource.Name", found.Name)
+		found.Spec.SourceType = catalogsource.Spec.SourceType
+		found.Spec.Image = catalogsource.Spec.Image
+		found.Spec.ConfigMap = catalogsource.Spec.ConfigMap
+		if found.Labels == nil {
+			found.Labels = map[string]string{}
+		}
+		previous := found.Labels[kniv1beta1.CatalogVersionLabel]
+		found.Labels[kniv1beta1.CatalogVersionLabel] = version
+		err = r.client.Update(context.TODO(), found)
+		if err != nil {
+			return err
+		}
+		if rollback == nil {
+			r.queueUpgradeStarted(instance, previous, version)
+		}
+	}
+
+	// Add it to the list of RelatedObjects if found
+	objectRef, err := reference.GetReference(r.scheme, found)
+	if err != nil {
+		return err
+	}
+	// Add it to the list of RelatedObjects if found
+	objectreferencesv1.SetObjectReference(&instance.Status.RelatedObjects, *objectRef)
+
+	return nil
+}
+
+// clusterVersion returns the cluster's only ClusterVersion
+func (r *ReconcileKNICluster) clusterVersion() (*osconfigv1.ClusterVersion, error) {
+	cvs := osconfigv1.ClusterVersionList{}
+	err := r.client.List(context.TODO(), &client.ListOptions{}, &cvs)
+	if err != nil {
+		return nil, err
+	}
+	if len(cvs.Items) != 1 {
+		return nil, fmt.Errorf("Expected 1 ClusterVersion, found %d", len(cvs.Items))
+	}
+	return &cvs.Items[0], nil
+}
+
+// ensureCatalogConfigMap ensures the ConfigMap backing a ConfigMap catalog
+// holds the manifests for version. The switch to version has already been
+// authorized along with the CatalogSource's. The manifests are checked for
+// every managed package and channel before they are written.
+func (r *ReconcileKNICluster) ensureCatalogConfigMap(instance *kniv1beta1.KNICluster, version string, reqLogger logr.Logger) error {
+	if instance.Spec.Catalog.Type != kniv1beta1.CatalogTypeConfigMap {
+		return nil
+	}
+	configMap, err := render.CatalogConfigMap(instance.Spec.Catalog, version)
+	if err != nil {
+		return err
+	}
+
+	found := &corev1.ConfigMap{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: configMap.Name, Namespace: configMap.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		if err := validateConfigMapContent(instance, version); err != nil {
+			return err
+		}
+		reqLogger.Info("Creating a new catalog ConfigMap", "ConfigMap.Namespace", configMap.Namespace, "ConfigMap.Name", configMap.Name)
+		return r.client.Create(context.TODO(), configMap)
+	} else if err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(found.Data, configMap.Data) || found.Labels[kniv1beta1.CatalogVersionLabel] != version {
+		if err := validateConfigMapContent(instance, version); err != nil {
+			return err
+		}
+		reqLogger.Info("Updating the catalog ConfigMap", "ConfigMap.Namespace", found.Namespace, "ConfigMap.Name", found.Name)
+		found.Data = configMap.Data
+		if found.Labels == nil {
+			found.Labels = map[string]string{}
+		}
+		found.Labels[kniv1beta1.CatalogVersionLabel] = version
+		return r.client.Update(context.TODO(), found)
+	}
+	return nil
+}
+
+func (r *ReconcileKNICluster) ensureCatalogSourceDeleted(instance *kniv1beta1.KNICluster) error {
+	cs := render.CatalogSource(instance.Spec.Catalog, "latest")
+	if err := r.ensureStagingCatalogSourceDeleted(cs); err != nil {
+		return err
+	}
+	if err := r.ensurePreviewCatalogSourceDeleted(cs); err != nil {
+		return err
+	}
+
+	found := &olm.CatalogSource{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: cs.Name, Namespace: cs.Namespace}, found)
+	switch {
+	case errors.IsNotFound(err):
+	case err != nil:
+		return err
+	case !isOwner(instance, found) && !isLegacyObject(instance, olm.CatalogSourceKind, found) &&
+		(adoptionPolicies(instance).CatalogSources != kniv1beta1.AdoptionAdopt || otherOwner(found) != ""):
+		// the catalog belongs to someone else, and so does its ConfigMap
+		return nil
+	default:
+		err = r.client.Delete(context.TODO(), found)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	if instance.Spec.Catalog.Type == kniv1beta1.CatalogTypeConfigMap {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      instance.Spec.Catalog.Name,
+				Namespace: instance.Spec.Catalog.Namespace,
+			},
+		}
+		err = r.client.Delete(context.TODO(), configMap)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
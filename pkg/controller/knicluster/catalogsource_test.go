@@ -0,0 +1,140 @@
+package knicluster
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// newConfigMapCatalogKNICluster returns the test KNICluster with a ConfigMap
+// catalog built from the manifests in dir
+func newConfigMapCatalogKNICluster(dir string) *kniv1beta1.KNICluster {
+	instance := newTestKNICluster()
+	instance.Spec.Catalog.Type = kniv1beta1.CatalogTypeConfigMap
+	instance.Spec.Catalog.ConfigMap.Directory = dir
+	return instance
+}
+
+// ownedConfigMapCatalog returns the CatalogSource of the ConfigMap catalog of
+// instance, and its ConfigMap, as they are when they serve version
+func ownedConfigMapCatalog(t *testing.T, instance *kniv1beta1.KNICluster, version string) []runtime.Object {
+	t.Helper()
+	catalogsource := render.CatalogSource(instance.Spec.Catalog, version)
+	for key, value := range render.OwnerLabels(instance) {
+		catalogsource.Labels[key] = value
+	}
+	configMap, err := render.CatalogConfigMap(instance.Spec.Catalog, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []runtime.Object{catalogsource, configMap}
+}
+
+// configMapCatalogVersions returns the versions the test CatalogSource and
+// its ConfigMap are labelled with
+func configMapCatalogVersions(t *testing.T, c *fakeClient) (string, string) {
+	t.Helper()
+	key := types.NamespacedName{Namespace: "olm", Name: "demo-catalog"}
+	catalogsource := &olm.CatalogSource{}
+	if err := c.Get(context.TODO(), key, catalogsource); err != nil {
+		t.Fatal(err)
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := c.Get(context.TODO(), key, configMap); err != nil {
+		t.Fatal(err)
+	}
+	return catalogsource.Labels[kniv1beta1.CatalogVersionLabel], configMap.Labels[kniv1beta1.CatalogVersionLabel]
+}
+
+func TestConfigMapCatalogSwitchAuthorizedOnce(t *testing.T) {
+	dir := newTestManifestsDir(t, "4.0.0", "4.1.0")
+	defer os.RemoveAll(dir)
+	endpoint := newTestPolicyEndpoint(t, kniv1beta1.PolicyDefer)
+	instance := newConfigMapCatalogKNICluster(dir)
+	instance.Spec.Policy = &kniv1beta1.PolicySpec{URL: endpoint.URL}
+	r, c := newTestReconciler(t, append([]runtime.Object{instance, testClusterVersion("4.1.0")},
+		ownedConfigMapCatalog(t, instance, "4.0.0")...)...)
+
+	reconcileWithPolicy(t, r)
+	if catalogsource, configMap := configMapCatalogVersions(t, c); catalogsource != "4.0.0" || configMap != "4.0.0" {
+		t.Errorf("CatalogSource serves %s and ConfigMap holds %s while the switch is deferred, want 4.0.0", catalogsource, configMap)
+	}
+	requests := endpoint.received()
+	if len(requests) != 1 {
+		t.Fatalf("policy endpoint was asked %d times, want once", len(requests))
+	}
+	if change := requests[0].CatalogSwitch; change == nil || change.FromVersion != "4.0.0" || change.ToVersion != "4.1.0" {
+		t.Errorf("policy endpoint was asked about %v, want the switch from 4.0.0 to 4.1.0", change)
+	}
+
+	endpoint.setDecision(kniv1beta1.PolicyAllow)
+	reconcileWithPolicy(t, r)
+	if catalogsource, configMap := configMapCatalogVersions(t, c); catalogsource != "4.1.0" || configMap != "4.1.0" {
+		t.Errorf("CatalogSource serves %s and ConfigMap holds %s once allowed, want 4.1.0", catalogsource, configMap)
+	}
+	if requests := endpoint.received(); len(requests) != 2 {
+		t.Errorf("policy endpoint was asked %d times, want once per decision", len(requests))
+	}
+}
+
+// writeTestPackageManifest replaces the etcd package manifest for version in
+// dir with one that only has channel
+func writeTestPackageManifest(t *testing.T, dir, version, channel string) {
+	t.Helper()
+	manifest := []byte("packageName: etcd\nchannels:\n- name: " + channel + "\n  currentCSV: etcdoperator.v" + version + "\n")
+	if err := ioutil.WriteFile(filepath.Join(dir, version, "etcd", "package.yaml"), manifest, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigMapCatalogCreateValidatesContent(t *testing.T) {
+	dir := newTestManifestsDir(t, "4.1.0")
+	defer os.RemoveAll(dir)
+	writeTestPackageManifest(t, dir, "4.1.0", "alpha")
+	instance := newConfigMapCatalogKNICluster(dir)
+	r, c := newTestReconciler(t, instance, testClusterVersion("4.1.0"))
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err == nil {
+		t.Fatal("reconcile succeeded, want the missing channel")
+	}
+
+	catalog := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, kniv1beta1.ConditionCatalogReady)
+	if catalog == nil || catalog.Reason != ReasonCatalogContentInvalid {
+		t.Errorf("CatalogReady condition is %v, want reason %s", catalog, ReasonCatalogContentInvalid)
+	}
+	key := types.NamespacedName{Namespace: "olm", Name: "demo-catalog"}
+	if err := c.Get(context.TODO(), key, &corev1.ConfigMap{}); !errors.IsNotFound(err) {
+		t.Errorf("getting the ConfigMap returned %v, want it not created", err)
+	}
+	if err := c.Get(context.TODO(), key, &olm.CatalogSource{}); !errors.IsNotFound(err) {
+		t.Errorf("getting the CatalogSource returned %v, want it not created", err)
+	}
+}
+
+func TestConfigMapCatalogSwitchValidatesContent(t *testing.T) {
+	dir := newTestManifestsDir(t, "4.0.0", "4.1.0")
+	defer os.RemoveAll(dir)
+	instance := newConfigMapCatalogKNICluster(dir)
+	r, c := newTestReconciler(t, append([]runtime.Object{instance, testClusterVersion("4.1.0")},
+		ownedConfigMapCatalog(t, instance, "4.0.0")...)...)
+	writeTestPackageManifest(t, dir, "4.1.0", "alpha")
+
+	if _, _, err := reconcileTestKey(t, r); err == nil {
+		t.Fatal("reconcile succeeded, want the missing channel")
+	}
+	if catalogsource, configMap := configMapCatalogVersions(t, c); catalogsource != "4.0.0" || configMap != "4.0.0" {
+		t.Errorf("CatalogSource serves %s and ConfigMap holds %s, want 4.0.0 kept", catalogsource, configMap)
+	}
+}
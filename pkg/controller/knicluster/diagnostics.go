@@ -0,0 +1,88 @@
+package knicluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/diagnostics"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// diagnosticsTailLines is the number of log lines per container in
+	// bundles collected by the operator, which must fit in a ConfigMap
+	diagnosticsTailLines int64 = 200
+	// maxDiagnosticsSize leaves room in a ConfigMap, which is limited to
+	// 1MiB, for its metadata
+	maxDiagnosticsSize = 900 * 1024
+)
+
+// ensureDiagnostics collects a diagnostics bundle into a ConfigMap when the
+// collect-diagnostics annotation has changed. Failures are reported in the
+// status rather than retried, since they are unlikely to resolve themselves.
+func (r *ReconcileKNICluster) ensureDiagnostics(instance *kniv1beta1.KNICluster, reqLogger logr.Logger) {
+	request := instance.Annotations[kniv1beta1.CollectDiagnosticsAnnotation]
+	if request == "" || (instance.Status.Diagnostics != nil && instance.Status.Diagnostics.Request == request) {
+		return
+	}
+	reqLogger.Info("Collecting diagnostics", "Request", request)
+
+	status := &kniv1beta1.DiagnosticsStatus{Request: request}
+	err := r.collectDiagnostics(instance, status)
+	if err != nil {
+		reqLogger.Error(err, "Failed to collect diagnostics")
+		status.Error = err.Error()
+	}
+	status.CompletionTime = metav1.Now()
+	instance.Status.Diagnostics = status
+}
+
+func (r *ReconcileKNICluster) collectDiagnostics(instance *kniv1beta1.KNICluster, status *kniv1beta1.DiagnosticsStatus) error {
+	if r.diagnostics == nil {
+		return fmt.Errorf("diagnostics collection is not available")
+	}
+
+	at := time.Now()
+	buf := &bytes.Buffer{}
+	kni := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	if err := r.diagnostics.Collect(context.TODO(), kni, at, buf); err != nil {
+		return err
+	}
+	if buf.Len() > maxDiagnosticsSize {
+		return fmt.Errorf("the bundle is %d bytes, which is too large for a ConfigMap; collect it with \"kubectl kni diagnostics\" instead", buf.Len())
+	}
+
+	bundle := diagnostics.BundleName(at) + ".tar.gz"
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      instance.Name + "-diagnostics",
+			Namespace: instance.Namespace,
+		},
+		BinaryData: map[string][]byte{bundle: buf.Bytes()},
+	}
+	if err := controllerutil.SetControllerReference(instance, configMap, r.scheme); err != nil {
+		return err
+	}
+
+	// the ConfigMap only ever holds the latest bundle, so it is replaced
+	// without reading it first
+	err := r.client.Create(context.TODO(), configMap)
+	if errors.IsAlreadyExists(err) {
+		err = r.client.Update(context.TODO(), configMap)
+	}
+	if err != nil {
+		return err
+	}
+
+	status.ConfigMap = configMap.Name
+	status.Bundle = bundle
+	return nil
+}
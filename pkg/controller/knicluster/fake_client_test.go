@@ -0,0 +1,255 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// fakeClient is an in-memory client.Client that records the writes made
+// through it. It keeps the objects its tests need, but does not implement
+// field selectors, admission or garbage collection.
+type fakeClient struct {
+	scheme *runtime.Scheme
+
+	mu              sync.Mutex
+	objects         map[schema.GroupVersionKind]map[types.NamespacedName]runtime.Object
+	resourceVersion int
+	// writes lists the creates, updates and deletes, as "verb Kind
+	// namespace/name"
+	writes []string
+	// statusUpdates lists the objects whose status was updated, as "Kind
+	// namespace/name"
+	statusUpdates []string
+}
+
+var _ client.Client = &fakeClient{}
+
+func newFakeClient(scheme *runtime.Scheme, objs ...runtime.Object) *fakeClient {
+	c := &fakeClient{scheme: scheme, objects: map[schema.GroupVersionKind]map[types.NamespacedName]runtime.Object{}}
+	for _, obj := range objs {
+		if err := c.store(obj, false); err != nil {
+			panic(fmt.Sprintf("adding %T to the fake client: %v", obj, err))
+		}
+	}
+	return c
+}
+
+func (c *fakeClient) gvk(obj runtime.Object) (schema.GroupVersionKind, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.GroupVersionKind(), nil
+	}
+	return apiutil.GVKForObject(obj, c.scheme)
+}
+
+func objectKey(obj runtime.Object) (types.NamespacedName, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return types.NamespacedName{}, err
+	}
+	return types.NamespacedName{Namespace: accessor.GetNamespace(), Name: accessor.GetName()}, nil
+}
+
+// store saves a copy of obj, which must exist already if update is set
+func (c *fakeClient) store(obj runtime.Object, update bool) error {
+	gvk, err := c.gvk(obj)
+	if err != nil {
+		return err
+	}
+	key, err := objectKey(obj)
+	if err != nil {
+		return err
+	}
+	gr := schema.GroupResource{Group: gvk.Group, Resource: strings.ToLower(gvk.Kind)}
+	_, exists := c.objects[gvk][key]
+	switch {
+	case update && !exists:
+		return errors.NewNotFound(gr, key.Name)
+	case !update && exists:
+		return errors.NewAlreadyExists(gr, key.Name)
+	}
+
+	c.resourceVersion++
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	accessor.SetResourceVersion(strconv.Itoa(c.resourceVersion))
+	// object references are made from the self link
+	accessor.SetSelfLink(fmt.Sprintf("/apis/%s/%s/namespaces/%s/%ss/%s", gvk.Group, gvk.Version, key.Namespace, strings.ToLower(gvk.Kind), key.Name))
+
+	if c.objects[gvk] == nil {
+		c.objects[gvk] = map[types.NamespacedName]runtime.Object{}
+	}
+	c.objects[gvk][key] = obj.DeepCopyObject()
+	return nil
+}
+
+// copyInto copies src into dst, converting between typed and unstructured
+// objects as needed
+func (c *fakeClient) copyInto(src, dst runtime.Object) error {
+	if u, ok := dst.(*unstructured.Unstructured); ok {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(src)
+		if err != nil {
+			return err
+		}
+		gvk := u.GroupVersionKind()
+		u.SetUnstructuredContent(content)
+		u.SetGroupVersionKind(gvk)
+		return nil
+	}
+	if u, ok := src.(*unstructured.Unstructured); ok {
+		return runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), dst)
+	}
+	reflect.ValueOf(dst).Elem().Set(reflect.ValueOf(src.DeepCopyObject()).Elem())
+	return nil
+}
+
+func (c *fakeClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	gvk, err := c.gvk(obj)
+	if err != nil {
+		return err
+	}
+	found, ok := c.objects[gvk][key]
+	if !ok {
+		return errors.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: strings.ToLower(gvk.Kind)}, key.Name)
+	}
+	return c.copyInto(found, obj)
+}
+
+func (c *fakeClient) List(ctx context.Context, opts *client.ListOptions, list runtime.Object) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	gvk, err := c.gvk(list)
+	if err != nil {
+		return err
+	}
+	gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+
+	selector := labels.Everything()
+	namespace := ""
+	if opts != nil {
+		if opts.LabelSelector != nil {
+			selector = opts.LabelSelector
+		}
+		namespace = opts.Namespace
+	}
+
+	_, isUnstructured := list.(*unstructured.UnstructuredList)
+	var items []runtime.Object
+	for key, obj := range c.objects[gvk] {
+		if namespace != "" && key.Namespace != namespace {
+			continue
+		}
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		if !selector.Matches(labels.Set(accessor.GetLabels())) {
+			continue
+		}
+		item := obj.DeepCopyObject()
+		if isUnstructured {
+			u := &unstructured.Unstructured{}
+			u.SetGroupVersionKind(gvk)
+			if err := c.copyInto(obj, u); err != nil {
+				return err
+			}
+			item = u
+		}
+		items = append(items, item)
+	}
+	return meta.SetList(list, items)
+}
+
+func (c *fakeClient) Create(ctx context.Context, obj runtime.Object) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.store(obj, false); err != nil {
+		return err
+	}
+	return c.recordWrite("create", obj)
+}
+
+func (c *fakeClient) Update(ctx context.Context, obj runtime.Object) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.store(obj, true); err != nil {
+		return err
+	}
+	return c.recordWrite("update", obj)
+}
+
+func (c *fakeClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOptionFunc) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	gvk, err := c.gvk(obj)
+	if err != nil {
+		return err
+	}
+	key, err := objectKey(obj)
+	if err != nil {
+		return err
+	}
+	if _, ok := c.objects[gvk][key]; !ok {
+		return errors.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: strings.ToLower(gvk.Kind)}, key.Name)
+	}
+	delete(c.objects[gvk], key)
+	return c.recordWrite("delete", obj)
+}
+
+func (c *fakeClient) recordWrite(verb string, obj runtime.Object) error {
+	gvk, err := c.gvk(obj)
+	if err != nil {
+		return err
+	}
+	key, err := objectKey(obj)
+	if err != nil {
+		return err
+	}
+	c.writes = append(c.writes, fmt.Sprintf("%s %s %s", verb, gvk.Kind, key))
+	return nil
+}
+
+func (c *fakeClient) Status() client.StatusWriter {
+	return fakeStatusWriter{c}
+}
+
+// fakeStatusWriter updates whole objects, like the client of a resource
+// without a status subresource
+type fakeStatusWriter struct {
+	c *fakeClient
+}
+
+func (w fakeStatusWriter) Update(ctx context.Context, obj runtime.Object) error {
+	w.c.mu.Lock()
+	defer w.c.mu.Unlock()
+	if err := w.c.store(obj, true); err != nil {
+		return err
+	}
+	gvk, err := w.c.gvk(obj)
+	if err != nil {
+		return err
+	}
+	key, err := objectKey(obj)
+	if err != nil {
+		return err
+	}
+	w.c.statusUpdates = append(w.c.statusUpdates, fmt.Sprintf("%s %s", gvk.Kind, key))
+	return nil
+}
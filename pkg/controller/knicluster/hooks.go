@@ -0,0 +1,362 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// ReasonHookFailed is the condition reason when a hook Job failed
+	ReasonHookFailed = "HookFailed"
+	// ReasonHookRunning is the condition reason while hook Jobs run
+	ReasonHookRunning = "HookRunning"
+	// ReasonRolledBack is the condition reason while the catalog is rolled
+	// back because a post-switch hook failed
+	ReasonRolledBack = "RolledBack"
+
+	// HookLabel is set on hook Jobs to the name of their KNICluster
+	HookLabel = "kni.openshift.com/hook"
+	// HookVersionLabel is set on hook Jobs to a hash of the version of their
+	// switch, since versions are not always valid label values
+	HookVersionLabel = "kni.openshift.com/hook-version"
+	// HookVersionAnnotation is set on hook Jobs to the version of their
+	// switch
+	HookVersionAnnotation = "kni.openshift.com/hook-version"
+)
+
+// runPreSwitchHooks runs the pre-switch hooks for a switch of the catalog to
+// version and image. It returns nil once they have all succeeded.
+func (r *ReconcileKNICluster) runPreSwitchHooks(instance *kniv1beta1.KNICluster, version, image, previous string, reqLogger logr.Logger) error {
+	if instance.Spec.Hooks == nil || len(instance.Spec.Hooks.PreSwitch) == 0 {
+		return nil
+	}
+	if _, dryRun := r.client.(*recordingClient); dryRun {
+		// the switch is planned or skipped, so its hooks are too
+		return nil
+	}
+	return r.runHooks(instance, kniv1beta1.HookPhasePreSwitch, instance.Spec.Hooks.PreSwitch, version, image, previous, reqLogger)
+}
+
+// ensurePostSwitchHooks runs the post-switch hooks once the CSV of every
+// managed operator has succeeded after the catalog was switched from an
+// earlier recorded version. No hooks run on the first install. If a hook
+// fails and the failure policy is Rollback, the catalog is switched back to
+// the previous version.
+func (r *ReconcileKNICluster) ensurePostSwitchHooks(instance *kniv1beta1.KNICluster, reqLogger logr.Logger) error {
+	hooks := instance.Spec.Hooks
+	if hooks == nil || len(hooks.PostSwitch) == 0 {
+		return nil
+	}
+	if instance.Status.Rollback != nil {
+		// the hooks already failed for the version rolled back from, and the
+		// RolledBack condition reports it
+		return nil
+	}
+	if _, dryRun := r.client.(*recordingClient); dryRun {
+		return nil
+	}
+	version := catalogVersion(instance)
+	if version == "" || upgradeRecorded(instance) {
+		// the hooks ran when the switch was recorded
+		return nil
+	}
+	previous := previousVersion(instance)
+	if previous == "" || previous == version {
+		// the catalog was installed, or its image changed, rather than
+		// switched to a new version
+		return nil
+	}
+
+	if err := r.managedCSVsSucceeded(instance); err != nil {
+		return err
+	}
+
+	err := r.runHooks(instance, kniv1beta1.HookPhasePostSwitch, hooks.PostSwitch, version, instance.Status.CatalogImage, previous, reqLogger)
+	if errorReason(err) != ReasonHookFailed || hooks.PostSwitchFailurePolicy != kniv1beta1.HookFailureRollback {
+		return err
+	}
+	reqLogger.Info("Rolling back the catalog after a post-switch hook failed", "FromVersion", version, "ToVersion", previous)
+	now := metav1.Now()
+	instance.Status.Rollback = &kniv1beta1.RollbackStatus{
+		FromVersion: version,
+		ToVersion:   previous,
+		Reason:      err.Error(),
+		Time:        now,
+	}
+	appendUpgradeRecord(instance, kniv1beta1.UpgradeRecord{
+		Version:        version,
+		CatalogImage:   instance.Status.CatalogImage,
+		CompletionTime: now,
+		Hooks:          hookStatuses(instance, version),
+		RolledBack:     true,
+	})
+	return &reasonError{reason: ReasonRolledBack, err: fmt.Errorf("rolling back the catalog from %s to %s: %v", version, previous, err)}
+}
+
+// previousVersion returns the version of the latest upgrade record that was
+// not rolled back, or "" if there is none
+func previousVersion(instance *kniv1beta1.KNICluster) string {
+	history := instance.Status.UpgradeHistory
+	for i := len(history) - 1; i >= 0; i-- {
+		if !history[i].RolledBack {
+			return history[i].Version
+		}
+	}
+	return ""
+}
+
+// setRollbackCondition reflects whether the catalog is rolled back. The
+// rollback does not stop the reconcile, since the catalog serves a version
+// whose operators worked, until the cluster moves on to another version.
+func setRollbackCondition(instance *kniv1beta1.KNICluster) {
+	rollback := instance.Status.Rollback
+	if rollback == nil {
+		conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
+			Type:    kniv1beta1.ConditionRolledBack,
+			Status:  corev1.ConditionFalse,
+			Reason:  "NotRolledBack",
+			Message: "The catalog serves the cluster version",
+		})
+		return
+	}
+	conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
+		Type:    kniv1beta1.ConditionRolledBack,
+		Status:  corev1.ConditionTrue,
+		Reason:  ReasonRolledBack,
+		Message: fmt.Sprintf("The catalog is rolled back from %s to %s: %s", rollback.FromVersion, rollback.ToVersion, rollback.Reason),
+	})
+}
+
+// managedCSVsSucceeded returns nil once every managed operator has installed
+// the CSV its Subscription points to, and the CSV has succeeded
+func (r *ReconcileKNICluster) managedCSVsSucceeded(instance *kniv1beta1.KNICluster) error {
+	var waiting []string
+	for _, operator := range instance.Spec.Operators {
+		namespace := render.OperatorNamespace(instance, operator)
+		subscription := &olm.Subscription{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: operator.Name}, subscription)
+		if errors.IsNotFound(err) {
+			waiting = append(waiting, operator.Name)
+			continue
+		} else if err != nil {
+			return err
+		}
+		installed := subscription.Status.InstalledCSV
+		if installed == "" || installed != subscription.Status.CurrentCSV {
+			waiting = append(waiting, operator.Name)
+			continue
+		}
+
+		csv := &olm.ClusterServiceVersion{}
+		err = r.apiReader.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: installed}, csv)
+		if errors.IsNotFound(err) {
+			waiting = append(waiting, operator.Name)
+			continue
+		} else if err != nil {
+			return err
+		}
+		if csv.Status.Phase != olm.CSVPhaseSucceeded {
+			waiting = append(waiting, operator.Name)
+		}
+	}
+	if len(waiting) > 0 {
+		return &pendingError{reason: ReasonHookRunning, err: fmt.Errorf("waiting for the CSVs of %s to succeed before running post-switch hooks",
+			strings.Join(waiting, ", "))}
+	}
+	return nil
+}
+
+// runHooks runs a Job for each hook of phase, and records their results. It
+// returns nil once every Job has succeeded, a pendingError while any runs,
+// and a reasonError if any failed.
+func (r *ReconcileKNICluster) runHooks(instance *kniv1beta1.KNICluster, phase kniv1beta1.HookPhase, hooks []kniv1beta1.HookSpec, version, image, previous string, reqLogger logr.Logger) error {
+	if err := r.deleteStaleHookJobs(instance, version); err != nil {
+		return err
+	}
+
+	var running, failed []string
+	for _, hook := range hooks {
+		job := hookJob(instance, phase, hook, version, image, previous)
+		if err := controllerutil.SetControllerReference(instance, job, r.scheme); err != nil {
+			return err
+		}
+		status := kniv1beta1.HookStatus{
+			Name:    hook.Name,
+			Phase:   phase,
+			Version: version,
+			Job:     job.Name,
+			Result:  kniv1beta1.HookRunning,
+		}
+
+		found := &batchv1.Job{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, found)
+		switch {
+		case errors.IsNotFound(err):
+			reqLogger.Info("Creating a hook Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name, "Phase", phase, "Version", version)
+			if err := r.client.Create(context.TODO(), job); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			status.Result, status.Message, status.CompletionTime = jobResult(found)
+		}
+		setHookStatus(instance, status)
+
+		switch status.Result {
+		case kniv1beta1.HookRunning:
+			running = append(running, hook.Name)
+		case kniv1beta1.HookFailed:
+			failed = append(failed, fmt.Sprintf("%s: %s", hook.Name, status.Message))
+		}
+	}
+
+	if len(failed) > 0 {
+		return &reasonError{reason: ReasonHookFailed, err: fmt.Errorf("%s hooks for %s failed: %s", phase, version, strings.Join(failed, "; "))}
+	}
+	if len(running) > 0 {
+		return &pendingError{reason: ReasonHookRunning, err: fmt.Errorf("waiting for %s hooks for %s: %s", phase, version, strings.Join(running, ", "))}
+	}
+	return nil
+}
+
+// hookJob returns the Job that runs hook for a switch to version. The Job
+// name changes with the switch and with forced reconciles, so that hooks run
+// again for either.
+func hookJob(instance *kniv1beta1.KNICluster, phase kniv1beta1.HookPhase, hook kniv1beta1.HookSpec, version, image, previous string) *batchv1.Job {
+	h := fnv.New32a()
+	for _, s := range []string{instance.Name, version, image, instance.Status.LastForcedReconcile} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	short := "pre"
+	if phase == kniv1beta1.HookPhasePostSwitch {
+		short = "post"
+	}
+	name := fmt.Sprintf("%s-%s-%08x", hook.Name, short, h.Sum32())
+	labels := map[string]string{
+		HookLabel:        instance.Name,
+		HookVersionLabel: hookVersionLabel(version),
+	}
+
+	return &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: batchv1.SchemeGroupVersion.String(),
+			Kind:       "Job",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   instance.Namespace,
+			Labels:      labels,
+			Annotations: map[string]string{HookVersionAnnotation: version},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          hook.BackoffLimit,
+			ActiveDeadlineSeconds: hook.ActiveDeadlineSeconds,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: hook.ServiceAccountName,
+					RestartPolicy:      corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    "hook",
+							Image:   hook.Image,
+							Command: hook.Command,
+							Args:    hook.Args,
+							Env: []corev1.EnvVar{
+								{Name: "KNI_HOOK_PHASE", Value: string(phase)},
+								{Name: "KNI_CATALOG_VERSION", Value: version},
+								{Name: "KNI_CATALOG_IMAGE", Value: image},
+								{Name: "KNI_PREVIOUS_VERSION", Value: previous},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// hookVersionLabel returns the HookVersionLabel value for version
+func hookVersionLabel(version string) string {
+	h := fnv.New32a()
+	h.Write([]byte(version))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// jobResult returns the result of a hook Job
+func jobResult(job *batchv1.Job) (kniv1beta1.HookResult, string, *metav1.Time) {
+	if job.Status.Succeeded > 0 {
+		return kniv1beta1.HookSucceeded, "", job.Status.CompletionTime
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			completion := condition.LastTransitionTime
+			return kniv1beta1.HookFailed, condition.Message, &completion
+		}
+	}
+	return kniv1beta1.HookRunning, "", nil
+}
+
+// setHookStatus records status, replacing the results of hooks run for
+// other versions
+func setHookStatus(instance *kniv1beta1.KNICluster, status kniv1beta1.HookStatus) {
+	var statuses []kniv1beta1.HookStatus
+	for _, existing := range instance.Status.Hooks {
+		if existing.Version != status.Version {
+			continue
+		}
+		if existing.Name == status.Name && existing.Phase == status.Phase {
+			continue
+		}
+		statuses = append(statuses, existing)
+	}
+	instance.Status.Hooks = append(statuses, status)
+}
+
+// hookStatuses returns the results of the hooks run for a switch to version
+func hookStatuses(instance *kniv1beta1.KNICluster, version string) []kniv1beta1.HookStatus {
+	var statuses []kniv1beta1.HookStatus
+	for _, status := range instance.Status.Hooks {
+		if status.Version == version {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// deleteStaleHookJobs deletes the hook Jobs of switches to other versions
+func (r *ReconcileKNICluster) deleteStaleHookJobs(instance *kniv1beta1.KNICluster, version string) error {
+	jobs := &batchv1.JobList{}
+	opts := client.InNamespace(instance.Namespace).MatchingLabels(map[string]string{HookLabel: instance.Name})
+	if err := r.client.List(context.TODO(), opts, jobs); err != nil {
+		return err
+	}
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if job.Labels[HookVersionLabel] == hookVersionLabel(version) {
+			continue
+		}
+		err := r.client.Delete(context.TODO(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
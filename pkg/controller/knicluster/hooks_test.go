@@ -0,0 +1,222 @@
+package knicluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// newHookTestReconciler returns a reconciler of a cluster whose catalog was
+// switched from 4.0.0 to 4.1.0, and whose operator has installed its CSV
+func newHookTestReconciler(t *testing.T, policy kniv1beta1.HookFailurePolicy) (*ReconcileKNICluster, *fakeClient) {
+	instance := newTestKNICluster()
+	instance.Spec.Catalog.Image.SkipContentValidation = true
+	instance.Spec.Hooks = &kniv1beta1.HooksSpec{
+		PostSwitch:              []kniv1beta1.HookSpec{{Name: "smoke", Image: "quay.io/example/kni-tests:latest"}},
+		PostSwitchFailurePolicy: policy,
+	}
+	instance.Status.UpgradeHistory = []kniv1beta1.UpgradeRecord{
+		{Version: "4.0.0", CatalogImage: testUpdateImage("4.0.0"), CompletionTime: metav1.Now()},
+	}
+	return newTestReconciler(t, append([]runtime.Object{instance, newOwnedCatalogSource(instance, "4.1.0"), testClusterVersion("4.1.0")},
+		installedOperator(t, instance, "etcdoperator.v4.1.0")...)...)
+}
+
+// installedOperator returns the Subscription of the operator of instance,
+// and the CSV it installed, which has succeeded
+func installedOperator(t *testing.T, instance *kniv1beta1.KNICluster, csvName string) []runtime.Object {
+	operator := instance.Spec.Operators[0]
+	subscription := render.Subscription(render.OperatorNamespace(instance, operator), instance.Spec.Catalog, operator, render.InstallPlanApproval(instance))
+	if err := controllerutil.SetControllerReference(instance, subscription, newTestScheme(t)); err != nil {
+		t.Fatal(err)
+	}
+	subscription.Status.CurrentCSV = csvName
+	subscription.Status.InstalledCSV = csvName
+	csv := &olm.ClusterServiceVersion{
+		ObjectMeta: metav1.ObjectMeta{Namespace: subscription.Namespace, Name: csvName},
+		Status:     olm.ClusterServiceVersionStatus{Phase: olm.CSVPhaseSucceeded},
+	}
+	return []runtime.Object{subscription, csv}
+}
+
+// hookJobs returns the hook Jobs of the test KNICluster
+func hookJobs(t *testing.T, c *fakeClient) []batchv1.Job {
+	t.Helper()
+	jobs := &batchv1.JobList{}
+	if err := c.List(context.TODO(), nil, jobs); err != nil {
+		t.Fatal(err)
+	}
+	return jobs.Items
+}
+
+// setJobStatus sets the status of the only hook Job
+func setJobStatus(t *testing.T, c *fakeClient, status batchv1.JobStatus) {
+	t.Helper()
+	jobs := hookJobs(t, c)
+	if len(jobs) != 1 {
+		t.Fatalf("found %d hook Jobs, want 1", len(jobs))
+	}
+	job := jobs[0]
+	job.Status = status
+	if err := c.Update(context.TODO(), &job); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostSwitchHooksRunAfterSwitch(t *testing.T) {
+	r, c := newHookTestReconciler(t, kniv1beta1.HookFailureDegrade)
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	hooks := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, kniv1beta1.ConditionHooksSucceeded)
+	if hooks == nil || hooks.Reason != ReasonHookRunning {
+		t.Errorf("HooksSucceeded condition is %v, want reason %s", hooks, ReasonHookRunning)
+	}
+	jobs := hookJobs(t, c)
+	if len(jobs) != 1 {
+		t.Fatalf("found %d hook Jobs, want 1", len(jobs))
+	}
+	env := map[string]string{}
+	for _, variable := range jobs[0].Spec.Template.Spec.Containers[0].Env {
+		env[variable.Name] = variable.Value
+	}
+	if env["KNI_CATALOG_VERSION"] != "4.1.0" || env["KNI_PREVIOUS_VERSION"] != "4.0.0" {
+		t.Errorf("hook Job environment is %v, want a switch from 4.0.0 to 4.1.0", env)
+	}
+	if jobs[0].Annotations[HookVersionAnnotation] != "4.1.0" {
+		t.Errorf("hook Job annotations are %v, want the version", jobs[0].Annotations)
+	}
+
+	setJobStatus(t, c, batchv1.JobStatus{Succeeded: 1})
+	reconciled, _, err = reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	hooks = conditionsv1.FindStatusCondition(reconciled.Status.Conditions, kniv1beta1.ConditionHooksSucceeded)
+	if hooks == nil || hooks.Status != corev1.ConditionTrue {
+		t.Errorf("HooksSucceeded condition is %v, want True", hooks)
+	}
+	history := reconciled.Status.UpgradeHistory
+	latest := history[len(history)-1]
+	if latest.Version != "4.1.0" || len(latest.Hooks) != 1 || latest.Hooks[0].Result != kniv1beta1.HookSucceeded {
+		t.Errorf("latest upgrade record is %v, want 4.1.0 with the hook succeeded", latest)
+	}
+}
+
+func TestPostSwitchHooksSkipFirstInstall(t *testing.T) {
+	instance := newTestKNICluster()
+	instance.Spec.Catalog.Image.SkipContentValidation = true
+	instance.Spec.Hooks = &kniv1beta1.HooksSpec{
+		PostSwitch:              []kniv1beta1.HookSpec{{Name: "smoke", Image: "quay.io/example/kni-tests:latest"}},
+		PostSwitchFailurePolicy: kniv1beta1.HookFailureDegrade,
+	}
+	r, c := newTestReconciler(t, append([]runtime.Object{instance, testClusterVersion("4.1.0")},
+		installedOperator(t, instance, "etcdoperator.v4.1.0")...)...)
+
+	// the first reconcile creates the CatalogSource, the second finds it
+	var reconciled *kniv1beta1.KNICluster
+	for i := 0; i < 2; i++ {
+		var err error
+		reconciled, _, err = reconcileTestKey(t, r)
+		if err != nil {
+			t.Fatalf("reconcile %d: %v", i, err)
+		}
+	}
+
+	if jobs := hookJobs(t, c); len(jobs) != 0 {
+		t.Errorf("first install ran %d hook Jobs", len(jobs))
+	}
+	if history := reconciled.Status.UpgradeHistory; len(history) != 1 || history[0].Version != "4.1.0" {
+		t.Errorf("upgrade history is %v, want the install of 4.1.0", history)
+	}
+}
+
+func TestPostSwitchHookFailureRollsBack(t *testing.T) {
+	r, c := newHookTestReconciler(t, kniv1beta1.HookFailureRollback)
+	if _, _, err := reconcileTestKey(t, r); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	setJobStatus(t, c, batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+		{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "smoke tests failed"},
+	}})
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err == nil || !strings.Contains(err.Error(), "rolling back") {
+		t.Fatalf("reconcile returned %v, want the rollback", err)
+	}
+	rollback := reconciled.Status.Rollback
+	if rollback == nil || rollback.FromVersion != "4.1.0" || rollback.ToVersion != "4.0.0" {
+		t.Fatalf("rollback is %v, want 4.1.0 to 4.0.0", rollback)
+	}
+
+	// the rollback is reported, but does not stop later reconciles
+	for i := 0; i < 2; i++ {
+		reconciled, _, err = reconcileTestKey(t, r)
+		if err != nil {
+			t.Fatalf("reconcile %d after the rollback: %v", i, err)
+		}
+	}
+	if version := catalogSourceVersion(t, c); version != "4.0.0" {
+		t.Errorf("catalog serves %s, want 4.0.0 after the rollback", version)
+	}
+	rolledBack := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, kniv1beta1.ConditionRolledBack)
+	if rolledBack == nil || rolledBack.Status != corev1.ConditionTrue || rolledBack.Reason != ReasonRolledBack {
+		t.Errorf("RolledBack condition is %v, want True", rolledBack)
+	}
+	degraded := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, conditionsv1.ConditionDegraded)
+	if degraded == nil || degraded.Status != corev1.ConditionFalse {
+		t.Errorf("Degraded condition is %v, want False", degraded)
+	}
+	if jobs := hookJobs(t, c); len(jobs) != 1 {
+		t.Errorf("found %d hook Jobs after the rollback, want only the failed one", len(jobs))
+	}
+}
+
+func TestHookVersionLabelIsValid(t *testing.T) {
+	instance := newTestKNICluster()
+	hook := kniv1beta1.HookSpec{Name: "smoke", Image: "quay.io/example/kni-tests:latest"}
+	for _, version := range []string{"4.1.0", "4.2.0+build.1", strings.Repeat("4.2.0-rc.", 10)} {
+		job := hookJob(instance, kniv1beta1.HookPhasePostSwitch, hook, version, testUpdateImage("4.2.0"), "4.1.0")
+		label := job.Labels[HookVersionLabel]
+		if errs := validation.IsValidLabelValue(label); len(errs) > 0 {
+			t.Errorf("label for version %q is invalid: %v", version, errs)
+		}
+		if job.Annotations[HookVersionAnnotation] != version {
+			t.Errorf("annotation for version %q is %q", version, job.Annotations[HookVersionAnnotation])
+		}
+	}
+}
+
+func TestDeleteStaleHookJobs(t *testing.T) {
+	instance := newTestKNICluster()
+	hook := kniv1beta1.HookSpec{Name: "smoke", Image: "quay.io/example/kni-tests:latest"}
+	stale := hookJob(instance, kniv1beta1.HookPhasePostSwitch, hook, "4.1.0+build.1", "", "")
+	current := hookJob(instance, kniv1beta1.HookPhasePostSwitch, hook, "4.2.0+build.1", "", "")
+	r, c := newTestReconciler(t, stale, current)
+
+	if err := r.deleteStaleHookJobs(instance, "4.2.0+build.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs := hookJobs(t, c)
+	if len(jobs) != 1 || jobs[0].Name != current.Name {
+		t.Errorf("hook Jobs left are %v, want only %s", jobs, current.Name)
+	}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: stale.Namespace, Name: stale.Name}, &batchv1.Job{}); err == nil {
+		t.Errorf("stale hook Job %s was kept", stale.Name)
+	}
+}
@@ -4,25 +4,30 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
+	"strings"
+	"time"
 
 	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
-	objectreferencesv1 "github.com/djzager/custom-resource-status/objectreferences/v1"
-	kniv1alpha1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1alpha1"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/diagnostics"
+	"github.com/mhrivnak/kni-operator/pkg/registry"
 	osconfigv1 "github.com/openshift/api/config/v1"
 	olmv1 "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1"
 	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/client-go/tools/reference"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -35,10 +40,62 @@ const (
 	// FinalizerName is the finalizer value used on non-owned resources
 	FinalizerName          = "knicluster.kni.openshift.com"
 	KNIClusterNameEnv      = "KNI_CLUSTER_NAME"
-	KNIClusterNameDefault  = "kni-cluster"
 	KNIClusterNamespaceEnv = "KNI_CLUSTER_NAMESPACE"
+
+	// maxUpgradeHistory is the number of upgrades kept in the status
+	maxUpgradeHistory = 10
+
+	// pendingRequeueAfter is how long to wait before checking a pending
+	// component again
+	pendingRequeueAfter = 10 * time.Second
+)
+
+const (
+	// ReasonReconcileFailed is the condition reason of errors that have no
+	// more specific reason
+	ReasonReconcileFailed = "ReconcileFailed"
+	// ReasonSignatureVerificationFailed is the condition reason when a new
+	// catalog image is refused because its signature could not be verified
+	ReasonSignatureVerificationFailed = "SignatureVerificationFailed"
 )
 
+// reasonError is an error with a specific reason to report in conditions
+type reasonError struct {
+	reason string
+	err    error
+}
+
+func (e *reasonError) Error() string {
+	return e.err.Error()
+}
+
+// pendingError reports a component that is waiting for something to happen,
+// rather than failing. It does not degrade the KNICluster, and the component
+// is checked again after pendingRequeueAfter.
+type pendingError struct {
+	reason string
+	err    error
+	// waitForChange is set when the component waits for a change to the
+	// KNICluster, which triggers a reconcile, so it need not be checked
+	// periodically
+	waitForChange bool
+}
+
+func (e *pendingError) Error() string {
+	return e.err.Error()
+}
+
+// errorReason returns the condition reason for err
+func errorReason(err error) string {
+	switch e := err.(type) {
+	case *reasonError:
+		return e.reason
+	case *pendingError:
+		return e.reason
+	}
+	return ReasonReconcileFailed
+}
+
 // Add creates a new KNICluster Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
@@ -47,7 +104,26 @@ func Add(mgr manager.Manager) error {
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileKNICluster{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+	r := &ReconcileKNICluster{client: mgr.GetClient(), apiReader: mgr.GetClient(), scheme: mgr.GetScheme(), dialCatalog: registry.DialCatalog}
+
+	// Secrets are read directly, so that they are not cached cluster-wide
+	apiReader, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		log.Error(err, "Failed to create an uncached client, reading through the cache instead")
+	} else {
+		r.apiReader = apiReader
+	}
+	r.notifier = newNotifier(r.client, r.apiReader)
+	r.decider = newDecider()
+
+	collector, err := diagnostics.NewCollector(mgr.GetConfig(), mgr.GetScheme())
+	if err != nil {
+		log.Error(err, "Diagnostics collection is not available")
+	} else {
+		collector.TailLines = diagnosticsTailLines
+		r.diagnostics = collector
+	}
+	return r
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -59,7 +135,7 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	}
 
 	// Watch for changes to primary resource KNICluster
-	err = c.Watch(&source.Kind{Type: &kniv1alpha1.KNICluster{}}, &handler.EnqueueRequestForObject{})
+	err = c.Watch(&source.Kind{Type: &kniv1beta1.KNICluster{}}, &handler.EnqueueRequestForObject{})
 	if err != nil {
 		return err
 	}
@@ -69,163 +145,124 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		&olmv1.OperatorGroup{},
 		&olm.CatalogSource{},
 		&olm.Subscription{},
+		&appsv1.DaemonSet{},
+		&batchv1.Job{},
 	} {
 		err = c.Watch(&source.Kind{Type: resource}, &handler.EnqueueRequestForOwner{
 			IsController: true,
-			OwnerType:    &kniv1alpha1.KNICluster{},
+			OwnerType:    &kniv1beta1.KNICluster{},
 		})
 		if err != nil {
 			return err
 		}
 	}
 
-	kni, err := GetKNINamespacedName()
-	if err != nil {
-		return err
+	// objects in other namespaces, and namespaces themselves, cannot have an
+	// owner reference to the KNICluster
+	for _, resource := range []runtime.Object{
+		&corev1.Namespace{},
+		&olmv1.OperatorGroup{},
+		&olm.CatalogSource{},
+		&olm.Subscription{},
+	} {
+		err = c.Watch(&source.Kind{Type: resource}, enqueueLabelledOwner)
+		if err != nil {
+			return err
+		}
 	}
+
+	// the ClusterVersion is shared by every KNICluster
 	err = c.Watch(&source.Kind{Type: &osconfigv1.ClusterVersion{}}, &handler.EnqueueRequestsFromMapFunc{
-		ToRequests: handler.ToRequestsFunc(
-			// always enqueued the same KNICluster object, since there should be only one
-			func(a handler.MapObject) []reconcile.Request {
-				return []reconcile.Request{
-					{NamespacedName: kni},
-				}
-			}),
+		ToRequests: allKNIClusters(mgr.GetClient()),
 	})
 
 	return err
 }
 
+// allKNIClusters returns a mapping of any object to requests for every
+// KNICluster, for cluster-scoped inputs they all depend on
+func allKNIClusters(c client.Client) handler.ToRequestsFunc {
+	return func(a handler.MapObject) []reconcile.Request {
+		instances := &kniv1beta1.KNIClusterList{}
+		if err := c.List(context.TODO(), &client.ListOptions{}, instances); err != nil {
+			log.Error(err, "Failed to list KNIClusters")
+			return nil
+		}
+		requests := make([]reconcile.Request, 0, len(instances.Items))
+		for _, instance := range instances.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name},
+			})
+		}
+		return requests
+	}
+}
+
 // blank assignment to verify that ReconcileKNICluster implements reconcile.Reconciler
 var _ reconcile.Reconciler = &ReconcileKNICluster{}
 
 // ReconcileKNICluster reconciles a KNICluster object
 type ReconcileKNICluster struct {
 	client client.Client
-	scheme *runtime.Scheme
-}
-
-func (r *ReconcileKNICluster) ensureOperatorGroup(instance *kniv1alpha1.KNICluster, reqLogger logr.Logger) error {
-	// ensure OperatorGroup exists
-	operatorGroup := newOperatorGroup(instance.Namespace)
-	if err := controllerutil.SetControllerReference(instance, operatorGroup, r.scheme); err != nil {
-		return err
-	}
-
-	// Check if this OperatorGroup already exists
-	found := &olmv1.OperatorGroup{}
-	err := r.client.Get(context.TODO(), types.NamespacedName{Name: operatorGroup.Name, Namespace: operatorGroup.Namespace}, found)
-	if err != nil && errors.IsNotFound(err) {
-		reqLogger.Info("Creating a new OperatorGroup", "OperatorGroup.Namespace", operatorGroup.Namespace, "OperatorGroup.Name", operatorGroup.Name)
-		err = r.client.Create(context.TODO(), operatorGroup)
-		if err != nil {
-			return err
-		}
-		return nil
-	} else if err != nil {
-		return err
-	}
-
-	// already exists - don't requeue
-	reqLogger.Info("OperatorGroup already exists", "OperatorGroup.Namespace", found.Namespace, "OperatorGroup.Name", found.Name)
-
-	objectRef, err := reference.GetReference(r.scheme, found)
-	if err != nil {
-		return err
-	}
-	// Add it to the list of RelatedObjects if found
-	objectreferencesv1.SetObjectReference(&instance.Status.RelatedObjects, *objectRef)
-
-	return nil
+	// apiReader reads directly from the API server
+	apiReader client.Reader
+	scheme    *runtime.Scheme
+	// diagnostics collects diagnostics bundles on request
+	diagnostics *diagnostics.Collector
+	// notifier delivers queued notifications
+	notifier *notifier
+	// decider asks the policy endpoint
+	decider *decider
+	// dialCatalog connects to the registry service of a catalog
+	dialCatalog func(ctx context.Context, address string) (*registry.CatalogClient, error)
 }
 
-func (r *ReconcileKNICluster) ensureSubscription(instance *kniv1alpha1.KNICluster, reqLogger logr.Logger) error {
-	// ensure Subscription exists
-	subscription := newSubscription(instance.Namespace)
-	if err := controllerutil.SetControllerReference(instance, subscription, r.scheme); err != nil {
-		return err
-	}
-
-	// Check if this Subscription already exists
-	found := &olm.Subscription{}
-	err := r.client.Get(context.TODO(), types.NamespacedName{Name: subscription.Name, Namespace: subscription.Namespace}, found)
-	if err != nil && errors.IsNotFound(err) {
-		reqLogger.Info("Creating a new Subscription", "Subscription.Namespace", subscription.Namespace, "Subscription.Name", subscription.Name)
-		err = r.client.Create(context.TODO(), subscription)
-		if err != nil {
-			return err
-		}
-		return nil
-	} else if err != nil {
-		return err
-	}
-
-	// already exists - don't requeue
-	reqLogger.Info("Subscription already exists", "Subscription.Namespace", found.Namespace, "Subscription.Name", found.Name)
-
-	// Add it to the list of RelatedObjects if found
-	objectRef, err := reference.GetReference(r.scheme, found)
-	if err != nil {
-		return err
-	}
-	// Add it to the list of RelatedObjects if found
-	objectreferencesv1.SetObjectReference(&instance.Status.RelatedObjects, *objectRef)
-
-	return nil
+// component is a part of the KNICluster that gets reconciled independently
+// and reports its own condition.
+type component struct {
+	name      string
+	condition conditionsv1.ConditionType
+	ensure    func(*kniv1beta1.KNICluster, logr.Logger) error
 }
 
-func (r *ReconcileKNICluster) ensureCatalogSource(instance *kniv1alpha1.KNICluster, reqLogger logr.Logger) error {
-	cvs := osconfigv1.ClusterVersionList{}
-	err := r.client.List(context.TODO(), &client.ListOptions{}, &cvs)
-	if err != nil {
-		return err
-	}
-	if len(cvs.Items) != 1 {
-		return fmt.Errorf("Expected 1 ClusterVersion, found %d", len(cvs.Items))
-	}
-	cv := &cvs.Items[0]
-
-	// ensure CatalogSource exists
-	catalogsource := newCatalogSource(cv.Spec.DesiredUpdate.Version)
-
-	// Check if this CatalogSource already exists
-	found := &olm.CatalogSource{}
-	err = r.client.Get(context.TODO(), types.NamespacedName{Name: catalogsource.Name, Namespace: catalogsource.Namespace}, found)
-	if err != nil && errors.IsNotFound(err) {
-		reqLogger.Info("Creating a new CatalogSource", "CatalogSource.Namespace", catalogsource.Namespace, "CatalogSource.Name", catalogsource.Name)
-		err = r.client.Create(context.TODO(), catalogsource)
-		if err != nil {
-			return err
-		}
-
-		// created successfully - don't requeue
-		return nil
-	} else if err != nil {
-		return err
-	}
-
-	// already exists - don't requeue
-	reqLogger.Info("CatalogSource already exists", "CatalogSource.Namespace", found.Namespace, "CatalogSource.Name", found.Name)
-
-	// update the image if necessary
-	if found.Spec.Image != catalogsource.Spec.Image {
-		reqLogger.Info("Updating the CatalogSource image", "CatalogSource.Namespace", found.Namespace, "CatalogSource.Name", found.Name)
-		found.Spec.Image = catalogsource.Spec.Image
-		err = r.client.Update(context.TODO(), found)
-		if err != nil {
-			return err
-		}
-	}
-
-	// Add it to the list of RelatedObjects if found
-	objectRef, err := reference.GetReference(r.scheme, found)
-	if err != nil {
-		return err
+func (r *ReconcileKNICluster) components() []component {
+	return []component{
+		{
+			name:      "namespaces",
+			condition: kniv1beta1.ConditionNamespacesReady,
+			ensure:    r.ensureNamespaces,
+		},
+		{
+			name:      "catalog",
+			condition: kniv1beta1.ConditionCatalogReady,
+			ensure:    r.ensureCatalogSource,
+		},
+		{
+			name:      "operator groups",
+			condition: kniv1beta1.ConditionOperatorGroupReady,
+			ensure:    r.ensureOperatorGroups,
+		},
+		{
+			name:      "subscriptions",
+			condition: kniv1beta1.ConditionSubscriptionsReady,
+			ensure:    r.ensureSubscriptions,
+		},
+		{
+			name:      "install plans",
+			condition: kniv1beta1.ConditionInstallPlansApproved,
+			ensure:    r.ensureInstallPlans,
+		},
+		{
+			name:      "operands",
+			condition: kniv1beta1.ConditionOperandsReady,
+			ensure:    r.ensureOperands,
+		},
+		{
+			name:      "post-switch hooks",
+			condition: kniv1beta1.ConditionHooksSucceeded,
+			ensure:    r.ensurePostSwitchHooks,
+		},
 	}
-	// Add it to the list of RelatedObjects if found
-	objectreferencesv1.SetObjectReference(&instance.Status.RelatedObjects, *objectRef)
-
-	return nil
 }
 
 func containsString(slice []string, s string) bool {
@@ -247,18 +284,6 @@ func removeString(slice []string, s string) (result []string) {
 	return
 }
 
-func (r *ReconcileKNICluster) ensureCatalogSourceDeleted() error {
-	cs := newCatalogSource("latest")
-	err := r.client.Delete(context.TODO(), cs)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			return nil
-		}
-		return err
-	}
-	return nil
-}
-
 // Reconcile reads that state of the cluster for a KNICluster object and makes changes based on the state read
 // and what is in the KNICluster.Spec
 func (r *ReconcileKNICluster) Reconcile(request reconcile.Request) (reconcile.Result, error) {
@@ -266,7 +291,7 @@ func (r *ReconcileKNICluster) Reconcile(request reconcile.Request) (reconcile.Re
 	reqLogger.Info("Reconciling KNICluster")
 
 	// Fetch the KNICluster instance
-	instance := &kniv1alpha1.KNICluster{}
+	instance := &kniv1beta1.KNICluster{}
 	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -279,6 +304,8 @@ func (r *ReconcileKNICluster) Reconcile(request reconcile.Request) (reconcile.Re
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
+	// lifecycle events are found by comparing the status with this
+	before := instance.Status.DeepCopy()
 
 	// Add conditions if there are none
 	if instance.Status.Conditions == nil {
@@ -306,14 +333,37 @@ func (r *ReconcileKNICluster) Reconcile(request reconcile.Request) (reconcile.Re
 		}
 	}
 
-	if instance.ObjectMeta.DeletionTimestamp.IsZero() {
+	paused := isPaused(instance)
+	setPausedCondition(instance, paused)
+
+	if paused {
+		reqLogger.Info("Reconciliation is paused")
+		// act on the defaults without storing them
+		instance.SetDefaults()
+		if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
+			return reconcile.Result{}, r.client.Status().Update(context.TODO(), instance)
+		}
+	} else if instance.ObjectMeta.DeletionTimestamp.IsZero() {
+		// Store defaults for omitted fields, in case the KNICluster was
+		// created without the defaulting webhook
+		defaulted := instance.DeepCopy()
+		defaulted.SetDefaults()
+		if !reflect.DeepEqual(defaulted.Spec, instance.Spec) {
+			reqLogger.Info("Setting defaults on KNICluster spec")
+			return reconcile.Result{}, r.client.Update(context.TODO(), defaulted)
+		}
+
 		if !containsString(instance.ObjectMeta.Finalizers, FinalizerName) {
 			instance.ObjectMeta.Finalizers = append(instance.ObjectMeta.Finalizers, FinalizerName)
 			return reconcile.Result{}, r.client.Update(context.TODO(), instance)
 		}
 	} else {
 		if containsString(instance.ObjectMeta.Finalizers, FinalizerName) {
-			err = r.ensureCatalogSourceDeleted()
+			err = r.ensureCatalogSourceDeleted(instance)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+			err = r.ensureLabelledObjectsDeleted(instance, reqLogger)
 			if err != nil {
 				return reconcile.Result{}, err
 			}
@@ -324,31 +374,193 @@ func (r *ReconcileKNICluster) Reconcile(request reconcile.Request) (reconcile.Re
 		}
 	}
 
-	for _, f := range []func(*kniv1alpha1.KNICluster, logr.Logger) error{
-		r.ensureOperatorGroup,
-		r.ensureCatalogSource,
-		r.ensureSubscription,
-	} {
-		err = f(instance, reqLogger)
-		if err != nil {
-			reqLogger.Error(err, "Failed reconcile")
-			reqLogger.Info("Updating degraded condition")
+	instance.Status.ObservedGeneration = instance.Generation
+
+	if at := instance.Annotations[kniv1beta1.ReconcileAtAnnotation]; at != "" && at != instance.Status.LastForcedReconcile {
+		reqLogger.Info("Forcing a full resync", "ReconcileAt", at)
+		// related objects get rediscovered by the components
+		instance.Status.RelatedObjects = nil
+		// a rejected catalog image gets checked again
+		instance.Status.CatalogValidation = nil
+		instance.Status.UpgradePreview = nil
+		// hooks run again, and a rollback is retried
+		instance.Status.Hooks = nil
+		instance.Status.Rollback = nil
+		// the policy endpoint is asked again
+		instance.Status.PolicyDecisions = nil
+		instance.Status.LastForcedReconcile = at
+	}
 
+	// while paused or planning, components run against a client that records
+	// changes instead of making them
+	planning := instance.Spec.Mode == kniv1beta1.ModePlan
+	skipReason, skipMessage := "Paused", "Skipped while paused"
+	if planning {
+		skipReason, skipMessage = "Planned", "Planned"
+	}
+	componentReconciler := r
+	var recorder *recordingClient
+	if paused || planning {
+		recorder = newRecordingClient(r.client, r.scheme)
+		componentReconciler = &ReconcileKNICluster{client: recorder, apiReader: r.apiReader, scheme: r.scheme, dialCatalog: r.dialCatalog}
+	} else {
+		// diagnostics requested while paused or planning are collected once
+		// the changes are applied again
+		r.ensureDiagnostics(instance, reqLogger)
+	}
+
+	// effective images and conflicts are collected by the components
+	instance.Status.Images = nil
+	instance.Status.Conflicts = nil
+
+	// reconcile each component independently so that one failing component
+	// does not block the others
+	var errs []error
+	var skipped []action
+	var pending []string
+	pollPending := false
+	degradedReason := ReasonReconcileFailed
+	for _, c := range componentReconciler.components() {
+		err = c.ensure(instance, reqLogger)
+
+		var componentSkipped []action
+		if recorder != nil {
+			componentSkipped = recorder.actions
+			recorder.actions = nil
+			skipped = append(skipped, componentSkipped...)
+		}
+
+		pendingErr, isPending := err.(*pendingError)
+
+		switch {
+		case isPending:
+			reqLogger.Info("Component is pending", "Component", c.name, "Reason", err.Error())
+			pending = append(pending, fmt.Sprintf("%s: %v", c.name, err))
+			pollPending = pollPending || !pendingErr.waitForChange
+			conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
+				Type:    c.condition,
+				Status:  corev1.ConditionFalse,
+				Reason:  errorReason(err),
+				Message: err.Error(),
+			})
+		case err != nil:
+			reqLogger.Error(err, "Failed to reconcile component", "Component", c.name)
+			errs = append(errs, fmt.Errorf("%s: %v", c.name, err))
+			reason := errorReason(err)
+			if degradedReason == ReasonReconcileFailed {
+				// the first specific reason explains the degradation best
+				degradedReason = reason
+			}
+			conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
+				Type:    c.condition,
+				Status:  corev1.ConditionFalse,
+				Reason:  reason,
+				Message: err.Error(),
+			})
+		case len(componentSkipped) > 0:
+			conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
+				Type:    c.condition,
+				Status:  corev1.ConditionFalse,
+				Reason:  skipReason,
+				Message: fmt.Sprintf("%s: %s", skipMessage, describeActions(componentSkipped)),
+			})
+		default:
 			conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
-				Type:    conditionsv1.ConditionDegraded,
+				Type:    c.condition,
 				Status:  corev1.ConditionTrue,
-				Reason:  "ReconcileFailed",
-				Message: fmt.Sprintf("Failed reconciliation %v", err),
+				Reason:  "ReconcileCompleted",
+				Message: fmt.Sprintf("The %s is reconciled", c.name),
 			})
+		}
+	}
 
-			statusErr := r.client.Status().Update(context.TODO(), instance)
-			if statusErr != nil {
-				reqLogger.Error(statusErr, "Failed to update degraded condition")
-			}
-			return reconcile.Result{}, err
+	setConflictCondition(instance)
+	setRollbackCondition(instance)
+
+	// the upgrade preview, pre-pulling and the Upgradeable condition change
+	// the cluster, so none of them happen while paused or planning. They do
+	// not depend on the components, and are most useful while a catalog
+	// switch waits or fails.
+	if !paused && !planning {
+		if err := r.previewUpgrades(instance, reqLogger); err != nil {
+			// the preview does not affect the managed operators
+			reqLogger.Error(err, "Failed to preview upgrades")
+		}
+		if err := r.ensurePrePull(instance, reqLogger); err != nil {
+			reqLogger.Error(err, "Failed to pre-pull catalog images")
+		}
+		r.ensureUpgradeable(instance, reqLogger)
+	}
+
+	if aggregate := utilerrors.NewAggregate(errs); aggregate != nil {
+		reqLogger.Info("Updating degraded condition")
+
+		conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
+			Type:    conditionsv1.ConditionDegraded,
+			Status:  corev1.ConditionTrue,
+			Reason:  degradedReason,
+			Message: fmt.Sprintf("Failed reconciliation %v", aggregate),
+		})
+
+		statusErr := r.updateStatus(instance, before, reqLogger)
+		if statusErr != nil {
+			reqLogger.Error(statusErr, "Failed to update degraded condition")
 		}
+		return reconcile.Result{}, aggregate
 	}
 
+	if planning {
+		// the plan is kept even when paused, since it does not change the
+		// cluster
+		instance.Status.Plan = newPlan(instance, skipped)
+	} else {
+		instance.Status.Plan = nil
+	}
+
+	if len(pending) > 0 {
+		conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
+			Type:    conditionsv1.ConditionProgressing,
+			Status:  corev1.ConditionTrue,
+			Reason:  "Pending",
+			Message: strings.Join(pending, "; "),
+		})
+		result := reconcile.Result{RequeueAfter: requeueAfter(instance)}
+		if pollPending {
+			result.RequeueAfter = pendingRequeueAfter
+		}
+		return result, r.updateStatus(instance, before, reqLogger)
+	}
+
+	if planning && !paused {
+		reqLogger.Info("Planned changes", "Changes", describeActions(skipped))
+		message := "The cluster matches the spec"
+		if len(skipped) > 0 {
+			message = fmt.Sprintf("%d changes planned; set spec.mode to %s to make them", len(skipped), kniv1beta1.ModeApply)
+		}
+		conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
+			Type:    conditionsv1.ConditionProgressing,
+			Status:  corev1.ConditionFalse,
+			Reason:  "Planned",
+			Message: message,
+		})
+		return reconcile.Result{RequeueAfter: resolveRequeueAfter(instance)}, r.updateStatus(instance, before, reqLogger)
+	}
+
+	if paused {
+		if len(skipped) > 0 {
+			reqLogger.Info("Skipped changes while paused", "Changes", describeActions(skipped))
+			conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
+				Type:    kniv1beta1.ConditionPaused,
+				Status:  corev1.ConditionTrue,
+				Reason:  "PausedByAnnotation",
+				Message: fmt.Sprintf("Reconciliation is paused, skipped: %s", describeActions(skipped)),
+			})
+		}
+		return reconcile.Result{}, r.updateStatus(instance, before, reqLogger)
+	}
+
+	recordUpgrade(instance)
+
 	conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
 		Type:    conditionsv1.ConditionAvailable,
 		Status:  corev1.ConditionTrue,
@@ -367,74 +579,110 @@ func (r *ReconcileKNICluster) Reconcile(request reconcile.Request) (reconcile.Re
 		Reason:  "ReconcileCompleted",
 		Message: "All objects created",
 	})
-	conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
-		Type:    conditionsv1.ConditionUpgradeable,
-		Status:  corev1.ConditionTrue,
-		Reason:  "ReconcileCompleted",
-		Message: "All objects created",
-	})
+	return reconcile.Result{RequeueAfter: requeueAfter(instance)}, r.updateStatus(instance, before, reqLogger)
+}
 
-	return reconcile.Result{}, r.client.Status().Update(context.TODO(), instance)
+// requeueAfter returns when the KNICluster should be reconciled again, or
+// zero if it need not be
+func requeueAfter(instance *kniv1beta1.KNICluster) time.Duration {
+	after := resolveRequeueAfter(instance)
+	if previewPending(instance) && (after == 0 || after > pendingRequeueAfter) {
+		after = pendingRequeueAfter
+	}
+	if notificationsPending(instance) && (after == 0 || after > notificationRetryAfter) {
+		after = notificationRetryAfter
+	}
+	if retry := previewRetryIn(instance); retry > 0 && (after == 0 || after > retry) {
+		after = retry
+	}
+	if timeout := prePullTimeoutIn(instance); timeout > 0 && (after == 0 || after > timeout) {
+		after = timeout
+	}
+	return after
 }
 
-func newCatalogSource(version string) *olm.CatalogSource {
-	return &olm.CatalogSource{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "demo-catalog",
-			Namespace: "olm",
-		},
-		Spec: olm.CatalogSourceSpec{
-			SourceType: olm.SourceTypeGrpc,
-			// TODO get this from the Status and ensure the update is complete
-			Image:       fmt.Sprintf("quay.io/mhrivnak/demo-operator-registry:%s", version),
-			Publisher:   "kni.openshift.com",
-			DisplayName: "KNI Operators",
-		},
+// recordUpgrade adds the version the catalog currently serves to the upgrade
+// history, unless it is already the latest entry
+func recordUpgrade(instance *kniv1beta1.KNICluster) {
+	version := catalogVersion(instance)
+	if version == "" || upgradeRecorded(instance) {
+		return
 	}
+	appendUpgradeRecord(instance, kniv1beta1.UpgradeRecord{
+		Version:        version,
+		CatalogImage:   instance.Status.CatalogImage,
+		CompletionTime: metav1.Now(),
+		Hooks:          hookStatuses(instance, version),
+	})
 }
 
-func newSubscription(namespace string) *olm.Subscription {
-	return &olm.Subscription{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "kni",
-			Namespace: namespace,
-		},
-		Spec: &olm.SubscriptionSpec{
-			Channel:                "singlenamespace-alpha",
-			Package:                "etcd",
-			CatalogSource:          "demo-catalog",
-			CatalogSourceNamespace: "olm",
-		},
+// catalogVersion returns the cluster version the CatalogSource serves
+func catalogVersion(instance *kniv1beta1.KNICluster) string {
+	version := instance.Status.CatalogVersion
+	image := instance.Status.CatalogImage
+	if version == "" && image != "" {
+		// CatalogSources created before the version label was added
+		version = image[strings.LastIndex(image, ":")+1:]
 	}
+	return version
 }
 
-func newOperatorGroup(namespace string) *olmv1.OperatorGroup {
-	return &olmv1.OperatorGroup{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "kni",
-			Namespace: namespace,
-		},
-		Spec: olmv1.OperatorGroupSpec{
-			TargetNamespaces: []string{namespace},
-		},
+// upgradeRecorded returns whether the latest upgrade record is of the catalog
+// the CatalogSource serves
+func upgradeRecorded(instance *kniv1beta1.KNICluster) bool {
+	history := instance.Status.UpgradeHistory
+	if len(history) == 0 {
+		return false
 	}
+	latest := history[len(history)-1]
+	return latest.Version == catalogVersion(instance) && latest.CatalogImage == instance.Status.CatalogImage && !latest.RolledBack
 }
 
-func GetKNINamespacedName() (types.NamespacedName, error) {
-	kni := types.NamespacedName{
-		Name: KNIClusterNameDefault,
+// appendUpgradeRecord adds record to the upgrade history, dropping the oldest
+// records beyond maxUpgradeHistory
+func appendUpgradeRecord(instance *kniv1beta1.KNICluster, record kniv1beta1.UpgradeRecord) {
+	history := append(instance.Status.UpgradeHistory, record)
+	if len(history) > maxUpgradeHistory {
+		history = history[len(history)-maxUpgradeHistory:]
 	}
+	instance.Status.UpgradeHistory = history
+}
+
+// isPaused returns whether reconciliation of instance is paused
+func isPaused(instance *kniv1beta1.KNICluster) bool {
+	return instance.Annotations[kniv1beta1.PausedAnnotation] == "true"
+}
 
-	// get name
-	if name, ok := os.LookupEnv(KNIClusterNameEnv); ok {
-		kni.Name = name
+// setPausedCondition reflects whether reconciliation is paused
+func setPausedCondition(instance *kniv1beta1.KNICluster, paused bool) {
+	if paused {
+		conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
+			Type:    kniv1beta1.ConditionPaused,
+			Status:  corev1.ConditionTrue,
+			Reason:  "PausedByAnnotation",
+			Message: fmt.Sprintf("Reconciliation is paused by the %s annotation", kniv1beta1.PausedAnnotation),
+		})
+		return
 	}
+	conditionsv1.SetStatusCondition(&instance.Status.Conditions, conditionsv1.Condition{
+		Type:    kniv1beta1.ConditionPaused,
+		Status:  corev1.ConditionFalse,
+		Reason:  "NotPaused",
+		Message: "Reconciliation is active",
+	})
+}
 
-	// get namespace
-	if namespace, ok := os.LookupEnv(KNIClusterNamespaceEnv); ok {
-		kni.Namespace = namespace
-	} else {
-		return kni, fmt.Errorf("%s unset or empty", KNIClusterNamespaceEnv)
+// GetDefaultKNINamespacedName returns the KNICluster the operator creates
+// when it starts. It returns false if KNIClusterNameEnv is unset, in which
+// case KNIClusters are only created by users, in any namespace.
+func GetDefaultKNINamespacedName() (types.NamespacedName, bool, error) {
+	name, ok := os.LookupEnv(KNIClusterNameEnv)
+	if !ok || name == "" {
+		return types.NamespacedName{}, false, nil
+	}
+	namespace, ok := os.LookupEnv(KNIClusterNamespaceEnv)
+	if !ok || namespace == "" {
+		return types.NamespacedName{}, false, fmt.Errorf("%s unset or empty", KNIClusterNamespaceEnv)
 	}
-	return kni, nil
+	return types.NamespacedName{Namespace: namespace, Name: name}, true, nil
 }
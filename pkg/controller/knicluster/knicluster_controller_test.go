@@ -0,0 +1,302 @@
+package knicluster
+
+import (
+	"context"
+	"testing"
+
+	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
+	"github.com/mhrivnak/kni-operator/pkg/apis"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/registry"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	olmv1 "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var testKey = types.NamespacedName{Namespace: "kniops", Name: "example-knicluster"}
+
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		apis.AddToScheme,
+		olm.AddToScheme,
+		olmv1.AddToScheme,
+		osconfigv1.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return scheme
+}
+
+// newTestKNICluster returns a defaulted KNICluster like the sample one, which
+// already has the controller's finalizer
+func newTestKNICluster() *kniv1beta1.KNICluster {
+	instance := &kniv1beta1.KNICluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       testKey.Name,
+			Namespace:  testKey.Namespace,
+			Generation: 1,
+			Finalizers: []string{FinalizerName},
+		},
+		Spec: kniv1beta1.KNIClusterSpec{
+			Catalog: kniv1beta1.CatalogSpec{
+				Name:      "demo-catalog",
+				Namespace: "olm",
+				Image:     kniv1beta1.CatalogImageSpec{Repository: "quay.io/mhrivnak/demo-operator-registry"},
+			},
+			Operators: []kniv1beta1.OperatorSpec{
+				{Name: "kni", Package: "etcd", Channel: "singlenamespace-alpha", Catalog: "demo-catalog"},
+			},
+		},
+	}
+	instance.SetDefaults()
+	return instance
+}
+
+// testClusterVersion returns the ClusterVersion of a cluster that is moving to
+// version
+func testClusterVersion(version string) *osconfigv1.ClusterVersion {
+	return &osconfigv1.ClusterVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "version"},
+		Spec: osconfigv1.ClusterVersionSpec{
+			DesiredUpdate: &osconfigv1.Update{Version: version},
+		},
+	}
+}
+
+// newTestReconciler returns a reconciler of a fake cluster that holds objs
+func newTestReconciler(t *testing.T, objs ...runtime.Object) (*ReconcileKNICluster, *fakeClient) {
+	scheme := newTestScheme(t)
+	c := newFakeClient(scheme, objs...)
+	return &ReconcileKNICluster{client: c, apiReader: c, scheme: scheme, decider: newDecider(), dialCatalog: registry.DialCatalog}, c
+}
+
+// reconcileTestKey reconciles the test KNICluster once, and returns the
+// stored KNICluster along with the result of the reconcile
+func reconcileTestKey(t *testing.T, r *ReconcileKNICluster) (*kniv1beta1.KNICluster, reconcile.Result, error) {
+	t.Helper()
+	result, reconcileErr := r.Reconcile(reconcile.Request{NamespacedName: testKey})
+	reconciled := &kniv1beta1.KNICluster{}
+	if err := r.client.Get(context.TODO(), testKey, reconciled); err != nil {
+		t.Fatal(err)
+	}
+	return reconciled, result, reconcileErr
+}
+
+// reconcileTestKNICluster reconciles instance against a fake cluster that also
+// holds objs, and returns the client and the stored KNICluster
+func reconcileTestKNICluster(t *testing.T, instance *kniv1beta1.KNICluster, objs ...runtime.Object) (*fakeClient, *kniv1beta1.KNICluster) {
+	t.Helper()
+	clusterOperator := &osconfigv1.ClusterOperator{ObjectMeta: metav1.ObjectMeta{Name: ClusterOperatorName}}
+	r, c := newTestReconciler(t, append([]runtime.Object{instance, testClusterVersion("4.1.0"), clusterOperator}, objs...)...)
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	return c, reconciled
+}
+
+func TestReconcilePausedMakesNoChanges(t *testing.T) {
+	instance := newTestKNICluster()
+	instance.Annotations = map[string]string{kniv1beta1.PausedAnnotation: "true"}
+
+	c, reconciled := reconcileTestKNICluster(t, instance)
+
+	checkOnlyStatusUpdated(t, c)
+	paused := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, kniv1beta1.ConditionPaused)
+	if paused == nil || paused.Status != "True" {
+		t.Errorf("Paused condition is %v, want True", paused)
+	}
+	catalog := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, kniv1beta1.ConditionCatalogReady)
+	if catalog == nil || catalog.Reason != "Paused" {
+		t.Errorf("CatalogReady condition is %v, want reason Paused", catalog)
+	}
+	if reconciled.Status.Plan != nil {
+		t.Errorf("paused reconcile in Apply mode set a plan: %v", reconciled.Status.Plan)
+	}
+}
+
+func TestReconcilePlanReportsChanges(t *testing.T) {
+	instance := newTestKNICluster()
+	instance.Spec.Mode = kniv1beta1.ModePlan
+
+	c, reconciled := reconcileTestKNICluster(t, instance)
+
+	checkOnlyStatusUpdated(t, c)
+	plan := reconciled.Status.Plan
+	if plan == nil {
+		t.Fatal("no plan in the status")
+	}
+	if plan.Generation != instance.Generation {
+		t.Errorf("plan is of generation %d, want %d", plan.Generation, instance.Generation)
+	}
+	if !hasPlannedChange(plan, kniv1beta1.PlanActionCreate, "CatalogSource", "olm", "demo-catalog") {
+		t.Errorf("plan %v does not create the CatalogSource", plan.Changes)
+	}
+	progressing := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, conditionsv1.ConditionProgressing)
+	if progressing == nil || progressing.Reason != "Planned" {
+		t.Errorf("Progressing condition is %v, want reason Planned", progressing)
+	}
+}
+
+func TestReconcilePausedPlanKeepsPlan(t *testing.T) {
+	instance := newTestKNICluster()
+	instance.Annotations = map[string]string{kniv1beta1.PausedAnnotation: "true"}
+	instance.Spec.Mode = kniv1beta1.ModePlan
+
+	c, reconciled := reconcileTestKNICluster(t, instance)
+
+	checkOnlyStatusUpdated(t, c)
+	if reconciled.Status.Plan == nil || len(reconciled.Status.Plan.Changes) == 0 {
+		t.Errorf("plan is %v, want the planned changes", reconciled.Status.Plan)
+	}
+}
+
+func TestReconcileAdoptsLegacyCatalogSource(t *testing.T) {
+	t.Setenv(KNIClusterNameEnv, testKey.Name)
+	t.Setenv(KNIClusterNamespaceEnv, testKey.Namespace)
+	instance := newTestKNICluster()
+	instance.Spec.Catalog.Image.SkipContentValidation = true
+	// the CatalogSource as the operator created it before it kept track of
+	// what it owns
+	legacy := &olm.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-catalog", Namespace: "olm"},
+		Spec: olm.CatalogSourceSpec{
+			SourceType: olm.SourceTypeGrpc,
+			Image:      "quay.io/mhrivnak/demo-operator-registry:4.0.0",
+		},
+	}
+
+	c, reconciled := reconcileTestKNICluster(t, instance, legacy)
+
+	if len(reconciled.Status.Conflicts) != 0 {
+		t.Errorf("conflicts %v, want none", reconciled.Status.Conflicts)
+	}
+	found := &olm.CatalogSource{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "olm", Name: "demo-catalog"}, found); err != nil {
+		t.Fatal(err)
+	}
+	if !isOwner(reconciled, found) {
+		t.Errorf("CatalogSource labels are %v, want the owner labels", found.Labels)
+	}
+	if want := "quay.io/mhrivnak/demo-operator-registry:4.1.0"; found.Spec.Image != want {
+		t.Errorf("CatalogSource image is %s, want %s", found.Spec.Image, want)
+	}
+	if found.Labels[kniv1beta1.CatalogVersionLabel] != "4.1.0" {
+		t.Errorf("CatalogSource version label is %q, want 4.1.0", found.Labels[kniv1beta1.CatalogVersionLabel])
+	}
+}
+
+func TestDeleteRemovesLegacyCatalogSource(t *testing.T) {
+	t.Setenv(KNIClusterNameEnv, testKey.Name)
+	t.Setenv(KNIClusterNamespaceEnv, testKey.Namespace)
+	instance := newTestKNICluster()
+	now := metav1.Now()
+	instance.DeletionTimestamp = &now
+	legacy := &olm.CatalogSource{ObjectMeta: metav1.ObjectMeta{Name: "demo-catalog", Namespace: "olm"}}
+
+	c, _ := reconcileTestKNICluster(t, instance, legacy)
+
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: "olm", Name: "demo-catalog"}, &olm.CatalogSource{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("getting the legacy CatalogSource returned %v, want it deleted", err)
+	}
+}
+
+func TestReconcileRefusesUnownedCatalogSource(t *testing.T) {
+	// without a default KNICluster, the same CatalogSource is not legacy
+	instance := newTestKNICluster()
+	instance.Spec.Catalog.Image.SkipContentValidation = true
+	unowned := &olm.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-catalog", Namespace: "olm"},
+		Spec: olm.CatalogSourceSpec{
+			SourceType: olm.SourceTypeGrpc,
+			Image:      "quay.io/mhrivnak/demo-operator-registry:4.0.0",
+		},
+	}
+	r, c := newTestReconciler(t, instance, unowned, testClusterVersion("4.1.0"))
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err == nil {
+		t.Fatal("reconcile succeeded, want a conflict")
+	}
+	if len(reconciled.Status.Conflicts) != 1 || reconciled.Status.Conflicts[0].Name != "demo-catalog" {
+		t.Errorf("conflicts %v, want the CatalogSource", reconciled.Status.Conflicts)
+	}
+	found := &olm.CatalogSource{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "olm", Name: "demo-catalog"}, found); err != nil {
+		t.Fatal(err)
+	}
+	if found.Spec.Image != unowned.Spec.Image {
+		t.Errorf("CatalogSource image changed to %s", found.Spec.Image)
+	}
+}
+
+func TestReconcileSecondKNIClusterSameCatalog(t *testing.T) {
+	instance := newTestKNICluster()
+	instance.Spec.Catalog.Image.SkipContentValidation = true
+	second := newTestKNICluster()
+	second.Namespace = "other"
+	second.Spec.Catalog.Image.SkipContentValidation = true
+	r, c := newTestReconciler(t, instance, second, testClusterVersion("4.1.0"))
+	if _, _, err := reconcileTestKey(t, r); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	secondKey := types.NamespacedName{Namespace: second.Namespace, Name: second.Name}
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: secondKey}); err == nil {
+		t.Fatal("reconcile of the second KNICluster succeeded, want a conflict")
+	}
+
+	reconciled := &kniv1beta1.KNICluster{}
+	if err := c.Get(context.TODO(), secondKey, reconciled); err != nil {
+		t.Fatal(err)
+	}
+	if len(reconciled.Status.Conflicts) != 1 || reconciled.Status.Conflicts[0].Message != "it is managed by KNICluster "+testKey.String() {
+		t.Errorf("conflicts of the second KNICluster are %v, want the CatalogSource of the first", reconciled.Status.Conflicts)
+	}
+	found := &olm.CatalogSource{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "olm", Name: "demo-catalog"}, found); err != nil {
+		t.Fatal(err)
+	}
+	if !isOwner(instance, found) {
+		t.Errorf("CatalogSource labels are %v, want it kept by the first KNICluster", found.Labels)
+	}
+}
+
+// checkOnlyStatusUpdated checks that the only write made through c was to
+// the status of the KNICluster
+func checkOnlyStatusUpdated(t *testing.T, c *fakeClient) {
+	t.Helper()
+	if len(c.writes) != 0 {
+		t.Errorf("reconcile wrote %v", c.writes)
+	}
+	if len(c.statusUpdates) == 0 {
+		t.Error("reconcile did not update the status")
+	}
+	knicluster := "KNICluster " + testKey.String()
+	for _, updated := range c.statusUpdates {
+		if updated != knicluster {
+			t.Errorf("reconcile updated the status of %s", updated)
+		}
+	}
+}
+
+func hasPlannedChange(plan *kniv1beta1.PlanStatus, action, kind, namespace, name string) bool {
+	for _, change := range plan.Changes {
+		if change.Action == action && change.Kind == kind && change.Namespace == namespace && change.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package knicluster
+
+import (
+	"context"
+	"sort"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// imageContentSourcePolicyListGVK identifies OpenShift's list of
+// ImageContentSourcePolicies, which are read as unstructured objects
+var imageContentSourcePolicyListGVK = schema.GroupVersionKind{
+	Group:   "operator.openshift.io",
+	Version: "v1alpha1",
+	Kind:    "ImageContentSourcePolicyList",
+}
+
+// imageMirrors returns the mirrors that apply to instance, including those
+// of ImageContentSourcePolicies if the spec asks for them
+func (r *ReconcileKNICluster) imageMirrors(instance *kniv1beta1.KNICluster) ([]kniv1beta1.ImageMirror, error) {
+	if !instance.Spec.UseImageContentSourcePolicies {
+		return render.Mirrors(instance, nil), nil
+	}
+
+	policies := &unstructured.UnstructuredList{}
+	policies.SetGroupVersionKind(imageContentSourcePolicyListGVK)
+	err := r.client.List(context.TODO(), &client.ListOptions{}, policies)
+	if meta.IsNoMatchError(err) {
+		// not an OpenShift cluster
+		return render.Mirrors(instance, nil), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// sorted by name, so that the mirror used for a source is stable
+	sort.Slice(policies.Items, func(i, j int) bool {
+		return policies.Items[i].GetName() < policies.Items[j].GetName()
+	})
+	var policyMirrors []kniv1beta1.ImageMirror
+	for _, policy := range policies.Items {
+		digestMirrors, _, err := unstructured.NestedSlice(policy.Object, "spec", "repositoryDigestMirrors")
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range digestMirrors {
+			digestMirror, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			source, _, _ := unstructured.NestedString(digestMirror, "source")
+			mirrors, _, _ := unstructured.NestedStringSlice(digestMirror, "mirrors")
+			// the first mirror is the preferred one
+			if source != "" && len(mirrors) > 0 {
+				policyMirrors = append(policyMirrors, kniv1beta1.ImageMirror{Source: source, Mirror: mirrors[0]})
+			}
+		}
+	}
+	return render.Mirrors(instance, policyMirrors), nil
+}
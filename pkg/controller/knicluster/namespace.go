@@ -0,0 +1,152 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	objectreferencesv1 "github.com/djzager/custom-resource-status/objectreferences/v1"
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/reference"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReasonNamespaceTerminating is the condition reason while a managed
+// namespace is still being deleted, so that it cannot be used yet
+const ReasonNamespaceTerminating = "NamespaceTerminating"
+
+// ensureNamespaces ensures the namespaces in the spec exist with their labels
+// and annotations, and applies the deletion policy to the namespaces the
+// operator created that were removed from the spec.
+func (r *ReconcileKNICluster) ensureNamespaces(instance *kniv1beta1.KNICluster, reqLogger logr.Logger) error {
+	var errs []error
+	var terminating []string
+	desired := map[string]bool{}
+	for _, spec := range instance.Spec.Namespaces {
+		desired[spec.Name] = true
+		ready, err := r.ensureNamespace(instance, spec, reqLogger)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %v", spec.Name, err))
+		} else if !ready {
+			terminating = append(terminating, spec.Name)
+		}
+	}
+
+	if err := r.releaseNamespaces(instance, desired, reqLogger); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+	if len(terminating) > 0 {
+		return &pendingError{
+			reason: ReasonNamespaceTerminating,
+			err:    fmt.Errorf("waiting for the deletion of namespaces %s to finish", strings.Join(terminating, ", ")),
+		}
+	}
+	return nil
+}
+
+// ensureNamespace ensures the namespace described by spec exists and has its
+// labels and annotations. Only namespaces the operator creates get the owner
+// labels, so that namespaces that already existed are never deleted. It
+// returns false while the namespace is terminating.
+func (r *ReconcileKNICluster) ensureNamespace(instance *kniv1beta1.KNICluster, spec kniv1beta1.NamespaceSpec, reqLogger logr.Logger) (bool, error) {
+	namespace := render.Namespace(spec)
+
+	// Check if this Namespace already exists
+	found := &corev1.Namespace{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: namespace.Name}, found)
+	if err != nil && errors.IsNotFound(err) {
+		if err := r.setOwner(instance, namespace); err != nil {
+			return false, err
+		}
+		reqLogger.Info("Creating a new Namespace", "Namespace.Name", namespace.Name)
+		return true, r.client.Create(context.TODO(), namespace)
+	} else if err != nil {
+		return false, err
+	}
+
+	if found.DeletionTimestamp != nil {
+		reqLogger.Info("Namespace is terminating", "Namespace.Name", found.Name)
+		return false, nil
+	}
+
+	// add the labels and annotations of the spec, keeping any others
+	changed := false
+	for key, value := range namespace.Labels {
+		if found.Labels[key] != value {
+			if found.Labels == nil {
+				found.Labels = map[string]string{}
+			}
+			found.Labels[key] = value
+			changed = true
+		}
+	}
+	for key, value := range namespace.Annotations {
+		if found.Annotations[key] != value {
+			if found.Annotations == nil {
+				found.Annotations = map[string]string{}
+			}
+			found.Annotations[key] = value
+			changed = true
+		}
+	}
+	if changed {
+		reqLogger.Info("Updating the Namespace labels and annotations", "Namespace.Name", found.Name)
+		err = r.client.Update(context.TODO(), found)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	objectRef, err := reference.GetReference(r.scheme, found)
+	if err != nil {
+		return false, err
+	}
+	// Add it to the list of RelatedObjects if found
+	objectreferencesv1.SetObjectReference(&instance.Status.RelatedObjects, *objectRef)
+
+	return true, nil
+}
+
+// releaseNamespaces applies the deletion policy to the namespaces the operator
+// created for instance that are not in desired. With the Retain policy, the
+// owner labels are removed, so that the namespace is no longer managed.
+func (r *ReconcileKNICluster) releaseNamespaces(instance *kniv1beta1.KNICluster, desired map[string]bool, reqLogger logr.Logger) error {
+	namespaces := &corev1.NamespaceList{}
+	err := r.client.List(context.TODO(), client.MatchingLabels(render.OwnerLabels(instance)), namespaces)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range namespaces.Items {
+		namespace := &namespaces.Items[i]
+		if desired[namespace.Name] || namespace.DeletionTimestamp != nil {
+			continue
+		}
+		if instance.Spec.NamespaceDeletionPolicy == kniv1beta1.NamespaceDeletionDelete {
+			reqLogger.Info("Deleting Namespace that is no longer managed", "Namespace.Name", namespace.Name)
+			err = r.client.Delete(context.TODO(), namespace)
+			if err != nil && !errors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("namespace %s: %v", namespace.Name, err))
+			}
+			continue
+		}
+		reqLogger.Info("Retaining Namespace that is no longer managed", "Namespace.Name", namespace.Name)
+		delete(namespace.Labels, kniv1beta1.OwnerNameLabel)
+		delete(namespace.Labels, kniv1beta1.OwnerNamespaceLabel)
+		err = r.client.Update(context.TODO(), namespace)
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("namespace %s: %v", namespace.Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,144 @@
+package knicluster
+
+import (
+	"context"
+	"testing"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	olmv1 "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// newNamespaceTestKNICluster returns the test KNICluster installing its
+// operator in the managed namespace kni-operators
+func newNamespaceTestKNICluster() *kniv1beta1.KNICluster {
+	instance := newTestKNICluster()
+	instance.Spec.Namespaces = []kniv1beta1.NamespaceSpec{{
+		Name:        "kni-operators",
+		Labels:      map[string]string{"openshift.io/cluster-monitoring": "true"},
+		Annotations: map[string]string{"openshift.io/node-selector": ""},
+	}}
+	instance.Spec.Operators[0].Namespace = "kni-operators"
+	return instance
+}
+
+// ownedNamespace returns the namespace name as the operator created it for
+// instance
+func ownedNamespace(instance *kniv1beta1.KNICluster, name string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: render.OwnerLabels(instance)}}
+}
+
+func getNamespace(t *testing.T, c *fakeClient, name string) *corev1.Namespace {
+	t.Helper()
+	namespace := &corev1.Namespace{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: name}, namespace); err != nil {
+		t.Fatalf("getting namespace %s: %v", name, err)
+	}
+	return namespace
+}
+
+func TestReconcileInstallsInManagedNamespace(t *testing.T) {
+	instance := newNamespaceTestKNICluster()
+
+	c, _ := reconcileTestKNICluster(t, instance)
+
+	namespace := getNamespace(t, c, "kni-operators")
+	if !isOwner(instance, namespace) || namespace.Labels["openshift.io/cluster-monitoring"] != "true" {
+		t.Errorf("namespace has labels %v, want the spec's and the owner labels", namespace.Labels)
+	}
+	if _, ok := namespace.Annotations["openshift.io/node-selector"]; !ok {
+		t.Errorf("namespace has annotations %v, want the spec's", namespace.Annotations)
+	}
+
+	operatorGroup := &olmv1.OperatorGroup{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "kni-operators", Name: render.OperatorGroupName}, operatorGroup); err != nil {
+		t.Fatalf("getting the OperatorGroup: %v", err)
+	}
+	if !isOwner(instance, operatorGroup) {
+		t.Errorf("OperatorGroup has labels %v, want the owner labels", operatorGroup.Labels)
+	}
+	subscription := &olm.Subscription{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "kni-operators", Name: "kni"}, subscription); err != nil {
+		t.Fatalf("getting the Subscription: %v", err)
+	}
+	if !isOwner(instance, subscription) {
+		t.Errorf("Subscription has labels %v, want the owner labels", subscription.Labels)
+	}
+	// nothing is installed in the KNICluster's namespace
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: render.OperatorGroupName}, &olmv1.OperatorGroup{}); !errors.IsNotFound(err) {
+		t.Errorf("getting the OperatorGroup of %s returned %v, want none", instance.Namespace, err)
+	}
+}
+
+func TestEnsureNamespacesKeepsExistingNamespace(t *testing.T) {
+	instance := newNamespaceTestKNICluster()
+	existing := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "kni-operators",
+		Labels: map[string]string{"team": "kni"},
+	}}
+	r, c := newTestReconciler(t, instance, existing)
+
+	if err := r.ensureNamespaces(instance, log); err != nil {
+		t.Fatal(err)
+	}
+
+	namespace := getNamespace(t, c, "kni-operators")
+	if namespace.Labels["team"] != "kni" || namespace.Labels["openshift.io/cluster-monitoring"] != "true" {
+		t.Errorf("namespace has labels %v, want its own and the spec's", namespace.Labels)
+	}
+	// it existed before, so it is never deleted
+	if isOwner(instance, namespace) {
+		t.Error("existing namespace got the owner labels")
+	}
+}
+
+func TestEnsureNamespacesDeletionPolicy(t *testing.T) {
+	for _, policy := range []kniv1beta1.NamespaceDeletionPolicy{kniv1beta1.NamespaceDeletionRetain, kniv1beta1.NamespaceDeletionDelete} {
+		instance := newTestKNICluster()
+		instance.Spec.NamespaceDeletionPolicy = policy
+		unmanaged := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+		r, c := newTestReconciler(t, instance, ownedNamespace(instance, "kni-operators"), unmanaged)
+
+		// kni-operators was removed from the spec
+		if err := r.ensureNamespaces(instance, log); err != nil {
+			t.Fatalf("%s: %v", policy, err)
+		}
+
+		namespace := &corev1.Namespace{}
+		err := c.Get(context.TODO(), types.NamespacedName{Name: "kni-operators"}, namespace)
+		switch policy {
+		case kniv1beta1.NamespaceDeletionRetain:
+			if err != nil {
+				t.Fatalf("%s: getting the namespace: %v", policy, err)
+			}
+			if isOwner(instance, namespace) {
+				t.Errorf("%s: retained namespace still has the owner labels", policy)
+			}
+		case kniv1beta1.NamespaceDeletionDelete:
+			if !errors.IsNotFound(err) {
+				t.Errorf("%s: getting the namespace returned %v, want it deleted", policy, err)
+			}
+		}
+		getNamespace(t, c, "other")
+	}
+}
+
+func TestEnsureNamespacesWaitsForTerminating(t *testing.T) {
+	instance := newNamespaceTestKNICluster()
+	terminating := ownedNamespace(instance, "kni-operators")
+	now := metav1.Now()
+	terminating.DeletionTimestamp = &now
+	r, _ := newTestReconciler(t, instance, terminating)
+
+	err := r.ensureNamespaces(instance, log)
+
+	pending, ok := err.(*pendingError)
+	if !ok || pending.reason != ReasonNamespaceTerminating {
+		t.Errorf("ensureNamespaces returned %v, want reason %s", err, ReasonNamespaceTerminating)
+	}
+}
@@ -0,0 +1,371 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/notify"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// maxDeliveredNotifications is the number of delivered event IDs kept
+	// per notification to avoid sending an event twice
+	maxDeliveredNotifications = 20
+	// maxNotificationAttempts is the number of failed deliveries after which
+	// an event is dropped
+	maxNotificationAttempts = 10
+	// notificationRetryAfter is how long to wait before retrying failed
+	// deliveries
+	notificationRetryAfter = time.Minute
+)
+
+// updateStatus queues notifications for the lifecycle events between before
+// and the status of instance, and stores the status. Queued events are only
+// delivered once they are stored, by the notifier, so that a failed status
+// update cannot lose track of what was sent. Nothing is queued or delivered
+// while paused or planning, since the changes are not made.
+func (r *ReconcileKNICluster) updateStatus(instance *kniv1beta1.KNICluster, before *kniv1beta1.KNIClusterStatus, reqLogger logr.Logger) error {
+	quiet := notificationsSuppressed(instance)
+	if !quiet {
+		queueNotifications(instance, before)
+	}
+	if err := r.client.Status().Update(context.TODO(), instance); err != nil {
+		return err
+	}
+	if !quiet && notificationsPending(instance) && r.notifier != nil {
+		r.notifier.trigger(types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name})
+	}
+	return nil
+}
+
+// queueNotifications queues the lifecycle events between before and the
+// status of instance for each notification that wants them
+func queueNotifications(instance *kniv1beta1.KNICluster, before *kniv1beta1.KNIClusterStatus) {
+	queueEvents(instance, lifecycleEvents(before, &instance.Status))
+}
+
+// queueUpgradeStarted queues the event of the catalog switching from one
+// version to another, as the switch is written. The status only shows the
+// new version once the CatalogSource is read again.
+func (r *ReconcileKNICluster) queueUpgradeStarted(instance *kniv1beta1.KNICluster, from, to string) {
+	if _, dryRun := r.client.(*recordingClient); dryRun || from == "" || from == to {
+		return
+	}
+	now := metav1.Now()
+	queueEvents(instance, []kniv1beta1.PendingNotification{{
+		ID:      fmt.Sprintf("%s/%s/%s/%d", kniv1beta1.EventUpgradeStarted, from, to, now.Unix()),
+		Event:   kniv1beta1.EventUpgradeStarted,
+		Version: to,
+		Message: fmt.Sprintf("Started upgrading the KNI catalog from %s to %s", from, to),
+		Time:    now,
+	}})
+}
+
+// queueEvents queues events for each notification that wants them
+func queueEvents(instance *kniv1beta1.KNICluster, events []kniv1beta1.PendingNotification) {
+	if len(instance.Spec.Notifications) == 0 {
+		instance.Status.Notifications = nil
+		return
+	}
+
+	existing := map[string]kniv1beta1.NotificationStatus{}
+	for _, status := range instance.Status.Notifications {
+		existing[status.Name] = status
+	}
+
+	var statuses []kniv1beta1.NotificationStatus
+	for _, spec := range instance.Spec.Notifications {
+		status, ok := existing[spec.Name]
+		if !ok {
+			status = kniv1beta1.NotificationStatus{Name: spec.Name}
+		}
+		for _, event := range events {
+			if wantsEvent(spec, event.Event) && !notificationQueued(status, event.ID) {
+				status.Pending = append(status.Pending, event)
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	instance.Status.Notifications = statuses
+}
+
+// notifier delivers the queued notifications of KNIClusters in the
+// background, so that slow endpoints do not hold up reconciliation. Each
+// KNICluster is delivered for by at most one goroutine at a time.
+type notifier struct {
+	client    client.Client
+	apiReader client.Reader
+
+	mu sync.Mutex
+	// running holds the KNIClusters being delivered for, and whether they
+	// were triggered again meanwhile
+	running map[types.NamespacedName]bool
+}
+
+func newNotifier(c client.Client, apiReader client.Reader) *notifier {
+	return &notifier{client: c, apiReader: apiReader, running: map[types.NamespacedName]bool{}}
+}
+
+// trigger delivers the queued notifications of the KNICluster key
+func (n *notifier) trigger(key types.NamespacedName) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.running[key]; ok {
+		n.running[key] = true
+		return
+	}
+	n.running[key] = false
+	go n.run(key)
+}
+
+func (n *notifier) run(key types.NamespacedName) {
+	logger := log.WithValues("Request.Namespace", key.Namespace, "Request.Name", key.Name)
+	for {
+		if err := n.deliver(key, logger); err != nil {
+			logger.Error(err, "Failed to deliver notifications")
+		}
+		n.mu.Lock()
+		if !n.running[key] {
+			delete(n.running, key)
+			n.mu.Unlock()
+			return
+		}
+		n.running[key] = false
+		n.mu.Unlock()
+	}
+}
+
+// deliveryResult is the outcome of delivering the pending events of a
+// notification
+type deliveryResult struct {
+	delivered map[string]bool
+	failed    map[string]bool
+	dropped   map[string]bool
+	lastError string
+}
+
+// deliver sends the pending events of the KNICluster key, and records the
+// outcome in its latest status
+func (n *notifier) deliver(key types.NamespacedName, logger logr.Logger) error {
+	instance := &kniv1beta1.KNICluster{}
+	err := n.apiReader.Get(context.TODO(), key, instance)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if notificationsSuppressed(instance) {
+		return nil
+	}
+
+	results := map[string]*deliveryResult{}
+	for _, spec := range instance.Spec.Notifications {
+		for _, status := range instance.Status.Notifications {
+			if status.Name == spec.Name && len(status.Pending) > 0 {
+				results[spec.Name] = n.send(instance, spec, status.Pending, logger)
+			}
+		}
+	}
+	if len(results) == 0 {
+		return nil
+	}
+
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		latest := &kniv1beta1.KNICluster{}
+		if err := n.apiReader.Get(context.TODO(), key, latest); err != nil {
+			return err
+		}
+		for i := range latest.Status.Notifications {
+			if result, ok := results[latest.Status.Notifications[i].Name]; ok {
+				applyDelivery(&latest.Status.Notifications[i], result)
+			}
+		}
+		return n.client.Status().Update(context.TODO(), latest)
+	})
+}
+
+// send sends events in order to the endpoint of a notification
+func (n *notifier) send(instance *kniv1beta1.KNICluster, spec kniv1beta1.NotificationSpec, events []kniv1beta1.PendingNotification, logger logr.Logger) *deliveryResult {
+	result := &deliveryResult{delivered: map[string]bool{}, failed: map[string]bool{}, dropped: map[string]bool{}}
+
+	secret := &corev1.Secret{}
+	err := n.apiReader.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: spec.URLSecret}, secret)
+	if err == nil && len(secret.Data["url"]) == 0 {
+		err = fmt.Errorf("Secret %s has no url", spec.URLSecret)
+	}
+	if err != nil {
+		result.lastError = fmt.Sprintf("reading the URL: %v", err)
+		return result
+	}
+	url := string(secret.Data["url"])
+
+	for _, event := range events {
+		err := notify.Send(context.TODO(), url, spec.Format, notify.Event{
+			ID:      event.ID,
+			Event:   event.Event,
+			Cluster: notify.Cluster{Name: instance.Name, Namespace: instance.Namespace},
+			Version: event.Version,
+			Message: event.Message,
+			Time:    event.Time.Time,
+		})
+		if err == nil {
+			logger.Info("Sent notification", "Notification", spec.Name, "Event", event.Event, "ID", event.ID)
+			result.delivered[event.ID] = true
+			continue
+		}
+
+		result.lastError = err.Error()
+		if event.Attempts+1 >= maxNotificationAttempts {
+			logger.Error(err, "Dropping notification after repeated failures", "Notification", spec.Name, "Event", event.Event, "ID", event.ID)
+			result.dropped[event.ID] = true
+			continue
+		}
+		logger.Error(err, "Failed to send notification", "Notification", spec.Name, "Event", event.Event, "ID", event.ID)
+		result.failed[event.ID] = true
+	}
+	return result
+}
+
+// applyDelivery updates status with the outcome of a delivery. Events queued
+// after the delivery started stay pending.
+func applyDelivery(status *kniv1beta1.NotificationStatus, result *deliveryResult) {
+	var pending []kniv1beta1.PendingNotification
+	for _, event := range status.Pending {
+		switch {
+		case result.delivered[event.ID]:
+			if !notificationDelivered(*status, event.ID) {
+				status.Delivered = append(status.Delivered, event.ID)
+			}
+		case result.dropped[event.ID]:
+		case result.failed[event.ID]:
+			event.Attempts++
+			pending = append(pending, event)
+		default:
+			pending = append(pending, event)
+		}
+	}
+	status.Pending = pending
+	if result.lastError != "" {
+		status.LastError = result.lastError
+	}
+	if len(status.Delivered) > maxDeliveredNotifications {
+		status.Delivered = status.Delivered[len(status.Delivered)-maxDeliveredNotifications:]
+	}
+}
+
+// lifecycleEvents returns the events shown by the change from before to
+// after. Event IDs are derived from the change, so that the same change
+// seen twice has the same ID.
+func lifecycleEvents(before, after *kniv1beta1.KNIClusterStatus) []kniv1beta1.PendingNotification {
+	var events []kniv1beta1.PendingNotification
+	recorded := map[string]bool{}
+	for _, record := range before.UpgradeHistory {
+		recorded[upgradeRecordID(record)] = true
+	}
+	for _, record := range after.UpgradeHistory {
+		if recorded[upgradeRecordID(record)] {
+			continue
+		}
+		event := kniv1beta1.PendingNotification{
+			ID:      fmt.Sprintf("%s/%s", kniv1beta1.EventUpgradeCompleted, upgradeRecordID(record)),
+			Event:   kniv1beta1.EventUpgradeCompleted,
+			Version: record.Version,
+			Message: fmt.Sprintf("Completed upgrading the KNI catalog to %s", record.Version),
+		}
+		if record.RolledBack {
+			event.ID = fmt.Sprintf("%s/%s", kniv1beta1.EventRolledBack, upgradeRecordID(record))
+			event.Event = kniv1beta1.EventRolledBack
+			event.Message = fmt.Sprintf("Rolled back the KNI catalog from %s", record.Version)
+			if after.Rollback != nil {
+				event.Message = fmt.Sprintf("Rolled back the KNI catalog from %s to %s: %s", record.Version, after.Rollback.ToVersion, after.Rollback.Reason)
+			}
+		}
+		events = append(events, event)
+	}
+
+	wasDegraded := conditionsv1.IsStatusConditionTrue(before.Conditions, conditionsv1.ConditionDegraded)
+	degraded := conditionsv1.FindStatusCondition(after.Conditions, conditionsv1.ConditionDegraded)
+	if !wasDegraded && degraded != nil && degraded.Status == corev1.ConditionTrue {
+		events = append(events, kniv1beta1.PendingNotification{
+			ID:      fmt.Sprintf("%s/%d", kniv1beta1.EventDegraded, degraded.LastTransitionTime.Unix()),
+			Event:   kniv1beta1.EventDegraded,
+			Version: after.CatalogVersion,
+			Message: fmt.Sprintf("Degraded (%s): %s", degraded.Reason, degraded.Message),
+		})
+	}
+
+	now := metav1.Now()
+	for i := range events {
+		events[i].Time = now
+	}
+	return events
+}
+
+// upgradeRecordID identifies an upgrade record
+func upgradeRecordID(record kniv1beta1.UpgradeRecord) string {
+	return fmt.Sprintf("%s/%d", record.Version, record.CompletionTime.Unix())
+}
+
+// wantsEvent returns whether a notification sends events of type event
+func wantsEvent(spec kniv1beta1.NotificationSpec, event kniv1beta1.NotificationEvent) bool {
+	if len(spec.Events) == 0 {
+		return true
+	}
+	for _, wanted := range spec.Events {
+		if wanted == event {
+			return true
+		}
+	}
+	return false
+}
+
+// notificationQueued returns whether the event with id was delivered or is
+// pending
+func notificationQueued(status kniv1beta1.NotificationStatus, id string) bool {
+	if notificationDelivered(status, id) {
+		return true
+	}
+	for _, pending := range status.Pending {
+		if pending.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// notificationDelivered returns whether the event with id was delivered
+func notificationDelivered(status kniv1beta1.NotificationStatus, id string) bool {
+	for _, delivered := range status.Delivered {
+		if delivered == id {
+			return true
+		}
+	}
+	return false
+}
+
+// notificationsSuppressed returns whether notifications of instance are
+// neither queued nor delivered, because it is paused or planning
+func notificationsSuppressed(instance *kniv1beta1.KNICluster) bool {
+	return isPaused(instance) || instance.Spec.Mode == kniv1beta1.ModePlan
+}
+
+// notificationsPending returns whether any event waits to be delivered again
+func notificationsPending(instance *kniv1beta1.KNICluster) bool {
+	for _, status := range instance.Status.Notifications {
+		if len(status.Pending) > 0 {
+			return true
+		}
+	}
+	return false
+}
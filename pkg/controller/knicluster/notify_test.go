@@ -0,0 +1,276 @@
+package knicluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/notify"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testEndpoint is a notification endpoint that records the events posted to
+// it, and answers with a settable status
+type testEndpoint struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	status int
+	events []notify.Event
+}
+
+func newTestEndpoint(t *testing.T) *testEndpoint {
+	endpoint := &testEndpoint{status: http.StatusOK}
+	endpoint.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		event := notify.Event{}
+		if err := json.NewDecoder(req.Body).Decode(&event); err != nil {
+			t.Errorf("decoding the event: %v", err)
+		}
+		endpoint.mu.Lock()
+		defer endpoint.mu.Unlock()
+		endpoint.events = append(endpoint.events, event)
+		w.WriteHeader(endpoint.status)
+	}))
+	t.Cleanup(endpoint.Close)
+	return endpoint
+}
+
+func (e *testEndpoint) setStatus(status int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.status = status
+}
+
+func (e *testEndpoint) received() []notify.Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]notify.Event(nil), e.events...)
+}
+
+// newNotifyTestKNICluster returns the test KNICluster with a notification
+// posting to endpoint, and the Secret holding its URL
+func newNotifyTestKNICluster(endpoint *testEndpoint) (*kniv1beta1.KNICluster, *corev1.Secret) {
+	instance := newTestKNICluster()
+	instance.Spec.Catalog.Image.SkipContentValidation = true
+	instance.Spec.Notifications = []kniv1beta1.NotificationSpec{{Name: "audit", Format: kniv1beta1.NotificationFormatWebhook, URLSecret: "kni-audit-webhook"}}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: instance.Namespace, Name: "kni-audit-webhook"},
+		Data:       map[string][]byte{"url": []byte(endpoint.URL)},
+	}
+	return instance, secret
+}
+
+// pendingEvent returns a pending event of the test KNICluster
+func pendingEvent(id string, attempts int32) kniv1beta1.PendingNotification {
+	return kniv1beta1.PendingNotification{
+		ID:       id,
+		Event:    kniv1beta1.EventUpgradeCompleted,
+		Version:  "4.1.0",
+		Message:  "Completed upgrading the KNI catalog to 4.1.0",
+		Time:     metav1.Now(),
+		Attempts: attempts,
+	}
+}
+
+// deliverTestKey delivers the notifications of the test KNICluster once, and
+// returns its notification status
+func deliverTestKey(t *testing.T, r *ReconcileKNICluster) kniv1beta1.NotificationStatus {
+	t.Helper()
+	if err := newNotifier(r.client, r.apiReader).deliver(testKey, log); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	reconciled := &kniv1beta1.KNICluster{}
+	if err := r.client.Get(context.TODO(), testKey, reconciled); err != nil {
+		t.Fatal(err)
+	}
+	if len(reconciled.Status.Notifications) != 1 {
+		t.Fatalf("notification statuses are %v, want one", reconciled.Status.Notifications)
+	}
+	return reconciled.Status.Notifications[0]
+}
+
+// upgradeStartedEvents returns the UpgradeStarted events of status
+func upgradeStartedEvents(status kniv1beta1.NotificationStatus) []kniv1beta1.PendingNotification {
+	var events []kniv1beta1.PendingNotification
+	for _, event := range status.Pending {
+		if event.Event == kniv1beta1.EventUpgradeStarted {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestUpgradeStartedQueuedWithSwitch(t *testing.T) {
+	instance, secret := newNotifyTestKNICluster(newTestEndpoint(t))
+	clusterOperator := &osconfigv1.ClusterOperator{ObjectMeta: metav1.ObjectMeta{Name: ClusterOperatorName}}
+	r, c := newTestReconciler(t, instance, secret, newOwnedCatalogSource(instance, "4.0.0"), testClusterVersion("4.1.0"), clusterOperator)
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if version := catalogSourceVersion(t, c); version != "4.1.0" {
+		t.Fatalf("catalog serves %s, want 4.1.0", version)
+	}
+	started := upgradeStartedEvents(reconciled.Status.Notifications[0])
+	if len(started) != 1 || started[0].Version != "4.1.0" {
+		t.Fatalf("UpgradeStarted events are %v, want one for 4.1.0 queued with the switch", started)
+	}
+
+	// the status catching up with the switch queues nothing more
+	reconciled, _, err = reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if again := upgradeStartedEvents(reconciled.Status.Notifications[0]); len(again) != 1 || again[0].ID != started[0].ID {
+		t.Errorf("UpgradeStarted events are %v after the next reconcile, want only %s", again, started[0].ID)
+	}
+}
+
+func TestNotificationsSuppressedWhilePausedOrPlanning(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		setup func(*kniv1beta1.KNICluster)
+	}{
+		{
+			name: "paused",
+			setup: func(instance *kniv1beta1.KNICluster) {
+				instance.Annotations = map[string]string{kniv1beta1.PausedAnnotation: "true"}
+			},
+		},
+		{
+			name:  "planning",
+			setup: func(instance *kniv1beta1.KNICluster) { instance.Spec.Mode = kniv1beta1.ModePlan },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := newTestEndpoint(t)
+			instance, secret := newNotifyTestKNICluster(endpoint)
+			tc.setup(instance)
+			instance.Status.Notifications = []kniv1beta1.NotificationStatus{
+				{Name: "audit", Pending: []kniv1beta1.PendingNotification{pendingEvent("queued-before", 0)}},
+			}
+			clusterOperator := &osconfigv1.ClusterOperator{ObjectMeta: metav1.ObjectMeta{Name: ClusterOperatorName}}
+			r, _ := newTestReconciler(t, instance, secret, newOwnedCatalogSource(instance, "4.0.0"), testClusterVersion("4.1.0"), clusterOperator)
+
+			reconciled, _, err := reconcileTestKey(t, r)
+			if err != nil {
+				t.Fatalf("reconcile: %v", err)
+			}
+			pending := reconciled.Status.Notifications[0].Pending
+			if len(pending) != 1 || pending[0].ID != "queued-before" {
+				t.Errorf("pending events are %v, want only the one queued before", pending)
+			}
+
+			status := deliverTestKey(t, r)
+			if received := endpoint.received(); len(received) != 0 {
+				t.Errorf("endpoint received %v", received)
+			}
+			if len(status.Pending) != 1 {
+				t.Errorf("pending events are %v after delivery, want them kept", status.Pending)
+			}
+		})
+	}
+}
+
+func TestNotifierDelivers(t *testing.T) {
+	endpoint := newTestEndpoint(t)
+	instance, secret := newNotifyTestKNICluster(endpoint)
+	event := pendingEvent("UpgradeCompleted/4.1.0/1", 0)
+	instance.Status.Notifications = []kniv1beta1.NotificationStatus{
+		{Name: "audit", Pending: []kniv1beta1.PendingNotification{event}},
+	}
+	r, _ := newTestReconciler(t, instance, secret)
+
+	status := deliverTestKey(t, r)
+
+	received := endpoint.received()
+	if len(received) != 1 || received[0].ID != event.ID || received[0].Cluster.Name != testKey.Name {
+		t.Errorf("endpoint received %v, want %s", received, event.ID)
+	}
+	if len(status.Pending) != 0 {
+		t.Errorf("pending events are %v after delivery", status.Pending)
+	}
+	if !notificationDelivered(status, event.ID) {
+		t.Errorf("delivered events are %v, want %s", status.Delivered, event.ID)
+	}
+
+	// the same event seen again is not queued, or sent, twice
+	instance.Status.Notifications = []kniv1beta1.NotificationStatus{status}
+	queueEvents(instance, []kniv1beta1.PendingNotification{event})
+	if pending := instance.Status.Notifications[0].Pending; len(pending) != 0 {
+		t.Errorf("delivered event was queued again: %v", pending)
+	}
+	deliverTestKey(t, r)
+	if received := endpoint.received(); len(received) != 1 {
+		t.Errorf("endpoint received %d events, want 1", len(received))
+	}
+}
+
+func TestNotifierRetries(t *testing.T) {
+	endpoint := newTestEndpoint(t)
+	// client errors are not retried within a delivery
+	endpoint.setStatus(http.StatusBadRequest)
+	instance, secret := newNotifyTestKNICluster(endpoint)
+	instance.Status.Notifications = []kniv1beta1.NotificationStatus{{
+		Name: "audit",
+		Pending: []kniv1beta1.PendingNotification{
+			pendingEvent("retried", 0),
+			pendingEvent("given-up", maxNotificationAttempts-1),
+		},
+	}}
+	r, _ := newTestReconciler(t, instance, secret)
+
+	status := deliverTestKey(t, r)
+	if len(status.Pending) != 1 || status.Pending[0].ID != "retried" || status.Pending[0].Attempts != 1 {
+		t.Fatalf("pending events are %v, want retried with one attempt", status.Pending)
+	}
+	if status.LastError == "" {
+		t.Error("the failure is not recorded")
+	}
+	if len(status.Delivered) != 0 {
+		t.Errorf("delivered events are %v", status.Delivered)
+	}
+
+	endpoint.setStatus(http.StatusOK)
+	status = deliverTestKey(t, r)
+	if len(status.Pending) != 0 || !notificationDelivered(status, "retried") {
+		t.Errorf("notification status is %v, want the retried event delivered", status)
+	}
+	if notificationDelivered(status, "given-up") {
+		t.Error("dropped event was delivered")
+	}
+}
+
+func TestApplyDelivery(t *testing.T) {
+	status := kniv1beta1.NotificationStatus{
+		Name: "audit",
+		Pending: []kniv1beta1.PendingNotification{
+			pendingEvent("sent", 0),
+			pendingEvent("queued-meanwhile", 0),
+		},
+	}
+	for i := 0; i < maxDeliveredNotifications; i++ {
+		status.Delivered = append(status.Delivered, fmt.Sprintf("old-%d", i))
+	}
+
+	applyDelivery(&status, &deliveryResult{
+		delivered: map[string]bool{"sent": true},
+		failed:    map[string]bool{},
+		dropped:   map[string]bool{},
+	})
+
+	if len(status.Pending) != 1 || status.Pending[0].ID != "queued-meanwhile" || status.Pending[0].Attempts != 0 {
+		t.Errorf("pending events are %v, want the one queued meanwhile", status.Pending)
+	}
+	if len(status.Delivered) != maxDeliveredNotifications || status.Delivered[len(status.Delivered)-1] != "sent" || notificationDelivered(status, "old-0") {
+		t.Errorf("delivered events are %v, want the latest %d", status.Delivered, maxDeliveredNotifications)
+	}
+}
@@ -0,0 +1,85 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+
+	objectreferencesv1 "github.com/djzager/custom-resource-status/objectreferences/v1"
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	forkedreflect "k8s.io/apimachinery/third_party/forked/golang/reflect"
+	"k8s.io/client-go/tools/reference"
+)
+
+// ensureOperands ensures the operand objects of every operator exist. Operand
+// kinds are usually defined by the operators themselves, so creating them
+// fails until OLM has installed the corresponding operator.
+func (r *ReconcileKNICluster) ensureOperands(instance *kniv1beta1.KNICluster, reqLogger logr.Logger) error {
+	mirrors, err := r.imageMirrors(instance)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, operator := range instance.Spec.Operators {
+		for i, raw := range operator.Operands {
+			operand, err := render.Operand(render.OperatorNamespace(instance, operator), raw)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("operator %s operand %d: %v", operator.Name, i, err))
+				continue
+			}
+			images := render.MirrorOperand(mirrors, operand)
+			instance.Status.Images = append(instance.Status.Images, images...)
+			err = r.ensureOperand(instance, operand, reqLogger)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("operator %s operand %s: %v", operator.Name, operand.GetName(), err))
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (r *ReconcileKNICluster) ensureOperand(instance *kniv1beta1.KNICluster, operand *unstructured.Unstructured, reqLogger logr.Logger) error {
+	if err := r.setOwner(instance, operand); err != nil {
+		return err
+	}
+
+	// Check if this operand already exists
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(operand.GroupVersionKind())
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: operand.GetName(), Namespace: operand.GetNamespace()}, found)
+	if err != nil && errors.IsNotFound(err) {
+		reqLogger.Info("Creating a new operand", "Operand.Kind", operand.GetKind(), "Operand.Namespace", operand.GetNamespace(), "Operand.Name", operand.GetName())
+		return r.client.Create(context.TODO(), operand)
+	} else if err != nil {
+		return err
+	}
+
+	// already exists - don't requeue
+	reqLogger.Info("Operand already exists", "Operand.Kind", found.GetKind(), "Operand.Namespace", found.GetNamespace(), "Operand.Name", found.GetName())
+
+	// repair drift from the desired spec, ignoring fields it leaves unset,
+	// which the API server or the operator may have defaulted
+	if spec, ok := operand.Object["spec"]; ok && !(forkedreflect.Equalities{}).DeepDerivative(spec, found.Object["spec"]) {
+		reqLogger.Info("Updating the operand spec", "Operand.Kind", found.GetKind(), "Operand.Namespace", found.GetNamespace(), "Operand.Name", found.GetName())
+		found.Object["spec"] = spec
+		err = r.client.Update(context.TODO(), found)
+		if err != nil {
+			return err
+		}
+	}
+
+	objectRef, err := reference.GetReference(r.scheme, found)
+	if err != nil {
+		return err
+	}
+	// Add it to the list of RelatedObjects if found
+	objectreferencesv1.SetObjectReference(&instance.Status.RelatedObjects, *objectRef)
+
+	return nil
+}
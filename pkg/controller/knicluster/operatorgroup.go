@@ -0,0 +1,232 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	objectreferencesv1 "github.com/djzager/custom-resource-status/objectreferences/v1"
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	olmv1 "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/reference"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReasonUnsupportedInstallMode is the condition reason when the CSV of an
+// operator does not support the namespaces its OperatorGroup targets
+const ReasonUnsupportedInstallMode = "UnsupportedInstallMode"
+
+// ensureOperatorGroups ensures an OperatorGroup exists in each namespace that
+// operators are installed in, and deletes the OperatorGroups of namespaces
+// that no longer have any. The CSVs of the operators are checked against the
+// namespaces their OperatorGroup targets.
+func (r *ReconcileKNICluster) ensureOperatorGroups(instance *kniv1beta1.KNICluster, reqLogger logr.Logger) error {
+	var errs []error
+	var incompatible []string
+	var statuses []kniv1beta1.OperatorGroupStatus
+	desired := map[string]bool{}
+	for _, namespace := range render.OperatorNamespaces(instance) {
+		desired[namespace] = true
+		operatorGroup, err := r.ensureOperatorGroup(instance, namespace, reqLogger)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %v", namespace, err))
+			continue
+		}
+		status, err := r.checkInstallModes(instance, operatorGroup)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %v", namespace, err))
+			continue
+		}
+		statuses = append(statuses, status)
+		for _, operator := range status.Incompatible {
+			incompatible = append(incompatible, fmt.Sprintf("operator %s: %s", operator.Operator, operator.Message))
+		}
+	}
+	instance.Status.OperatorGroups = statuses
+
+	operatorGroups, err := r.ownedOperatorGroups(instance)
+	if err != nil {
+		return utilerrors.NewAggregate(append(errs, err))
+	}
+	for _, operatorGroup := range operatorGroups {
+		if desired[operatorGroup.Namespace] {
+			continue
+		}
+		reqLogger.Info("Deleting OperatorGroup of a namespace without operators", "OperatorGroup.Namespace", operatorGroup.Namespace, "OperatorGroup.Name", operatorGroup.Name)
+		err = r.client.Delete(context.TODO(), operatorGroup)
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("namespace %s: %v", operatorGroup.Namespace, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+	if len(incompatible) > 0 {
+		return &reasonError{reason: ReasonUnsupportedInstallMode, err: fmt.Errorf("%s", strings.Join(incompatible, "; "))}
+	}
+	return nil
+}
+
+// ensureOperatorGroup ensures the OperatorGroup in namespace exists and
+// targets the configured namespaces, and returns it
+func (r *ReconcileKNICluster) ensureOperatorGroup(instance *kniv1beta1.KNICluster, namespace string, reqLogger logr.Logger) (*olmv1.OperatorGroup, error) {
+	// ensure OperatorGroup exists
+	operatorGroup := render.OperatorGroup(namespace, render.OperatorGroupConfig(instance, namespace))
+	if err := r.setOwner(instance, operatorGroup); err != nil {
+		return nil, err
+	}
+
+	// Check if this OperatorGroup already exists
+	found := &olmv1.OperatorGroup{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: operatorGroup.Name, Namespace: operatorGroup.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		reqLogger.Info("Creating a new OperatorGroup", "OperatorGroup.Namespace", operatorGroup.Namespace, "OperatorGroup.Name", operatorGroup.Name)
+		err = r.client.Create(context.TODO(), operatorGroup)
+		if err != nil {
+			return nil, err
+		}
+		return operatorGroup, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// already exists - don't requeue
+	reqLogger.Info("OperatorGroup already exists", "OperatorGroup.Namespace", found.Namespace, "OperatorGroup.Name", found.Name)
+
+	managed, err := r.claim(instance, found, olmv1.OperatorGroupKind, adoptionPolicies(instance).OperatorGroups, "", reqLogger)
+	if err != nil {
+		return nil, err
+	}
+	if !managed {
+		// the install modes are still checked against its targets
+		return found, r.addRelatedObject(instance, found)
+	}
+
+	// repair drift from the desired targets
+	if !reflect.DeepEqual(found.Spec.TargetNamespaces, operatorGroup.Spec.TargetNamespaces) || !reflect.DeepEqual(found.Spec.Selector, operatorGroup.Spec.Selector) {
+		reqLogger.Info("Updating the OperatorGroup targets", "OperatorGroup.Namespace", found.Namespace, "OperatorGroup.Name", found.Name)
+		found.Spec.TargetNamespaces = operatorGroup.Spec.TargetNamespaces
+		found.Spec.Selector = operatorGroup.Spec.Selector
+		err = r.client.Update(context.TODO(), found)
+		if err != nil {
+			return nil, err
+		}
+		// OLM resolves the new targets
+		found.Status.Namespaces = nil
+	}
+
+	objectRef, err := reference.GetReference(r.scheme, found)
+	if err != nil {
+		return nil, err
+	}
+	// Add it to the list of RelatedObjects if found
+	objectreferencesv1.SetObjectReference(&instance.Status.RelatedObjects, *objectRef)
+
+	return found, nil
+}
+
+// checkInstallModes reports the operators in the namespace of operatorGroup
+// whose CSV does not support the namespaces it targets. OLM refuses to
+// install those CSVs.
+func (r *ReconcileKNICluster) checkInstallModes(instance *kniv1beta1.KNICluster, operatorGroup *olmv1.OperatorGroup) (kniv1beta1.OperatorGroupStatus, error) {
+	targets := targetNamespaces(operatorGroup)
+	status := kniv1beta1.OperatorGroupStatus{
+		Namespace:        operatorGroup.Namespace,
+		TargetNamespaces: targets,
+	}
+	if len(targets) == 0 {
+		// a selector that OLM has not resolved yet
+		return status, nil
+	}
+
+	for _, operator := range instance.Spec.Operators {
+		if render.OperatorNamespace(instance, operator) != operatorGroup.Namespace {
+			continue
+		}
+		subscription := &olm.Subscription{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: operatorGroup.Namespace, Name: operator.Name}, subscription)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return status, err
+		}
+		if subscription.Status.CurrentCSV == "" {
+			continue
+		}
+
+		csv := &olm.ClusterServiceVersion{}
+		err = r.apiReader.Get(context.TODO(), types.NamespacedName{Namespace: operatorGroup.Namespace, Name: subscription.Status.CurrentCSV}, csv)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return status, err
+		}
+		if len(csv.Spec.InstallModes) == 0 {
+			continue
+		}
+
+		modes, err := olm.NewInstallModeSet(csv.Spec.InstallModes)
+		if err == nil {
+			err = modes.Supports(operatorGroup.Namespace, targets)
+		}
+		if err != nil {
+			status.Incompatible = append(status.Incompatible, kniv1beta1.InstallModeIncompatibility{
+				Operator: operator.Name,
+				CSV:      csv.Name,
+				Message:  err.Error(),
+			})
+		}
+	}
+	return status, nil
+}
+
+// targetNamespaces returns the namespaces operatorGroup targets, as resolved
+// by OLM, or as requested by its spec until OLM has resolved them. It returns
+// nil for a selector that has not been resolved yet. All namespaces are
+// represented by an empty string.
+func targetNamespaces(operatorGroup *olmv1.OperatorGroup) []string {
+	switch {
+	case len(operatorGroup.Status.Namespaces) > 0:
+		return operatorGroup.Status.Namespaces
+	case len(operatorGroup.Spec.TargetNamespaces) > 0:
+		return operatorGroup.Spec.TargetNamespaces
+	case operatorGroup.Spec.Selector != nil:
+		return nil
+	}
+	return []string{metav1.NamespaceAll}
+}
+
+// ownedOperatorGroups returns the OperatorGroups owned by instance. Those in
+// its own namespace have an owner reference, and the others the owner labels.
+func (r *ReconcileKNICluster) ownedOperatorGroups(instance *kniv1beta1.KNICluster) ([]*olmv1.OperatorGroup, error) {
+	var owned []*olmv1.OperatorGroup
+	local := &olmv1.OperatorGroupList{}
+	if err := r.client.List(context.TODO(), client.InNamespace(instance.Namespace), local); err != nil {
+		return nil, err
+	}
+	for i := range local.Items {
+		if metav1.IsControlledBy(&local.Items[i], instance) {
+			owned = append(owned, &local.Items[i])
+		}
+	}
+
+	labelled := &olmv1.OperatorGroupList{}
+	if err := r.client.List(context.TODO(), client.MatchingLabels(render.OwnerLabels(instance)), labelled); err != nil {
+		return nil, err
+	}
+	for i := range labelled.Items {
+		if labelled.Items[i].Namespace != instance.Namespace {
+			owned = append(owned, &labelled.Items[i])
+		}
+	}
+	return owned, nil
+}
@@ -0,0 +1,165 @@
+package knicluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	olmv1 "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+var operatorGroupKey = types.NamespacedName{Namespace: testKey.Namespace, Name: render.OperatorGroupName}
+
+func getOperatorGroup(t *testing.T, c *fakeClient, key types.NamespacedName) *olmv1.OperatorGroup {
+	t.Helper()
+	operatorGroup := &olmv1.OperatorGroup{}
+	if err := c.Get(context.TODO(), key, operatorGroup); err != nil {
+		t.Fatalf("getting OperatorGroup %s: %v", key, err)
+	}
+	return operatorGroup
+}
+
+// installedOperatorWithModes returns the objects of the test operator once
+// OLM has installed a CSV supporting only modes
+func installedOperatorWithModes(t *testing.T, instance *kniv1beta1.KNICluster, modes ...olm.InstallModeType) []runtime.Object {
+	objs := installedOperator(t, instance, "etcdoperator.v0.9.4")
+	csv := objs[1].(*olm.ClusterServiceVersion)
+	for _, mode := range []olm.InstallModeType{olm.InstallModeTypeOwnNamespace, olm.InstallModeTypeSingleNamespace, olm.InstallModeTypeMultiNamespace, olm.InstallModeTypeAllNamespaces} {
+		supported := false
+		for _, m := range modes {
+			supported = supported || m == mode
+		}
+		csv.Spec.InstallModes = append(csv.Spec.InstallModes, olm.InstallMode{Type: mode, Supported: supported})
+	}
+	return objs
+}
+
+func TestEnsureOperatorGroupsTargets(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"kni.openshift.com/operators": "true"}}
+	for name, tc := range map[string]struct {
+		config       *kniv1beta1.OperatorGroupSpec
+		wantTargets  []string
+		wantSelector *metav1.LabelSelector
+		wantStatus   []string
+	}{
+		"own namespace": {
+			wantTargets: []string{"kniops"},
+			wantStatus:  []string{"kniops"},
+		},
+		"all namespaces": {
+			config:     &kniv1beta1.OperatorGroupSpec{Namespace: "kniops", AllNamespaces: true},
+			wantStatus: []string{metav1.NamespaceAll},
+		},
+		"target namespaces": {
+			config:      &kniv1beta1.OperatorGroupSpec{Namespace: "kniops", TargetNamespaces: []string{"kniops", "apps"}},
+			wantTargets: []string{"kniops", "apps"},
+			wantStatus:  []string{"kniops", "apps"},
+		},
+		// OLM resolves the selector
+		"selector": {
+			config:       &kniv1beta1.OperatorGroupSpec{Namespace: "kniops", Selector: selector},
+			wantSelector: selector,
+		},
+	} {
+		instance := newTestKNICluster()
+		if tc.config != nil {
+			instance.Spec.OperatorGroups = []kniv1beta1.OperatorGroupSpec{*tc.config}
+		}
+		r, c := newTestReconciler(t, instance)
+
+		if err := r.ensureOperatorGroups(instance, log); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		operatorGroup := getOperatorGroup(t, c, operatorGroupKey)
+		if !reflect.DeepEqual(operatorGroup.Spec.TargetNamespaces, tc.wantTargets) || !reflect.DeepEqual(operatorGroup.Spec.Selector, tc.wantSelector) {
+			t.Errorf("%s: OperatorGroup targets %v with selector %v, want %v with %v", name, operatorGroup.Spec.TargetNamespaces, operatorGroup.Spec.Selector, tc.wantTargets, tc.wantSelector)
+		}
+		statuses := instance.Status.OperatorGroups
+		if len(statuses) != 1 || !reflect.DeepEqual(statuses[0].TargetNamespaces, tc.wantStatus) {
+			t.Errorf("%s: OperatorGroup status is %v, want targets %q", name, statuses, tc.wantStatus)
+		}
+	}
+}
+
+func TestEnsureOperatorGroupsRepairsTargets(t *testing.T) {
+	instance := newTestKNICluster()
+	instance.Spec.OperatorGroups = []kniv1beta1.OperatorGroupSpec{{Namespace: "kniops", AllNamespaces: true}}
+	drifted := render.OperatorGroup("kniops", nil)
+	if err := controllerutil.SetControllerReference(instance, drifted, newTestScheme(t)); err != nil {
+		t.Fatal(err)
+	}
+	drifted.Status.Namespaces = []string{"kniops"}
+	r, c := newTestReconciler(t, instance, drifted)
+
+	if err := r.ensureOperatorGroups(instance, log); err != nil {
+		t.Fatal(err)
+	}
+
+	operatorGroup := getOperatorGroup(t, c, operatorGroupKey)
+	if len(operatorGroup.Spec.TargetNamespaces) != 0 || operatorGroup.Spec.Selector != nil {
+		t.Errorf("OperatorGroup targets %v with selector %v, want all namespaces", operatorGroup.Spec.TargetNamespaces, operatorGroup.Spec.Selector)
+	}
+	// the namespaces OLM resolved for the old targets no longer apply
+	if statuses := instance.Status.OperatorGroups; len(statuses) != 1 || !reflect.DeepEqual(statuses[0].TargetNamespaces, []string{metav1.NamespaceAll}) {
+		t.Errorf("OperatorGroup status is %v, want all namespaces", statuses)
+	}
+}
+
+func TestEnsureOperatorGroupsUnsupportedInstallMode(t *testing.T) {
+	instance := newTestKNICluster()
+	instance.Spec.OperatorGroups = []kniv1beta1.OperatorGroupSpec{{Namespace: "kniops", AllNamespaces: true}}
+	r, _ := newTestReconciler(t, append([]runtime.Object{instance}, installedOperatorWithModes(t, instance, olm.InstallModeTypeOwnNamespace)...)...)
+
+	err := r.ensureOperatorGroups(instance, log)
+
+	reason, ok := err.(*reasonError)
+	if !ok || reason.reason != ReasonUnsupportedInstallMode {
+		t.Errorf("ensureOperatorGroups returned %v, want reason %s", err, ReasonUnsupportedInstallMode)
+	}
+	statuses := instance.Status.OperatorGroups
+	if len(statuses) != 1 || len(statuses[0].Incompatible) != 1 {
+		t.Fatalf("OperatorGroup status is %v, want one incompatible operator", statuses)
+	}
+	if incompatible := statuses[0].Incompatible[0]; incompatible.Operator != "kni" || incompatible.CSV != "etcdoperator.v0.9.4" {
+		t.Errorf("incompatible operator is %v, want kni with its CSV", incompatible)
+	}
+}
+
+func TestEnsureOperatorGroupsSupportedInstallMode(t *testing.T) {
+	instance := newTestKNICluster()
+	r, _ := newTestReconciler(t, append([]runtime.Object{instance}, installedOperatorWithModes(t, instance, olm.InstallModeTypeOwnNamespace)...)...)
+
+	if err := r.ensureOperatorGroups(instance, log); err != nil {
+		t.Fatal(err)
+	}
+	if statuses := instance.Status.OperatorGroups; len(statuses) != 1 || len(statuses[0].Incompatible) != 0 {
+		t.Errorf("OperatorGroup status is %v, want no incompatible operator", statuses)
+	}
+}
+
+func TestEnsureOperatorGroupsDeletesUnused(t *testing.T) {
+	instance := newTestKNICluster()
+	unused := render.OperatorGroup("old-operators", nil)
+	unused.Labels = render.OwnerLabels(instance)
+	unmanaged := render.OperatorGroup("other", nil)
+	r, c := newTestReconciler(t, instance, unused, unmanaged)
+
+	if err := r.ensureOperatorGroups(instance, log); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "old-operators", Name: render.OperatorGroupName}, &olmv1.OperatorGroup{}); !errors.IsNotFound(err) {
+		t.Errorf("getting the unused OperatorGroup returned %v, want it deleted", err)
+	}
+	getOperatorGroup(t, c, types.NamespacedName{Namespace: "other", Name: render.OperatorGroupName})
+	getOperatorGroup(t, c, operatorGroupKey)
+}
@@ -0,0 +1,127 @@
+package knicluster
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// setOwner makes instance the owner of obj. Owner references cannot cross
+// namespaces, so objects in other namespaces get the owner labels instead.
+// They are not garbage collected, and get deleted by the finalizer.
+func (r *ReconcileKNICluster) setOwner(instance *kniv1beta1.KNICluster, obj metav1.Object) error {
+	if obj.GetNamespace() == instance.Namespace {
+		return controllerutil.SetControllerReference(instance, obj, r.scheme)
+	}
+	render.SetOwnerLabels(instance, obj)
+	return nil
+}
+
+// isOwner returns whether instance owns obj, through either an owner
+// reference or the owner labels
+func isOwner(instance *kniv1beta1.KNICluster, obj metav1.Object) bool {
+	if metav1.IsControlledBy(obj, instance) {
+		return true
+	}
+	labels := obj.GetLabels()
+	return labels[kniv1beta1.OwnerNameLabel] == instance.Name && labels[kniv1beta1.OwnerNamespaceLabel] == instance.Namespace
+}
+
+// enqueueLabelledOwner enqueues the KNICluster named by the owner labels of an
+// object
+var enqueueLabelledOwner = &handler.EnqueueRequestsFromMapFunc{
+	ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+		labels := a.Meta.GetLabels()
+		name, namespace := labels[kniv1beta1.OwnerNameLabel], labels[kniv1beta1.OwnerNamespaceLabel]
+		if name == "" || namespace == "" {
+			return nil
+		}
+		return []reconcile.Request{
+			{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}},
+		}
+	}),
+}
+
+// ensureLabelledObjectsDeleted deletes the objects that instance owns through
+// the owner labels, since they are not garbage collected along with it, and
+// applies the deletion policy to the namespaces it created
+func (r *ReconcileKNICluster) ensureLabelledObjectsDeleted(instance *kniv1beta1.KNICluster, reqLogger logr.Logger) error {
+	var errs []error
+	for _, operator := range instance.Spec.Operators {
+		for _, raw := range operator.Operands {
+			operand, err := render.Operand(render.OperatorNamespace(instance, operator), raw)
+			if err != nil || operand.GetNamespace() == instance.Namespace {
+				continue
+			}
+			err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: operand.GetNamespace(), Name: operand.GetName()}, operand)
+			if err != nil || !isOwner(instance, operand) {
+				continue
+			}
+			reqLogger.Info("Deleting operand", "Operand.Kind", operand.GetKind(), "Operand.Namespace", operand.GetNamespace(), "Operand.Name", operand.GetName())
+			if err := r.client.Delete(context.TODO(), operand); err != nil && !errors.IsNotFound(err) {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	subscriptions, err := r.ownedSubscriptions(instance)
+	if err != nil {
+		return err
+	}
+	for _, subscription := range subscriptions {
+		if subscription.Namespace == instance.Namespace {
+			continue
+		}
+		reqLogger.Info("Deleting Subscription", "Subscription.Namespace", subscription.Namespace, "Subscription.Name", subscription.Name)
+		if err := r.client.Delete(context.TODO(), subscription); err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+
+	// temporary catalogs are normally deleted once queried, and with the
+	// catalog, but the catalog may belong to someone else
+	catalogSources := &olm.CatalogSourceList{}
+	if err := r.client.List(context.TODO(), client.MatchingLabels(render.OwnerLabels(instance)), catalogSources); err != nil {
+		return err
+	}
+	for i := range catalogSources.Items {
+		catalogSource := &catalogSources.Items[i]
+		if catalogSource.Labels[kniv1beta1.TemporaryCatalogLabel] == "" {
+			continue
+		}
+		reqLogger.Info("Deleting temporary CatalogSource", "CatalogSource.Namespace", catalogSource.Namespace, "CatalogSource.Name", catalogSource.Name)
+		if err := r.client.Delete(context.TODO(), catalogSource); err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+
+	operatorGroups, err := r.ownedOperatorGroups(instance)
+	if err != nil {
+		return err
+	}
+	for _, operatorGroup := range operatorGroups {
+		if operatorGroup.Namespace == instance.Namespace {
+			continue
+		}
+		reqLogger.Info("Deleting OperatorGroup", "OperatorGroup.Namespace", operatorGroup.Namespace, "OperatorGroup.Name", operatorGroup.Name)
+		if err := r.client.Delete(context.TODO(), operatorGroup); err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := r.releaseNamespaces(instance, nil, reqLogger); err != nil {
+		errs = append(errs, err)
+	}
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,108 @@
+package knicluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// maxDiffValueLength limits how much of each value is shown in a diff, so
+// that large fields such as embedded objects don't bloat the status
+const maxDiffValueLength = 100
+
+// planActions maps recorded verbs to the actions shown in a plan
+var planActions = map[string]string{
+	"create": kniv1beta1.PlanActionCreate,
+	"update": kniv1beta1.PlanActionUpdate,
+	"delete": kniv1beta1.PlanActionDelete,
+}
+
+// newPlan returns the plan for making the changes in actions to the
+// generation of instance
+func newPlan(instance *kniv1beta1.KNICluster, actions []action) *kniv1beta1.PlanStatus {
+	plan := &kniv1beta1.PlanStatus{Generation: instance.Generation}
+	for _, a := range actions {
+		plan.Changes = append(plan.Changes, kniv1beta1.PlannedChange{
+			Action:    planActions[a.verb],
+			Kind:      a.kind,
+			Namespace: a.namespace,
+			Name:      a.name,
+			Diff:      a.diff,
+			DependsOn: a.dependsOn,
+		})
+	}
+	return plan
+}
+
+// diffObjects lists the fields outside of metadata and status that differ
+// between live and desired, as "path: live -> desired"
+func diffObjects(live, desired runtime.Object) ([]string, error) {
+	liveMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(live)
+	if err != nil {
+		return nil, err
+	}
+	desiredMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(desired)
+	if err != nil {
+		return nil, err
+	}
+	for _, ignored := range []string{"apiVersion", "kind", "metadata", "status"} {
+		delete(liveMap, ignored)
+		delete(desiredMap, ignored)
+	}
+
+	var diff []string
+	diffValues("", liveMap, desiredMap, &diff)
+	return diff, nil
+}
+
+func diffValues(path string, live, desired interface{}, diff *[]string) {
+	liveMap, liveIsMap := live.(map[string]interface{})
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+	if liveIsMap && desiredIsMap {
+		keys := map[string]bool{}
+		for key := range liveMap {
+			keys[key] = true
+		}
+		for key := range desiredMap {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+
+		for _, key := range sorted {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			diffValues(childPath, liveMap[key], desiredMap[key], diff)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(live, desired) {
+		*diff = append(*diff, fmt.Sprintf("%s: %s -> %s", path, formatDiffValue(live), formatDiffValue(desired)))
+	}
+}
+
+func formatDiffValue(value interface{}) string {
+	if value == nil {
+		return "<unset>"
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	s := strings.TrimSpace(string(encoded))
+	if len(s) > maxDiffValueLength {
+		s = s[:maxDiffValueLength] + "..."
+	}
+	return s
+}
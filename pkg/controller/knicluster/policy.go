@@ -0,0 +1,308 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/policy"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// ReasonPolicyDenied is the condition reason when the policy endpoint
+	// denied a change
+	ReasonPolicyDenied = "PolicyDenied"
+	// ReasonPolicyDeferred is the condition reason while the policy endpoint
+	// defers a decision
+	ReasonPolicyDeferred = "PolicyDeferred"
+	// ReasonPolicyUnavailable is the condition reason when the policy
+	// endpoint could not be asked
+	ReasonPolicyUnavailable = "PolicyUnavailable"
+	// ReasonPolicyPending is the condition reason while the policy endpoint
+	// is being asked
+	ReasonPolicyPending = "PolicyPending"
+
+	// maxPolicyDecisions is the number of decisions kept in the status
+	maxPolicyDecisions = 20
+)
+
+// authorizeSwitch returns nil if the catalog may be switched from previous
+// to version: the switch must be approved, if approval is manual, and
+// allowed by the policy endpoint, if there is one
+func (r *ReconcileKNICluster) authorizeSwitch(instance *kniv1beta1.KNICluster, version, image, previous string, reqLogger logr.Logger) error {
+	if err := requireApproval(instance, version, image, previous, reqLogger); err != nil {
+		return err
+	}
+	return r.authorize(instance, policy.Request{
+		Action: kniv1beta1.PolicyActionCatalogSwitch,
+		CatalogSwitch: &policy.CatalogSwitch{
+			FromVersion:  previous,
+			ToVersion:    version,
+			CatalogImage: image,
+		},
+	}, version, reqLogger)
+}
+
+// ensureInstallPlans asks the policy endpoint about each InstallPlan of the
+// managed Subscriptions that waits for approval, and approves the allowed
+// ones
+func (r *ReconcileKNICluster) ensureInstallPlans(instance *kniv1beta1.KNICluster, reqLogger logr.Logger) error {
+	if instance.Spec.Policy == nil || !instance.Spec.Policy.InstallPlans {
+		return nil
+	}
+
+	var pending, failed error
+	for _, operator := range instance.Spec.Operators {
+		subscription := &olm.Subscription{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: render.OperatorNamespace(instance, operator), Name: operator.Name}, subscription)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		ref := subscription.Status.InstallPlanRef
+		if ref == nil {
+			continue
+		}
+
+		plan := &olm.InstallPlan{}
+		err = r.apiReader.Get(context.TODO(), types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, plan)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if plan.Spec.Approval != olm.ApprovalManual || plan.Spec.Approved || plan.Status.Phase != olm.InstallPlanPhaseRequiresApproval {
+			continue
+		}
+
+		err = r.authorize(instance, policy.Request{
+			Action: kniv1beta1.PolicyActionInstallPlanApproval,
+			InstallPlan: &policy.InstallPlan{
+				Namespace: plan.Namespace,
+				Name:      plan.Name,
+				Operator:  operator.Name,
+				CSVs:      plan.Spec.ClusterServiceVersionNames,
+			},
+		}, plan.Namespace+"/"+plan.Name, reqLogger)
+		if _, isPending := err.(*pendingError); isPending {
+			pending = err
+			continue
+		} else if err != nil {
+			failed = err
+			continue
+		}
+
+		reqLogger.Info("Approving InstallPlan", "InstallPlan.Namespace", plan.Namespace, "InstallPlan.Name", plan.Name)
+		plan.Spec.Approved = true
+		if err := r.client.Update(context.TODO(), plan); err != nil {
+			return err
+		}
+	}
+	if failed != nil {
+		return failed
+	}
+	return pending
+}
+
+// authorize asks the policy endpoint, if there is one, whether the change
+// described by req and identified by subject may be made. The endpoint is
+// asked in the background, and the change waits until a later reconcile
+// picks up the answer. Decisions to allow or deny are kept in the status
+// until their TTL has passed, so that a change is not asked about on every
+// reconcile. Deferred changes are asked about again. While paused or
+// planning the endpoint is not asked, and the planned change depends on its
+// decision instead.
+func (r *ReconcileKNICluster) authorize(instance *kniv1beta1.KNICluster, req policy.Request, subject string, reqLogger logr.Logger) error {
+	spec := instance.Spec.Policy
+	if spec == nil {
+		return nil
+	}
+	if decision := findPolicyDecision(instance, req.Action, subject); decision != nil && decision.Decision != kniv1beta1.PolicyDefer &&
+		time.Since(decision.Time.Time) < decisionTTL(spec) {
+		return decisionError(decision)
+	}
+	if recorder, dryRun := r.client.(*recordingClient); dryRun {
+		recorder.dependOn(fmt.Sprintf("the policy decision on %s %s", req.Action, subject))
+		return nil
+	}
+
+	key := decisionKey{
+		cluster: types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name},
+		action:  req.Action,
+		subject: subject,
+	}
+	if answer, ok := r.decider.answer(key); ok {
+		if answer.err != nil {
+			return &reasonError{reason: ReasonPolicyUnavailable, err: fmt.Errorf("asking the policy endpoint about %s %s: %v", req.Action, subject, answer.err)}
+		}
+		reqLogger.Info("Policy decision", "Action", req.Action, "Subject", subject, "Decision", answer.resp.Decision, "Reason", answer.resp.Reason)
+		decision := kniv1beta1.PolicyDecisionStatus{
+			Action:   req.Action,
+			Subject:  subject,
+			Decision: answer.resp.Decision,
+			Reason:   answer.resp.Reason,
+			Time:     metav1.Now(),
+		}
+		setPolicyDecision(instance, decision)
+		return decisionError(&decision)
+	}
+
+	client, err := r.policyClient(instance)
+	if err != nil {
+		return &reasonError{reason: ReasonPolicyUnavailable, err: err}
+	}
+	req.Cluster = policy.Cluster{Name: instance.Name, Namespace: instance.Namespace}
+	r.decider.ask(key, client, req)
+	return &pendingError{reason: ReasonPolicyPending, err: fmt.Errorf("waiting for the policy endpoint to decide on %s %s", req.Action, subject)}
+}
+
+// policyClient returns a client for the policy endpoint of instance
+func (r *ReconcileKNICluster) policyClient(instance *kniv1beta1.KNICluster) (*policy.Client, error) {
+	spec := instance.Spec.Policy
+	var timeout time.Duration
+	if spec.Timeout != nil {
+		timeout = spec.Timeout.Duration
+	}
+	client, err := policy.NewClient(spec.URL, spec.CABundle, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if spec.AuthSecret != "" {
+		secret := &corev1.Secret{}
+		err := r.apiReader.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: spec.AuthSecret}, secret)
+		if err != nil {
+			return nil, fmt.Errorf("reading the policy credentials: %v", err)
+		}
+		client.Token = string(secret.Data["token"])
+		client.Username = string(secret.Data["username"])
+		client.Password = string(secret.Data["password"])
+	}
+	return client, nil
+}
+
+// decisionTTL returns how long the decisions of the endpoint are kept
+func decisionTTL(spec *kniv1beta1.PolicySpec) time.Duration {
+	if spec.DecisionTTL == nil {
+		return policy.DecisionTTLDefault
+	}
+	return spec.DecisionTTL.Duration
+}
+
+// decisionError returns the error that reports decision, or nil if it
+// allows the change
+func decisionError(decision *kniv1beta1.PolicyDecisionStatus) error {
+	switch decision.Decision {
+	case kniv1beta1.PolicyAllow:
+		return nil
+	case kniv1beta1.PolicyDefer:
+		return &pendingError{reason: ReasonPolicyDeferred, err: fmt.Errorf("the policy endpoint deferred %s %s: %s",
+			decision.Action, decision.Subject, decision.Reason)}
+	}
+	return &reasonError{reason: ReasonPolicyDenied, err: fmt.Errorf("the policy endpoint denied %s %s: %s",
+		decision.Action, decision.Subject, decision.Reason)}
+}
+
+func findPolicyDecision(instance *kniv1beta1.KNICluster, action kniv1beta1.PolicyAction, subject string) *kniv1beta1.PolicyDecisionStatus {
+	for i := range instance.Status.PolicyDecisions {
+		decision := &instance.Status.PolicyDecisions[i]
+		if decision.Action == action && decision.Subject == subject {
+			return decision
+		}
+	}
+	return nil
+}
+
+// setPolicyDecision records decision, replacing an earlier decision about
+// the same change and dropping the oldest beyond maxPolicyDecisions
+func setPolicyDecision(instance *kniv1beta1.KNICluster, decision kniv1beta1.PolicyDecisionStatus) {
+	var decisions []kniv1beta1.PolicyDecisionStatus
+	for _, existing := range instance.Status.PolicyDecisions {
+		if existing.Action == decision.Action && existing.Subject == decision.Subject {
+			continue
+		}
+		decisions = append(decisions, existing)
+	}
+	decisions = append(decisions, decision)
+	if len(decisions) > maxPolicyDecisions {
+		decisions = decisions[len(decisions)-maxPolicyDecisions:]
+	}
+	instance.Status.PolicyDecisions = decisions
+}
+
+// decider asks the policy endpoint in the background, so that an endpoint
+// that is slow, or that fails and is retried, does not hold up
+// reconciliation. Each change is asked about by at most one goroutine at a
+// time, and its answer is kept until a reconcile of the KNICluster picks it
+// up.
+type decider struct {
+	mu sync.Mutex
+	// asking holds the changes being asked about
+	asking map[decisionKey]bool
+	// answers holds the answers that were not picked up yet
+	answers map[decisionKey]decisionAnswer
+	// running tracks the goroutines asking
+	running sync.WaitGroup
+}
+
+// decisionKey identifies a change of a KNICluster asked about
+type decisionKey struct {
+	cluster types.NamespacedName
+	action  kniv1beta1.PolicyAction
+	subject string
+}
+
+// decisionAnswer is the answer of the endpoint, or the error of asking it
+type decisionAnswer struct {
+	resp *policy.Response
+	err  error
+}
+
+func newDecider() *decider {
+	return &decider{asking: map[decisionKey]bool{}, answers: map[decisionKey]decisionAnswer{}}
+}
+
+// ask asks client about req in the background, unless the change key is
+// already being asked about
+func (d *decider) ask(key decisionKey, client *policy.Client, req policy.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.asking[key] {
+		return
+	}
+	d.asking[key] = true
+	d.running.Add(1)
+	go func() {
+		defer d.running.Done()
+		resp, err := client.Decide(context.TODO(), req)
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.asking, key)
+		d.answers[key] = decisionAnswer{resp: resp, err: err}
+	}()
+}
+
+// answer returns the answer about the change key, if it has come, and
+// forgets it
+func (d *decider) answer(key decisionKey) (decisionAnswer, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	answer, ok := d.answers[key]
+	delete(d.answers, key)
+	return answer, ok
+}
+
+// wait waits for the changes being asked about to be answered
+func (d *decider) wait() {
+	d.running.Wait()
+}
@@ -0,0 +1,243 @@
+package knicluster
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/policy"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// testPolicyEndpoint is a policy endpoint that records the requests made to
+// it, and answers each with a settable decision
+type testPolicyEndpoint struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	decision kniv1beta1.PolicyDecision
+	requests []policy.Request
+	// hold, if set, keeps each request waiting until it is closed
+	hold chan struct{}
+}
+
+func newTestPolicyEndpoint(t *testing.T, decision kniv1beta1.PolicyDecision) *testPolicyEndpoint {
+	endpoint := &testPolicyEndpoint{decision: decision}
+	endpoint.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		decided := policy.Request{}
+		if err := json.NewDecoder(req.Body).Decode(&decided); err != nil {
+			t.Errorf("decoding the policy request: %v", err)
+		}
+		endpoint.mu.Lock()
+		hold := endpoint.hold
+		endpoint.mu.Unlock()
+		if hold != nil {
+			<-hold
+		}
+
+		endpoint.mu.Lock()
+		defer endpoint.mu.Unlock()
+		endpoint.requests = append(endpoint.requests, decided)
+		json.NewEncoder(w).Encode(policy.Response{Decision: endpoint.decision, Reason: "test"})
+	}))
+	t.Cleanup(endpoint.Close)
+	return endpoint
+}
+
+func (e *testPolicyEndpoint) setDecision(decision kniv1beta1.PolicyDecision) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.decision = decision
+}
+
+func (e *testPolicyEndpoint) received() []policy.Request {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]policy.Request(nil), e.requests...)
+}
+
+// reconcileWithPolicy reconciles the test KNICluster until the policy
+// endpoint has answered the changes it asked about, and returns the last
+// result
+func reconcileWithPolicy(t *testing.T, r *ReconcileKNICluster) (*kniv1beta1.KNICluster, reconcile.Result, error) {
+	t.Helper()
+	if _, _, err := reconcileTestKey(t, r); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	r.decider.wait()
+	return reconcileTestKey(t, r)
+}
+
+// newPolicyTestReconciler returns a reconciler of a cluster moving to 4.1.0
+// whose catalog serves 4.0.0, with the policy endpoint at url
+func newPolicyTestReconciler(t *testing.T, url string, decisions ...kniv1beta1.PolicyDecisionStatus) (*ReconcileKNICluster, *fakeClient) {
+	instance := newTestKNICluster()
+	instance.Spec.Catalog.Image.SkipContentValidation = true
+	instance.Spec.Policy = &kniv1beta1.PolicySpec{URL: url, DecisionTTL: &metav1.Duration{Duration: time.Hour}}
+	instance.Status.PolicyDecisions = decisions
+	clusterOperator := &osconfigv1.ClusterOperator{ObjectMeta: metav1.ObjectMeta{Name: ClusterOperatorName}}
+	return newTestReconciler(t, instance, newOwnedCatalogSource(instance, "4.0.0"), testClusterVersion("4.1.0"), clusterOperator)
+}
+
+func TestPolicyAskedInBackground(t *testing.T) {
+	endpoint := newTestPolicyEndpoint(t, kniv1beta1.PolicyAllow)
+	endpoint.hold = make(chan struct{})
+	r, c := newPolicyTestReconciler(t, endpoint.URL)
+
+	// the reconcile does not wait for the endpoint
+	reconciled, result, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	catalog := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, kniv1beta1.ConditionCatalogReady)
+	if catalog == nil || catalog.Reason != ReasonPolicyPending {
+		t.Errorf("CatalogReady condition is %v, want reason %s", catalog, ReasonPolicyPending)
+	}
+	if result.RequeueAfter == 0 || result.RequeueAfter > pendingRequeueAfter {
+		t.Errorf("reconcile requeues after %s, want to poll for the decision", result.RequeueAfter)
+	}
+	if version := catalogSourceVersion(t, c); version != "4.0.0" {
+		t.Errorf("catalog switched to %s before the decision", version)
+	}
+
+	// reconciles meanwhile do not ask again
+	if _, _, err := reconcileTestKey(t, r); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	close(endpoint.hold)
+	r.decider.wait()
+	if requests := endpoint.received(); len(requests) != 1 {
+		t.Errorf("policy endpoint was asked %d times, want once", len(requests))
+	}
+
+	reconciled, _, err = reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if version := catalogSourceVersion(t, c); version != "4.1.0" {
+		t.Errorf("catalog serves %s once allowed, want 4.1.0", version)
+	}
+	decision := findPolicyDecision(reconciled, kniv1beta1.PolicyActionCatalogSwitch, "4.1.0")
+	if decision == nil || decision.Decision != kniv1beta1.PolicyAllow {
+		t.Errorf("policy decision is %v, want allow", decision)
+	}
+}
+
+func TestPolicyDecisionTTL(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		decision kniv1beta1.PolicyDecision
+		age      time.Duration
+		asked    bool
+		reason   string
+	}{
+		{
+			name:     "allowed",
+			decision: kniv1beta1.PolicyAllow,
+			age:      30 * time.Minute,
+		},
+		{
+			name:     "denied",
+			decision: kniv1beta1.PolicyDeny,
+			age:      30 * time.Minute,
+			reason:   ReasonPolicyDenied,
+		},
+		{
+			name:     "denied long ago",
+			decision: kniv1beta1.PolicyDeny,
+			age:      2 * time.Hour,
+			asked:    true,
+			reason:   ReasonPolicyPending,
+		},
+		{
+			name:     "deferred",
+			decision: kniv1beta1.PolicyDefer,
+			age:      time.Minute,
+			asked:    true,
+			reason:   ReasonPolicyPending,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := newTestPolicyEndpoint(t, kniv1beta1.PolicyAllow)
+			r, _ := newPolicyTestReconciler(t, endpoint.URL, kniv1beta1.PolicyDecisionStatus{
+				Action:   kniv1beta1.PolicyActionCatalogSwitch,
+				Subject:  "4.1.0",
+				Decision: tc.decision,
+				Time:     metav1.NewTime(time.Now().Add(-tc.age)),
+			})
+			instance := &kniv1beta1.KNICluster{}
+			if err := r.client.Get(context.TODO(), testKey, instance); err != nil {
+				t.Fatal(err)
+			}
+
+			err := r.authorizeSwitch(instance, "4.1.0", testUpdateImage("4.1.0"), "4.0.0", log)
+			r.decider.wait()
+
+			if reason := errorReason(err); tc.reason != "" && reason != tc.reason {
+				t.Errorf("authorizing returned %v, want reason %s", err, tc.reason)
+			} else if tc.reason == "" && err != nil {
+				t.Errorf("authorizing returned %v, want the switch allowed", err)
+			}
+			if asked := len(endpoint.received()) > 0; asked != tc.asked {
+				t.Errorf("policy endpoint asked is %t, want %t", asked, tc.asked)
+			}
+		})
+	}
+}
+
+func TestPolicyUnavailable(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "no such policy", http.StatusNotFound)
+	}))
+	defer endpoint.Close()
+	r, c := newPolicyTestReconciler(t, endpoint.URL)
+
+	reconciled, _, err := reconcileWithPolicy(t, r)
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("reconcile returned %v, want the endpoint failure", err)
+	}
+	catalog := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, kniv1beta1.ConditionCatalogReady)
+	if catalog == nil || catalog.Reason != ReasonPolicyUnavailable {
+		t.Errorf("CatalogReady condition is %v, want reason %s", catalog, ReasonPolicyUnavailable)
+	}
+	if version := catalogSourceVersion(t, c); version != "4.0.0" {
+		t.Errorf("catalog switched to %s without a decision", version)
+	}
+}
+
+func TestPlanShowsPolicyDependency(t *testing.T) {
+	endpoint := newTestPolicyEndpoint(t, kniv1beta1.PolicyAllow)
+	r, c := newPolicyTestReconciler(t, endpoint.URL)
+	updateTestKNICluster(t, c, func(instance *kniv1beta1.KNICluster) { instance.Spec.Mode = kniv1beta1.ModePlan })
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	r.decider.wait()
+
+	if requests := endpoint.received(); len(requests) != 0 {
+		t.Errorf("policy endpoint was asked %d times while planning", len(requests))
+	}
+	var change *kniv1beta1.PlannedChange
+	for i, planned := range reconciled.Status.Plan.Changes {
+		if planned.Kind == "CatalogSource" && planned.Name == "demo-catalog" {
+			change = &reconciled.Status.Plan.Changes[i]
+		}
+	}
+	if change == nil {
+		t.Fatalf("plan %v does not switch the CatalogSource", reconciled.Status.Plan.Changes)
+	}
+	if len(change.DependsOn) != 1 || !strings.Contains(change.DependsOn[0], "policy decision") {
+		t.Errorf("planned switch depends on %v, want the policy decision", change.DependsOn)
+	}
+}
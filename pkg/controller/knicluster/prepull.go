@@ -0,0 +1,211 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// prePullSuffix is appended to the KNICluster name to name the DaemonSet
+	// that pre-pulls catalog images
+	prePullSuffix = "-catalog-prepull"
+
+	// prePullTimeoutDefault is how long pre-pulling may take when the spec
+	// does not say
+	prePullTimeoutDefault = 30 * time.Minute
+)
+
+// ensurePrePull pulls the catalog images of the updates available to the
+// cluster onto every selected node, if the spec asks for it. The images are
+// run by a DaemonSet, since catalog images serve their catalog when run, and a
+// node has pulled them once its pod is ready. The DaemonSet is deleted once
+// every node has, or once the timeout passes, since a node that cannot run
+// the pod would otherwise keep it forever.
+func (r *ReconcileKNICluster) ensurePrePull(instance *kniv1beta1.KNICluster, reqLogger logr.Logger) error {
+	images := prePullImages(instance)
+	status := instance.Status.PrePull
+	if !instance.Spec.Catalog.Image.PrePull || len(images) == 0 {
+		instance.Status.PrePull = nil
+		if status != nil && status.State == kniv1beta1.PrePullPulling {
+			return r.ensurePrePullDeleted(instance)
+		}
+		return nil
+	}
+	started := status != nil && reflect.DeepEqual(status.Images, images)
+	if started && status.State != kniv1beta1.PrePullPulling {
+		return nil
+	}
+
+	daemonSet := prePullDaemonSet(instance, images)
+	if err := controllerutil.SetControllerReference(instance, daemonSet, r.scheme); err != nil {
+		return err
+	}
+	startTime := metav1.Now()
+	if started && status.StartTime != nil {
+		startTime = *status.StartTime
+	}
+	status = &kniv1beta1.PrePullStatus{
+		Images:    images,
+		State:     kniv1beta1.PrePullPulling,
+		DaemonSet: daemonSet.Name,
+		StartTime: &startTime,
+	}
+	instance.Status.PrePull = status
+
+	found := &appsv1.DaemonSet{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: daemonSet.Name, Namespace: daemonSet.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		reqLogger.Info("Creating a DaemonSet to pre-pull catalog images", "DaemonSet.Namespace", daemonSet.Namespace, "DaemonSet.Name", daemonSet.Name, "Images", images)
+		return r.client.Create(context.TODO(), daemonSet)
+	} else if err != nil {
+		return err
+	}
+
+	status.NodesDesired = found.Status.DesiredNumberScheduled
+	status.NodesReady = found.Status.NumberReady
+	timedOut := time.Since(startTime.Time) >= prePullTimeout(instance)
+
+	if !timedOut && (!reflect.DeepEqual(containerImages(found.Spec.Template.Spec.Containers), images) ||
+		!labels.Equals(found.Spec.Template.Spec.NodeSelector, daemonSet.Spec.Template.Spec.NodeSelector)) {
+		reqLogger.Info("Updating the DaemonSet that pre-pulls catalog images", "DaemonSet.Namespace", found.Namespace, "DaemonSet.Name", found.Name, "Images", images)
+		found.Spec.Template = daemonSet.Spec.Template
+		return r.client.Update(context.TODO(), found)
+	}
+
+	now := metav1.Now()
+	if found.Status.ObservedGeneration == found.Generation && status.NodesDesired > 0 &&
+		found.Status.UpdatedNumberScheduled == status.NodesDesired && status.NodesReady == status.NodesDesired {
+		reqLogger.Info("Pre-pulled catalog images", "Images", images, "Nodes", status.NodesReady)
+		status.State = kniv1beta1.PrePullCompleted
+	} else if timedOut {
+		reqLogger.Info("Timed out pre-pulling catalog images", "Images", images, "Nodes", status.NodesDesired, "NodesReady", status.NodesReady)
+		status.State = kniv1beta1.PrePullTimedOut
+	} else {
+		return nil
+	}
+	status.CompletionTime = &now
+	return r.ensurePrePullDeleted(instance)
+}
+
+// prePullTimeout returns how long pre-pulling may take for instance
+func prePullTimeout(instance *kniv1beta1.KNICluster) time.Duration {
+	if timeout := instance.Spec.Catalog.Image.PrePullTimeout; timeout != nil {
+		return timeout.Duration
+	}
+	return prePullTimeoutDefault
+}
+
+// prePullTimeoutIn returns when pre-pulling times out, or zero if nothing is
+// being pulled
+func prePullTimeoutIn(instance *kniv1beta1.KNICluster) time.Duration {
+	status := instance.Status.PrePull
+	if status == nil || status.State != kniv1beta1.PrePullPulling || status.StartTime == nil {
+		return 0
+	}
+	in := prePullTimeout(instance) - time.Since(status.StartTime.Time)
+	if in <= 0 {
+		return time.Second
+	}
+	return in
+}
+
+// prePullImages returns the sorted catalog images of the updates available
+// to the cluster
+func prePullImages(instance *kniv1beta1.KNICluster) []string {
+	var images []string
+	for _, preview := range instance.Status.UpgradePreview {
+		if preview.CatalogImage == "" || preview.State == kniv1beta1.UpgradePreviewCatalogMissing {
+			continue
+		}
+		if preview.CatalogImage == instance.Status.CatalogImage {
+			continue
+		}
+		images = append(images, preview.CatalogImage)
+	}
+	sort.Strings(images)
+	return images
+}
+
+// prePullDaemonSet returns a DaemonSet that runs images on every node the
+// spec selects
+func prePullDaemonSet(instance *kniv1beta1.KNICluster, images []string) *appsv1.DaemonSet {
+	name := instance.Name + prePullSuffix
+	appLabels := map[string]string{"app": name}
+
+	var containers []corev1.Container
+	for i, image := range images {
+		containers = append(containers, corev1.Container{
+			Name:  fmt.Sprintf("catalog-%d", i),
+			Image: image,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("10m"),
+					corev1.ResourceMemory: resource.MustParse("50Mi"),
+				},
+			},
+		})
+	}
+
+	return &appsv1.DaemonSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "DaemonSet",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: instance.Namespace,
+			Labels:    appLabels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: appLabels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: appLabels},
+				Spec: corev1.PodSpec{
+					Containers:   containers,
+					NodeSelector: instance.Spec.Catalog.Image.PrePullNodeSelector,
+					// pull onto masters and other nodes that only refuse new
+					// pods, but not onto nodes whose taints evict them
+					Tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}},
+				},
+			},
+		},
+	}
+}
+
+// containerImages returns the sorted images of containers
+func containerImages(containers []corev1.Container) []string {
+	var images []string
+	for _, container := range containers {
+		images = append(images, container.Image)
+	}
+	sort.Strings(images)
+	return images
+}
+
+func (r *ReconcileKNICluster) ensurePrePullDeleted(instance *kniv1beta1.KNICluster) error {
+	daemonSet := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      instance.Name + prePullSuffix,
+			Namespace: instance.Namespace,
+		},
+	}
+	err := r.client.Delete(context.TODO(), daemonSet)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package knicluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var prePullKey = types.NamespacedName{Namespace: testKey.Namespace, Name: testKey.Name + prePullSuffix}
+
+// newPrePullTestKNICluster returns the test KNICluster pre-pulling the catalog
+// image of 4.2.0, an available update
+func newPrePullTestKNICluster() *kniv1beta1.KNICluster {
+	instance := newTestKNICluster()
+	instance.Spec.Catalog.Image.PrePull = true
+	instance.Status.CatalogImage = testUpdateImage("4.1.0")
+	instance.Status.UpgradePreview = []kniv1beta1.UpgradePreview{{
+		Version:      "4.2.0",
+		CatalogImage: testUpdateImage("4.2.0"),
+		State:        kniv1beta1.UpgradePreviewReady,
+	}}
+	return instance
+}
+
+// setPrePullDaemonSetStatus sets the status of the pre-pull DaemonSet, as
+// the DaemonSet controller does once it has scheduled a pod on desired nodes
+func setPrePullDaemonSetStatus(t *testing.T, c *fakeClient, desired, ready int32) {
+	t.Helper()
+	daemonSet := &appsv1.DaemonSet{}
+	if err := c.Get(context.TODO(), prePullKey, daemonSet); err != nil {
+		t.Fatal(err)
+	}
+	daemonSet.Status = appsv1.DaemonSetStatus{
+		ObservedGeneration:     daemonSet.Generation,
+		DesiredNumberScheduled: desired,
+		UpdatedNumberScheduled: desired,
+		NumberReady:            ready,
+	}
+	if err := c.Update(context.TODO(), daemonSet); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrePullDaemonSet(t *testing.T) {
+	instance := newPrePullTestKNICluster()
+	instance.Spec.Catalog.Image.PrePullNodeSelector = map[string]string{"node-role.kubernetes.io/worker": ""}
+
+	daemonSet := prePullDaemonSet(instance, prePullImages(instance))
+
+	spec := daemonSet.Spec.Template.Spec
+	if len(spec.Containers) != 1 || spec.Containers[0].Image != testUpdateImage("4.2.0") {
+		t.Errorf("containers are %v, want one running the 4.2.0 catalog", spec.Containers)
+	}
+	if len(spec.NodeSelector) != 1 || spec.NodeSelector["node-role.kubernetes.io/worker"] != "" {
+		t.Errorf("node selector is %v, want the spec's", spec.NodeSelector)
+	}
+	for _, toleration := range spec.Tolerations {
+		if toleration.Effect != corev1.TaintEffectNoSchedule {
+			t.Errorf("toleration %v tolerates more than NoSchedule taints", toleration)
+		}
+	}
+}
+
+func TestPrePullCompleted(t *testing.T) {
+	instance := newPrePullTestKNICluster()
+	r, c := newTestReconciler(t, instance)
+
+	if err := r.ensurePrePull(instance, log); err != nil {
+		t.Fatalf("ensurePrePull: %v", err)
+	}
+	status := instance.Status.PrePull
+	if status == nil || status.State != kniv1beta1.PrePullPulling || status.StartTime == nil {
+		t.Fatalf("pre-pull status is %v, want Pulling since now", status)
+	}
+	started := *status.StartTime
+
+	setPrePullDaemonSetStatus(t, c, 2, 1)
+	if err := r.ensurePrePull(instance, log); err != nil {
+		t.Fatalf("ensurePrePull: %v", err)
+	}
+	status = instance.Status.PrePull
+	if status.State != kniv1beta1.PrePullPulling || status.NodesReady != 1 || !status.StartTime.Equal(&started) {
+		t.Errorf("pre-pull status is %v, want Pulling on 1 of 2 nodes since the start", status)
+	}
+
+	setPrePullDaemonSetStatus(t, c, 2, 2)
+	if err := r.ensurePrePull(instance, log); err != nil {
+		t.Fatalf("ensurePrePull: %v", err)
+	}
+	if status := instance.Status.PrePull; status.State != kniv1beta1.PrePullCompleted || status.CompletionTime == nil {
+		t.Errorf("pre-pull status is %v, want Completed", status)
+	}
+	if err := c.Get(context.TODO(), prePullKey, &appsv1.DaemonSet{}); !errors.IsNotFound(err) {
+		t.Errorf("getting the DaemonSet returned %v, want it deleted", err)
+	}
+}
+
+func TestPrePullTimedOut(t *testing.T) {
+	instance := newPrePullTestKNICluster()
+	instance.Spec.Catalog.Image.PrePullTimeout = &metav1.Duration{Duration: 10 * time.Minute}
+	r, c := newTestReconciler(t, instance)
+	if err := r.ensurePrePull(instance, log); err != nil {
+		t.Fatalf("ensurePrePull: %v", err)
+	}
+	// a node that cannot run the pod keeps the DaemonSet from completing
+	setPrePullDaemonSetStatus(t, c, 2, 1)
+
+	if in := prePullTimeoutIn(instance); in <= 9*time.Minute || in > 10*time.Minute {
+		t.Errorf("pre-pull times out in %s, want 10m", in)
+	}
+	started := metav1.NewTime(time.Now().Add(-11 * time.Minute))
+	instance.Status.PrePull.StartTime = &started
+	if err := r.ensurePrePull(instance, log); err != nil {
+		t.Fatalf("ensurePrePull: %v", err)
+	}
+
+	status := instance.Status.PrePull
+	if status.State != kniv1beta1.PrePullTimedOut || status.NodesReady != 1 || status.CompletionTime == nil {
+		t.Errorf("pre-pull status is %v, want TimedOut on 1 of 2 nodes", status)
+	}
+	if err := c.Get(context.TODO(), prePullKey, &appsv1.DaemonSet{}); !errors.IsNotFound(err) {
+		t.Errorf("getting the DaemonSet returned %v, want it deleted", err)
+	}
+
+	// the same images are not pulled again
+	if err := r.ensurePrePull(instance, log); err != nil {
+		t.Fatalf("ensurePrePull: %v", err)
+	}
+	if err := c.Get(context.TODO(), prePullKey, &appsv1.DaemonSet{}); !errors.IsNotFound(err) {
+		t.Errorf("getting the DaemonSet returned %v, want it not created again", err)
+	}
+	if in := prePullTimeoutIn(instance); in != 0 {
+		t.Errorf("pre-pull times out in %s after timing out, want no requeue", in)
+	}
+}
+
+func TestPrePullNodeSelectorChanged(t *testing.T) {
+	instance := newPrePullTestKNICluster()
+	r, c := newTestReconciler(t, instance)
+	if err := r.ensurePrePull(instance, log); err != nil {
+		t.Fatalf("ensurePrePull: %v", err)
+	}
+
+	instance.Spec.Catalog.Image.PrePullNodeSelector = map[string]string{"kni.openshift.com/catalog": "true"}
+	if err := r.ensurePrePull(instance, log); err != nil {
+		t.Fatalf("ensurePrePull: %v", err)
+	}
+
+	daemonSet := &appsv1.DaemonSet{}
+	if err := c.Get(context.TODO(), prePullKey, daemonSet); err != nil {
+		t.Fatal(err)
+	}
+	if selector := daemonSet.Spec.Template.Spec.NodeSelector; selector["kni.openshift.com/catalog"] != "true" {
+		t.Errorf("node selector is %v, want the new one", selector)
+	}
+}
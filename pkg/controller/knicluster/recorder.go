@@ -0,0 +1,113 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// action is a change that the controller would make to an object
+type action struct {
+	verb      string
+	kind      string
+	namespace string
+	name      string
+	object    runtime.Object
+	// diff lists the fields an update would change
+	diff []string
+	// dependsOn lists what the change would wait for
+	dependsOn []string
+}
+
+func (a action) String() string {
+	if a.namespace == "" {
+		return fmt.Sprintf("%s %s %s", a.verb, a.kind, a.name)
+	}
+	return fmt.Sprintf("%s %s %s/%s", a.verb, a.kind, a.namespace, a.name)
+}
+
+// recordingClient reads through to a real client, but records creates,
+// updates and deletes instead of making them. Status updates are passed
+// through, so that status stays up to date.
+type recordingClient struct {
+	client.Client
+	scheme  *runtime.Scheme
+	actions []action
+	// dependsOn lists what the next recorded change would wait for
+	dependsOn []string
+}
+
+var _ client.Client = &recordingClient{}
+
+func newRecordingClient(c client.Client, scheme *runtime.Scheme) *recordingClient {
+	return &recordingClient{Client: c, scheme: scheme}
+}
+
+// Create records the creation of obj
+func (c *recordingClient) Create(ctx context.Context, obj runtime.Object) error {
+	return c.record("create", obj)
+}
+
+// Update records the update of obj, along with the fields that differ from
+// the live object
+func (c *recordingClient) Update(ctx context.Context, obj runtime.Object) error {
+	if err := c.record("update", obj); err != nil {
+		return err
+	}
+	recorded := &c.actions[len(c.actions)-1]
+
+	live := obj.DeepCopyObject()
+	err := c.Client.Get(ctx, types.NamespacedName{Namespace: recorded.namespace, Name: recorded.name}, live)
+	if err != nil {
+		return err
+	}
+	recorded.diff, err = diffObjects(live, obj)
+	return err
+}
+
+// dependOn notes that the next recorded change would wait for what, which is
+// only known when the change is made
+func (c *recordingClient) dependOn(what string) {
+	c.dependsOn = append(c.dependsOn, what)
+}
+
+// Delete records the deletion of obj
+func (c *recordingClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOptionFunc) error {
+	return c.record("delete", obj)
+}
+
+func (c *recordingClient) record(verb string, obj runtime.Object) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return err
+	}
+	c.actions = append(c.actions, action{
+		verb:      verb,
+		kind:      gvk.Kind,
+		namespace: accessor.GetNamespace(),
+		name:      accessor.GetName(),
+		object:    obj.DeepCopyObject(),
+		dependsOn: c.dependsOn,
+	})
+	c.dependsOn = nil
+	return nil
+}
+
+// describeActions returns a human-readable summary of actions
+func describeActions(actions []action) string {
+	descriptions := make([]string, 0, len(actions))
+	for _, a := range actions {
+		descriptions = append(descriptions, a.String())
+	}
+	return strings.Join(descriptions, ", ")
+}
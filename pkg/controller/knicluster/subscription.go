@@ -0,0 +1,131 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+
+	objectreferencesv1 "github.com/djzager/custom-resource-status/objectreferences/v1"
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/reference"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ensureSubscriptions ensures a Subscription exists for each operator, and
+// deletes Subscriptions of operators that were removed from the spec. A
+// failure for one operator does not prevent the others from being reconciled.
+func (r *ReconcileKNICluster) ensureSubscriptions(instance *kniv1beta1.KNICluster, reqLogger logr.Logger) error {
+	var errs []error
+	desired := map[types.NamespacedName]bool{}
+	for _, operator := range instance.Spec.Operators {
+		desired[types.NamespacedName{Namespace: render.OperatorNamespace(instance, operator), Name: operator.Name}] = true
+		err := r.ensureSubscription(instance, operator, reqLogger)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("operator %s: %v", operator.Name, err))
+		}
+	}
+
+	subscriptions, err := r.ownedSubscriptions(instance)
+	if err != nil {
+		return utilerrors.NewAggregate(append(errs, err))
+	}
+	for _, subscription := range subscriptions {
+		if desired[types.NamespacedName{Namespace: subscription.Namespace, Name: subscription.Name}] {
+			continue
+		}
+		reqLogger.Info("Deleting Subscription of removed operator", "Subscription.Namespace", subscription.Namespace, "Subscription.Name", subscription.Name)
+		err = r.client.Delete(context.TODO(), subscription)
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("operator %s: %v", subscription.Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// ownedSubscriptions returns the Subscriptions owned by instance. Those in its
+// own namespace have an owner reference, and the others the owner labels.
+func (r *ReconcileKNICluster) ownedSubscriptions(instance *kniv1beta1.KNICluster) ([]*olm.Subscription, error) {
+	var owned []*olm.Subscription
+	local := &olm.SubscriptionList{}
+	if err := r.client.List(context.TODO(), client.InNamespace(instance.Namespace), local); err != nil {
+		return nil, err
+	}
+	for i := range local.Items {
+		if metav1.IsControlledBy(&local.Items[i], instance) {
+			owned = append(owned, &local.Items[i])
+		}
+	}
+
+	labelled := &olm.SubscriptionList{}
+	if err := r.client.List(context.TODO(), client.MatchingLabels(render.OwnerLabels(instance)), labelled); err != nil {
+		return nil, err
+	}
+	for i := range labelled.Items {
+		if labelled.Items[i].Namespace != instance.Namespace {
+			owned = append(owned, &labelled.Items[i])
+		}
+	}
+	return owned, nil
+}
+
+func (r *ReconcileKNICluster) ensureSubscription(instance *kniv1beta1.KNICluster, operator kniv1beta1.OperatorSpec, reqLogger logr.Logger) error {
+	// ensure Subscription exists
+	subscription := render.Subscription(render.OperatorNamespace(instance, operator), instance.Spec.Catalog, operator, render.InstallPlanApproval(instance))
+	if err := r.setOwner(instance, subscription); err != nil {
+		return err
+	}
+
+	// Check if this Subscription already exists
+	found := &olm.Subscription{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: subscription.Name, Namespace: subscription.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		reqLogger.Info("Creating a new Subscription", "Subscription.Namespace", subscription.Namespace, "Subscription.Name", subscription.Name)
+		err = r.client.Create(context.TODO(), subscription)
+		if err != nil {
+			return err
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// already exists - don't requeue
+	reqLogger.Info("Subscription already exists", "Subscription.Namespace", found.Namespace, "Subscription.Name", found.Name)
+
+	mismatch := ""
+	if found.Spec == nil || found.Spec.Package != subscription.Spec.Package {
+		mismatch = fmt.Sprintf("it does not subscribe to the package %s", subscription.Spec.Package)
+	}
+	managed, err := r.claim(instance, found, olm.SubscriptionKind, adoptionPolicies(instance).Subscriptions, mismatch, reqLogger)
+	if err != nil {
+		return err
+	}
+	if !managed {
+		return r.addRelatedObject(instance, found)
+	}
+
+	// repair drift from the desired spec
+	if found.Spec == nil || *found.Spec != *subscription.Spec {
+		reqLogger.Info("Updating the Subscription spec", "Subscription.Namespace", found.Namespace, "Subscription.Name", found.Name)
+		found.Spec = subscription.Spec
+		err = r.client.Update(context.TODO(), found)
+		if err != nil {
+			return err
+		}
+	}
+
+	objectRef, err := reference.GetReference(r.scheme, found)
+	if err != nil {
+		return err
+	}
+	// Add it to the list of RelatedObjects if found
+	objectreferencesv1.SetObjectReference(&instance.Status.RelatedObjects, *objectRef)
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ClusterOperatorName is the ClusterOperator that reports the status of
+	// KNI to the cluster version operator, if it exists
+	ClusterOperatorName = "kni"
+
+	// ReasonNoCatalogForUpdate is the Upgradeable condition reason when an
+	// update available to the cluster has no KNI catalog
+	ReasonNoCatalogForUpdate = "NoCatalogForUpdate"
+	// ReasonUpgradePreviewIncomplete is the Upgradeable condition reason
+	// while it is not known whether every available update has a KNI catalog
+	ReasonUpgradePreviewIncomplete = "UpgradePreviewIncomplete"
+)
+
+// upgradeableCondition returns the Upgradeable condition for the updates
+// available to the cluster described by cv. It is False while any of them has
+// no catalog, and Unknown while the preview of any of them has not found
+// whether it has one.
+func upgradeableCondition(instance *kniv1beta1.KNICluster, cv *osconfigv1.ClusterVersion) conditionsv1.Condition {
+	previews := map[string]kniv1beta1.UpgradePreview{}
+	for _, preview := range instance.Status.UpgradePreview {
+		previews[preview.Version] = preview
+	}
+
+	var missing, unresolved []string
+	for _, update := range cv.Status.AvailableUpdates {
+		if update.Version == "" {
+			continue
+		}
+		preview, ok := previews[update.Version]
+		switch {
+		case !ok:
+			unresolved = append(unresolved, update.Version)
+		case preview.State == kniv1beta1.UpgradePreviewCatalogMissing:
+			missing = append(missing, update.Version)
+		case preview.State != kniv1beta1.UpgradePreviewReady:
+			unresolved = append(unresolved, update.Version)
+		}
+	}
+	if len(missing) > 0 {
+		return conditionsv1.Condition{
+			Type:    conditionsv1.ConditionUpgradeable,
+			Status:  corev1.ConditionFalse,
+			Reason:  ReasonNoCatalogForUpdate,
+			Message: fmt.Sprintf("There is no tested KNI catalog for cluster versions %s", strings.Join(missing, ", ")),
+		}
+	}
+	if len(unresolved) > 0 {
+		return conditionsv1.Condition{
+			Type:    conditionsv1.ConditionUpgradeable,
+			Status:  corev1.ConditionUnknown,
+			Reason:  ReasonUpgradePreviewIncomplete,
+			Message: fmt.Sprintf("The KNI catalogs for cluster versions %s have not been checked", strings.Join(unresolved, ", ")),
+		}
+	}
+	return conditionsv1.Condition{
+		Type:    conditionsv1.ConditionUpgradeable,
+		Status:  corev1.ConditionTrue,
+		Reason:  "ReconcileCompleted",
+		Message: "Every available update has a KNI catalog",
+	}
+}
+
+// ensureUpgradeable sets the Upgradeable condition of instance, and of the
+// ClusterOperator. It only depends on the upgrade preview, so it is done
+// whatever the components reported.
+func (r *ReconcileKNICluster) ensureUpgradeable(instance *kniv1beta1.KNICluster, reqLogger logr.Logger) {
+	var upgradeable conditionsv1.Condition
+	cv, err := r.clusterVersion()
+	if err != nil {
+		upgradeable = conditionsv1.Condition{
+			Type:    conditionsv1.ConditionUpgradeable,
+			Status:  corev1.ConditionUnknown,
+			Reason:  ReasonUpgradePreviewIncomplete,
+			Message: fmt.Sprintf("The available updates are unknown: %v", err),
+		}
+	} else {
+		upgradeable = upgradeableCondition(instance, cv)
+	}
+	conditionsv1.SetStatusCondition(&instance.Status.Conditions, upgradeable)
+	if err := r.ensureClusterOperatorUpgradeable(instance, upgradeable, reqLogger); err != nil {
+		reqLogger.Error(err, "Failed to update the ClusterOperator")
+	}
+}
+
+// clusterUpgradeable combines upgradeable, the Upgradeable condition of
+// instance, with those of the other KNIClusters. The cluster is only
+// upgradeable when every KNICluster is.
+func (r *ReconcileKNICluster) clusterUpgradeable(instance *kniv1beta1.KNICluster, upgradeable conditionsv1.Condition) (conditionsv1.Condition, error) {
+	instances := &kniv1beta1.KNIClusterList{}
+	if err := r.client.List(context.TODO(), &client.ListOptions{}, instances); err != nil {
+		return upgradeable, err
+	}
+
+	var reasons, messages []string
+	if upgradeable.Status == corev1.ConditionFalse {
+		reasons = append(reasons, upgradeable.Reason)
+		messages = append(messages, fmt.Sprintf("KNICluster %s/%s: %s", instance.Namespace, instance.Name, upgradeable.Message))
+	}
+	for i := range instances.Items {
+		other := &instances.Items[i]
+		if other.Namespace == instance.Namespace && other.Name == instance.Name {
+			continue
+		}
+		condition := conditionsv1.FindStatusCondition(other.Status.Conditions, conditionsv1.ConditionUpgradeable)
+		if condition == nil || condition.Status != corev1.ConditionFalse {
+			continue
+		}
+		reasons = append(reasons, condition.Reason)
+		messages = append(messages, fmt.Sprintf("KNICluster %s/%s: %s", other.Namespace, other.Name, condition.Message))
+	}
+	if len(messages) == 0 {
+		return upgradeable, nil
+	}
+	return conditionsv1.Condition{
+		Type:    conditionsv1.ConditionUpgradeable,
+		Status:  corev1.ConditionFalse,
+		Reason:  reasons[0],
+		Message: strings.Join(messages, "; "),
+	}, nil
+}
+
+// ensureClusterOperatorUpgradeable sets the Upgradeable condition of the KNI
+// ClusterOperator from those of all the KNIClusters, so that the cluster
+// version operator blocks updates that any of them has no catalog for.
+// Nothing is done if there is no ClusterOperator.
+func (r *ReconcileKNICluster) ensureClusterOperatorUpgradeable(instance *kniv1beta1.KNICluster, upgradeable conditionsv1.Condition, reqLogger logr.Logger) error {
+	co := &osconfigv1.ClusterOperator{}
+	err := r.apiReader.Get(context.TODO(), types.NamespacedName{Name: ClusterOperatorName}, co)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	upgradeable, err = r.clusterUpgradeable(instance, upgradeable)
+	if err != nil {
+		return err
+	}
+
+	condition := osconfigv1.ClusterOperatorStatusCondition{
+		Type:    osconfigv1.OperatorUpgradeable,
+		Status:  osconfigv1.ConditionStatus(upgradeable.Status),
+		Reason:  upgradeable.Reason,
+		Message: upgradeable.Message,
+	}
+	for i, existing := range co.Status.Conditions {
+		if existing.Type != condition.Type {
+			continue
+		}
+		if existing.Status == condition.Status && existing.Reason == condition.Reason && existing.Message == condition.Message {
+			return nil
+		}
+		condition.LastTransitionTime = existing.LastTransitionTime
+		if existing.Status != condition.Status {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		co.Status.Conditions[i] = condition
+		reqLogger.Info("Updating the Upgradeable condition of the ClusterOperator", "ClusterOperator.Name", co.Name, "Status", condition.Status)
+		return r.client.Status().Update(context.TODO(), co)
+	}
+
+	condition.LastTransitionTime = metav1.Now()
+	co.Status.Conditions = append(co.Status.Conditions, condition)
+	reqLogger.Info("Setting the Upgradeable condition of the ClusterOperator", "ClusterOperator.Name", co.Name, "Status", condition.Status)
+	return r.client.Status().Update(context.TODO(), co)
+}
@@ -0,0 +1,241 @@
+package knicluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// testUpdateImage is the catalog image of the test KNICluster for version
+func testUpdateImage(version string) string {
+	return "quay.io/mhrivnak/demo-operator-registry:" + version
+}
+
+// clusterVersionWithUpdates returns the ClusterVersion of a cluster at 4.1.0
+// that can be updated to versions
+func clusterVersionWithUpdates(versions ...string) *osconfigv1.ClusterVersion {
+	cv := testClusterVersion("4.1.0")
+	for _, version := range versions {
+		cv.Status.AvailableUpdates = append(cv.Status.AvailableUpdates, osconfigv1.Update{Version: version})
+	}
+	return cv
+}
+
+func TestUpgradeableCondition(t *testing.T) {
+	now := metav1.Now()
+	for _, tc := range []struct {
+		name     string
+		updates  []string
+		previews []kniv1beta1.UpgradePreview
+		status   corev1.ConditionStatus
+		reason   string
+	}{
+		{
+			name:   "no updates",
+			status: corev1.ConditionTrue,
+		},
+		{
+			name:    "every catalog found",
+			updates: []string{"4.2.0", "4.3.0"},
+			previews: []kniv1beta1.UpgradePreview{
+				{Version: "4.2.0", State: kniv1beta1.UpgradePreviewReady},
+				{Version: "4.3.0", State: kniv1beta1.UpgradePreviewReady},
+			},
+			status: corev1.ConditionTrue,
+		},
+		{
+			name:    "catalog missing",
+			updates: []string{"4.2.0", "4.3.0"},
+			previews: []kniv1beta1.UpgradePreview{
+				{Version: "4.2.0", State: kniv1beta1.UpgradePreviewReady},
+				{Version: "4.3.0", State: kniv1beta1.UpgradePreviewCatalogMissing},
+			},
+			status: corev1.ConditionFalse,
+			reason: ReasonNoCatalogForUpdate,
+		},
+		{
+			name:    "missing wins over failed",
+			updates: []string{"4.2.0", "4.3.0"},
+			previews: []kniv1beta1.UpgradePreview{
+				{Version: "4.2.0", State: kniv1beta1.UpgradePreviewFailed, PreviewTime: &now},
+				{Version: "4.3.0", State: kniv1beta1.UpgradePreviewCatalogMissing},
+			},
+			status: corev1.ConditionFalse,
+			reason: ReasonNoCatalogForUpdate,
+		},
+		{
+			name:    "preview failed",
+			updates: []string{"4.2.0"},
+			previews: []kniv1beta1.UpgradePreview{
+				{Version: "4.2.0", State: kniv1beta1.UpgradePreviewFailed, PreviewTime: &now},
+			},
+			status: corev1.ConditionUnknown,
+			reason: ReasonUpgradePreviewIncomplete,
+		},
+		{
+			name:    "preview pending",
+			updates: []string{"4.2.0"},
+			previews: []kniv1beta1.UpgradePreview{
+				{Version: "4.2.0", State: kniv1beta1.UpgradePreviewPending},
+			},
+			status: corev1.ConditionUnknown,
+			reason: ReasonUpgradePreviewIncomplete,
+		},
+		{
+			name:    "update not previewed",
+			updates: []string{"4.2.0", "4.3.0"},
+			previews: []kniv1beta1.UpgradePreview{
+				{Version: "4.2.0", State: kniv1beta1.UpgradePreviewReady},
+			},
+			status: corev1.ConditionUnknown,
+			reason: ReasonUpgradePreviewIncomplete,
+		},
+		{
+			name: "previews of updates no longer available",
+			previews: []kniv1beta1.UpgradePreview{
+				{Version: "4.2.0", State: kniv1beta1.UpgradePreviewCatalogMissing},
+			},
+			status: corev1.ConditionTrue,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			instance := newTestKNICluster()
+			instance.Status.UpgradePreview = tc.previews
+
+			condition := upgradeableCondition(instance, clusterVersionWithUpdates(tc.updates...))
+
+			if condition.Status != tc.status {
+				t.Errorf("status is %s, want %s: %s", condition.Status, tc.status, condition.Message)
+			}
+			if tc.reason != "" && condition.Reason != tc.reason {
+				t.Errorf("reason is %s, want %s", condition.Reason, tc.reason)
+			}
+		})
+	}
+}
+
+func TestReconcileDegradedReportsUpgradeable(t *testing.T) {
+	instance := newTestKNICluster()
+	instance.Status.UpgradePreview = []kniv1beta1.UpgradePreview{{
+		Version:      "4.2.0",
+		CatalogImage: testUpdateImage("4.2.0"),
+		State:        kniv1beta1.UpgradePreviewCatalogMissing,
+	}}
+	// a CatalogSource that is not the KNICluster's degrades the catalog
+	unowned := &olm.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-catalog", Namespace: "olm"},
+		Spec:       olm.CatalogSourceSpec{SourceType: olm.SourceTypeGrpc},
+	}
+	clusterOperator := &osconfigv1.ClusterOperator{ObjectMeta: metav1.ObjectMeta{Name: ClusterOperatorName}}
+	r, c := newTestReconciler(t, instance, unowned, clusterOperator, clusterVersionWithUpdates("4.2.0"))
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err == nil {
+		t.Fatal("reconcile succeeded, want the conflict")
+	}
+
+	degraded := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, conditionsv1.ConditionDegraded)
+	if degraded == nil || degraded.Status != corev1.ConditionTrue {
+		t.Errorf("Degraded condition is %v, want True", degraded)
+	}
+	upgradeable := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, conditionsv1.ConditionUpgradeable)
+	if upgradeable == nil || upgradeable.Status != corev1.ConditionFalse || upgradeable.Reason != ReasonNoCatalogForUpdate {
+		t.Errorf("Upgradeable condition is %v, want False with the reason %s", upgradeable, ReasonNoCatalogForUpdate)
+	}
+	co := &osconfigv1.ClusterOperator{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: ClusterOperatorName}, co); err != nil {
+		t.Fatal(err)
+	}
+	if !clusterOperatorUpgradeable(co, osconfigv1.ConditionFalse) {
+		t.Errorf("ClusterOperator conditions are %v, want Upgradeable False", co.Status.Conditions)
+	}
+}
+
+func TestReconcileUnknownUpgradeableAfterFailedPreview(t *testing.T) {
+	instance := newTestKNICluster()
+	// a recent failure is kept until it is retried
+	failed := metav1.NewTime(time.Now().Add(-time.Minute))
+	instance.Status.UpgradePreview = []kniv1beta1.UpgradePreview{{
+		Version:      "4.2.0",
+		CatalogImage: testUpdateImage("4.2.0"),
+		State:        kniv1beta1.UpgradePreviewFailed,
+		PreviewTime:  &failed,
+	}}
+	clusterOperator := &osconfigv1.ClusterOperator{ObjectMeta: metav1.ObjectMeta{Name: ClusterOperatorName}}
+	r, c := newTestReconciler(t, instance, clusterOperator, clusterVersionWithUpdates("4.2.0"))
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	upgradeable := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, conditionsv1.ConditionUpgradeable)
+	if upgradeable == nil || upgradeable.Status != corev1.ConditionUnknown {
+		t.Errorf("Upgradeable condition is %v, want Unknown", upgradeable)
+	}
+	co := &osconfigv1.ClusterOperator{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: ClusterOperatorName}, co); err != nil {
+		t.Fatal(err)
+	}
+	if !clusterOperatorUpgradeable(co, osconfigv1.ConditionUnknown) {
+		t.Errorf("ClusterOperator conditions are %v, want Upgradeable Unknown", co.Status.Conditions)
+	}
+}
+
+func TestClusterOperatorUpgradeableCombinesKNIClusters(t *testing.T) {
+	instance := newTestKNICluster()
+	instance.Spec.Catalog.Image.SkipContentValidation = true
+	other := newTestKNICluster()
+	other.Namespace = "other"
+	other.Spec.Catalog.Name = "other-catalog"
+	conditionsv1.SetStatusCondition(&other.Status.Conditions, conditionsv1.Condition{
+		Type:    conditionsv1.ConditionUpgradeable,
+		Status:  corev1.ConditionFalse,
+		Reason:  ReasonNoCatalogForUpdate,
+		Message: "no catalog for 4.2.0",
+	})
+	clusterOperator := &osconfigv1.ClusterOperator{ObjectMeta: metav1.ObjectMeta{Name: ClusterOperatorName}}
+	r, c := newTestReconciler(t, instance, other, clusterOperator, testClusterVersion("4.1.0"))
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	// the KNICluster itself is upgradeable, the cluster is not
+	upgradeable := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, conditionsv1.ConditionUpgradeable)
+	if upgradeable == nil || upgradeable.Status != corev1.ConditionTrue {
+		t.Errorf("Upgradeable condition is %v, want True", upgradeable)
+	}
+	co := &osconfigv1.ClusterOperator{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: ClusterOperatorName}, co); err != nil {
+		t.Fatal(err)
+	}
+	if !clusterOperatorUpgradeable(co, osconfigv1.ConditionFalse) {
+		t.Fatalf("ClusterOperator conditions are %v, want Upgradeable False", co.Status.Conditions)
+	}
+	for _, condition := range co.Status.Conditions {
+		if condition.Type == osconfigv1.OperatorUpgradeable && condition.Message != "KNICluster other/example-knicluster: no catalog for 4.2.0" {
+			t.Errorf("Upgradeable message is %q, want it to name the other KNICluster", condition.Message)
+		}
+	}
+}
+
+// clusterOperatorUpgradeable returns whether the Upgradeable condition of co
+// has status
+func clusterOperatorUpgradeable(co *osconfigv1.ClusterOperator, status osconfigv1.ConditionStatus) bool {
+	for _, condition := range co.Status.Conditions {
+		if condition.Type == osconfigv1.OperatorUpgradeable {
+			return condition.Status == status
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/registry"
+	"github.com/mhrivnak/kni-operator/pkg/render"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// previewCatalogSuffix is appended to the CatalogSource name to name the
+// CatalogSource that serves the catalog of an available update while it is
+// queried
+const previewCatalogSuffix = "-preview"
+
+// previewRetryAfter is how long a failed preview is kept before it is
+// computed again, so that it does not keep the catalogs of other updates
+// from being queried
+const previewRetryAfter = 10 * time.Minute
+
+// previewUpgrades computes, for each update available to the cluster, whether
+// it has a catalog and how the managed operators would change. The catalogs
+// of image-backed updates are served one at a time by a preview
+// CatalogSource. Each preview is kept in the status until the update or its
+// catalog image changes, and a failed one until previewRetryAfter has passed.
+func (r *ReconcileKNICluster) previewUpgrades(instance *kniv1beta1.KNICluster, reqLogger logr.Logger) error {
+	cv, err := r.clusterVersion()
+	if err != nil {
+		return err
+	}
+
+	wasPending := previewPending(instance)
+	previous := map[string]kniv1beta1.UpgradePreview{}
+	for _, preview := range instance.Status.UpgradePreview {
+		previous[preview.Version] = preview
+	}
+
+	mirrors, err := r.imageMirrors(instance)
+	if err != nil {
+		return err
+	}
+
+	var previews []kniv1beta1.UpgradePreview
+	var served *olm.CatalogSource
+	for _, update := range cv.Status.AvailableUpdates {
+		if update.Version == "" {
+			continue
+		}
+		catalogsource := render.CatalogSource(instance.Spec.Catalog, update.Version)
+		if mirrored, ok := render.MirrorImage(mirrors, catalogsource.Spec.Image); ok {
+			catalogsource.Spec.Image = mirrored
+		}
+
+		preview := kniv1beta1.UpgradePreview{
+			Version:      update.Version,
+			CatalogImage: catalogsource.Spec.Image,
+			State:        kniv1beta1.UpgradePreviewPending,
+		}
+		if old, ok := previous[update.Version]; ok && old.CatalogImage == preview.CatalogImage && previewDone(old) {
+			previews = append(previews, old)
+			continue
+		}
+
+		exists, err := r.updateCatalogExists(instance, catalogsource, update.Version)
+		now := metav1.Now()
+		switch {
+		case err != nil:
+			preview.State = kniv1beta1.UpgradePreviewFailed
+			preview.Message = fmt.Sprintf("checking the catalog: %v", err)
+			preview.PreviewTime = &now
+		case !exists:
+			preview.State = kniv1beta1.UpgradePreviewCatalogMissing
+			preview.Message = fmt.Sprintf("There is no catalog for version %s", update.Version)
+			if preview.CatalogImage != "" {
+				preview.Message = fmt.Sprintf("Catalog image %s does not exist", preview.CatalogImage)
+			}
+			preview.PreviewTime = &now
+		}
+		if err != nil || !exists {
+			previews = append(previews, preview)
+			continue
+		}
+
+		var packages map[string]*registry.Package
+		switch {
+		case catalogsource.Spec.Image == "":
+			packages, err = render.CatalogPackages(instance.Spec.Catalog, update.Version)
+		case served != nil:
+			// another catalog is being queried
+			preview.Message = "Waiting for the catalog of another update to be queried"
+		default:
+			served = catalogsource
+			packages, err = r.queryPreviewCatalog(instance, catalogsource, &preview, reqLogger)
+		}
+		if err != nil {
+			preview.State = kniv1beta1.UpgradePreviewFailed
+			preview.Message = err.Error()
+			preview.PreviewTime = &now
+		}
+		if packages != nil {
+			if err := r.fillUpgradePreview(instance, packages, &preview); err != nil {
+				return err
+			}
+		}
+		previews = append(previews, preview)
+	}
+	instance.Status.UpgradePreview = previews
+
+	if wasPending && !previewPending(instance) {
+		return r.ensurePreviewCatalogSourceDeleted(render.CatalogSource(instance.Spec.Catalog, "latest"))
+	}
+	return nil
+}
+
+// updateCatalogExists returns whether the catalog that catalogsource would
+// serve for version exists
+func (r *ReconcileKNICluster) updateCatalogExists(instance *kniv1beta1.KNICluster, catalogsource *olm.CatalogSource, version string) (bool, error) {
+	if catalogsource.Spec.Image == "" {
+		return render.CatalogManifestsExist(instance.Spec.Catalog, version), nil
+	}
+	client, err := r.registryClient(instance)
+	if err != nil {
+		return false, err
+	}
+	return client.ImageExists(context.TODO(), catalogsource.Spec.Image)
+}
+
+// queryPreviewCatalog serves the catalog of an available update and returns
+// its packages, or nil while the catalog is starting
+func (r *ReconcileKNICluster) queryPreviewCatalog(instance *kniv1beta1.KNICluster, catalogsource *olm.CatalogSource, preview *kniv1beta1.UpgradePreview, reqLogger logr.Logger) (map[string]*registry.Package, error) {
+	address, err := r.serveCatalog(instance, previewCatalogSource(catalogsource), reqLogger)
+	if err != nil {
+		return nil, err
+	}
+	if address == "" {
+		preview.Message = "Waiting for the catalog to be served"
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), catalogValidationTimeout)
+	defer cancel()
+	return r.catalogPackages(ctx, address, instance.Spec.Operators)
+}
+
+// fillUpgradePreview sets the operator changes of preview from the packages
+// of its catalog
+func (r *ReconcileKNICluster) fillUpgradePreview(instance *kniv1beta1.KNICluster, packages map[string]*registry.Package, preview *kniv1beta1.UpgradePreview) error {
+	heads, missing := channelHeads(packages, instance.Spec.Operators)
+	targets := map[string]string{}
+	for _, head := range heads {
+		targets[head.Package+"/"+head.Channel] = head.CSV
+	}
+
+	for _, operator := range instance.Spec.Operators {
+		target, ok := targets[operator.Package+"/"+operator.Channel]
+		if !ok {
+			continue
+		}
+
+		current := ""
+		subscription := &olm.Subscription{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: render.OperatorNamespace(instance, operator), Name: operator.Name}, subscription)
+		switch {
+		case err == nil:
+			current = subscription.Status.InstalledCSV
+		case !errors.IsNotFound(err):
+			return err
+		}
+
+		preview.Operators = append(preview.Operators, kniv1beta1.OperatorPreview{
+			Name:       operator.Name,
+			CurrentCSV: current,
+			TargetCSV:  target,
+			Changed:    current != target,
+		})
+	}
+
+	now := metav1.Now()
+	preview.State = kniv1beta1.UpgradePreviewReady
+	preview.Message = strings.Join(missing, "; ")
+	preview.PreviewTime = &now
+	return nil
+}
+
+// previewPending returns whether any upgrade preview is still being computed
+func previewPending(instance *kniv1beta1.KNICluster) bool {
+	for _, preview := range instance.Status.UpgradePreview {
+		if preview.State == kniv1beta1.UpgradePreviewPending {
+			return true
+		}
+	}
+	return false
+}
+
+// previewDone returns whether preview can be kept as it is, rather than
+// computed again
+func previewDone(preview kniv1beta1.UpgradePreview) bool {
+	switch preview.State {
+	case kniv1beta1.UpgradePreviewReady, kniv1beta1.UpgradePreviewCatalogMissing:
+		return true
+	case kniv1beta1.UpgradePreviewFailed:
+		return preview.PreviewTime != nil && time.Since(preview.PreviewTime.Time) < previewRetryAfter
+	}
+	return false
+}
+
+// previewRetryIn returns when the earliest failed preview should be computed
+// again, or zero if none failed
+func previewRetryIn(instance *kniv1beta1.KNICluster) time.Duration {
+	var after time.Duration
+	for _, preview := range instance.Status.UpgradePreview {
+		if preview.State != kniv1beta1.UpgradePreviewFailed || preview.PreviewTime == nil {
+			continue
+		}
+		in := previewRetryAfter - time.Since(preview.PreviewTime.Time)
+		if in <= 0 {
+			in = time.Second
+		}
+		if after == 0 || in < after {
+			after = in
+		}
+	}
+	return after
+}
+
+// previewCatalogSource returns the CatalogSource that serves the catalog of
+// an available update while it is queried
+func previewCatalogSource(catalogsource *olm.CatalogSource) *olm.CatalogSource {
+	return temporaryCatalogSource(catalogsource, previewCatalogSuffix, "preview")
+}
+
+func (r *ReconcileKNICluster) ensurePreviewCatalogSourceDeleted(catalogsource *olm.CatalogSource) error {
+	err := r.client.Delete(context.TODO(), previewCatalogSource(catalogsource))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	conditionsv1 "github.com/djzager/custom-resource-status/conditions/v1"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// testPackageManifest is the etcd package of the test catalogs, at the
+// version the catalog is for
+const testPackageManifest = `packageName: etcd
+defaultChannel: singlenamespace-alpha
+channels:
+- name: singlenamespace-alpha
+  currentCSV: etcdoperator.v%s
+`
+
+// newTestManifestsDir returns a ConfigMap catalog directory with manifests
+// for versions
+func newTestManifestsDir(t *testing.T, versions ...string) string {
+	dir, err := ioutil.TempDir("", "catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range versions {
+		if err := os.MkdirAll(filepath.Join(dir, version, "etcd"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		manifest := []byte(fmt.Sprintf(testPackageManifest, version))
+		if err := ioutil.WriteFile(filepath.Join(dir, version, "etcd", "package.yaml"), manifest, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// testRegistry is a registry that has the manifests of the tags in status
+// with http.StatusOK, and answers other requests with http.StatusNotFound
+type testRegistry struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	status map[string]int
+}
+
+func newTestRegistry(status map[string]int) *testRegistry {
+	registry := &testRegistry{status: status}
+	registry.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+		tag := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		if code, ok := registry.status[tag]; ok {
+			w.WriteHeader(code)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	return registry
+}
+
+// setStatus sets the status the registry answers requests for tag with
+func (r *testRegistry) setStatus(tag string, code int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status[tag] = code
+}
+
+// repository returns the name of the catalog repository in the registry
+func (r *testRegistry) repository() string {
+	return strings.TrimPrefix(r.URL, "https://") + "/demo"
+}
+
+// newImagePreviewTestKNICluster returns a KNICluster whose catalog images are
+// in registry
+func newImagePreviewTestKNICluster(registry *testRegistry) *kniv1beta1.KNICluster {
+	instance := newTestKNICluster()
+	instance.Spec.Catalog.Image.Repository = registry.repository()
+	instance.Spec.Catalog.Image.InsecureRegistry = true
+	instance.Spec.Catalog.Image.SkipContentValidation = true
+	return instance
+}
+
+// findPreview returns the preview of version, failing the test if there is
+// none
+func findPreview(t *testing.T, instance *kniv1beta1.KNICluster, version string) kniv1beta1.UpgradePreview {
+	t.Helper()
+	for _, preview := range instance.Status.UpgradePreview {
+		if preview.Version == version {
+			return preview
+		}
+	}
+	t.Fatalf("no preview of %s in %v", version, instance.Status.UpgradePreview)
+	return kniv1beta1.UpgradePreview{}
+}
+
+func TestPreviewConfigMapCatalog(t *testing.T) {
+	dir := newTestManifestsDir(t, "4.1.0", "4.2.0")
+	defer os.RemoveAll(dir)
+	instance := newTestKNICluster()
+	instance.Spec.Catalog.Type = kniv1beta1.CatalogTypeConfigMap
+	instance.Spec.Catalog.ConfigMap.Directory = dir
+	r, _ := newTestReconciler(t, instance, clusterVersionWithUpdates("4.2.0", "4.3.0"))
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	ready := findPreview(t, reconciled, "4.2.0")
+	if ready.State != kniv1beta1.UpgradePreviewReady {
+		t.Errorf("preview of 4.2.0 is %s: %s, want Ready", ready.State, ready.Message)
+	}
+	if len(ready.Operators) != 1 || ready.Operators[0].TargetCSV != "etcdoperator.v4.2.0" || !ready.Operators[0].Changed {
+		t.Errorf("preview of 4.2.0 has operators %v, want etcd changed to etcdoperator.v4.2.0", ready.Operators)
+	}
+	if missing := findPreview(t, reconciled, "4.3.0"); missing.State != kniv1beta1.UpgradePreviewCatalogMissing {
+		t.Errorf("preview of 4.3.0 is %s, want CatalogMissing", missing.State)
+	}
+	upgradeable := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, conditionsv1.ConditionUpgradeable)
+	if upgradeable == nil || upgradeable.Status != corev1.ConditionFalse || !strings.Contains(upgradeable.Message, "4.3.0") {
+		t.Errorf("Upgradeable condition is %v, want False for 4.3.0", upgradeable)
+	}
+}
+
+func TestPreviewRunsWhileDegraded(t *testing.T) {
+	dir := newTestManifestsDir(t, "4.1.0")
+	defer os.RemoveAll(dir)
+	instance := newTestKNICluster()
+	instance.Spec.Catalog.Type = kniv1beta1.CatalogTypeConfigMap
+	instance.Spec.Catalog.ConfigMap.Directory = dir
+	unowned := &olm.CatalogSource{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-catalog", Namespace: "olm"},
+		Spec:       olm.CatalogSourceSpec{SourceType: olm.SourceTypeConfigmap},
+	}
+	r, _ := newTestReconciler(t, instance, unowned, clusterVersionWithUpdates("4.2.0"))
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err == nil {
+		t.Fatal("reconcile succeeded, want the conflict")
+	}
+	if missing := findPreview(t, reconciled, "4.2.0"); missing.State != kniv1beta1.UpgradePreviewCatalogMissing {
+		t.Errorf("preview of 4.2.0 is %s, want CatalogMissing", missing.State)
+	}
+}
+
+func TestPreviewServesOneCatalogAtATime(t *testing.T) {
+	registry := newTestRegistry(map[string]int{"4.2.0": http.StatusOK, "4.3.0": http.StatusOK})
+	defer registry.Close()
+	instance := newImagePreviewTestKNICluster(registry)
+	r, c := newTestReconciler(t, instance, clusterVersionWithUpdates("4.2.0", "4.3.0", "4.4.0"))
+
+	reconciled, result, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	served := findPreview(t, reconciled, "4.2.0")
+	if served.State != kniv1beta1.UpgradePreviewPending || served.Message != "Waiting for the catalog to be served" {
+		t.Errorf("preview of 4.2.0 is %s: %s, want it waiting to be served", served.State, served.Message)
+	}
+	waiting := findPreview(t, reconciled, "4.3.0")
+	if waiting.State != kniv1beta1.UpgradePreviewPending || !strings.Contains(waiting.Message, "another update") {
+		t.Errorf("preview of 4.3.0 is %s: %s, want it waiting for another update", waiting.State, waiting.Message)
+	}
+	if missing := findPreview(t, reconciled, "4.4.0"); missing.State != kniv1beta1.UpgradePreviewCatalogMissing {
+		t.Errorf("preview of 4.4.0 is %s, want CatalogMissing", missing.State)
+	}
+	preview := &olm.CatalogSource{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: "olm", Name: "demo-catalog" + previewCatalogSuffix}, preview); err != nil {
+		t.Fatalf("getting the preview CatalogSource: %v", err)
+	}
+	if preview.Spec.Image != registry.repository()+":4.2.0" {
+		t.Errorf("preview CatalogSource serves %s, want the 4.2.0 catalog", preview.Spec.Image)
+	}
+	if result.RequeueAfter != pendingRequeueAfter {
+		t.Errorf("requeued after %v, want %v while previews are pending", result.RequeueAfter, pendingRequeueAfter)
+	}
+}
+
+func TestPreviewFailureIsRetriedLater(t *testing.T) {
+	registry := newTestRegistry(map[string]int{"4.2.0": http.StatusInternalServerError})
+	defer registry.Close()
+	instance := newImagePreviewTestKNICluster(registry)
+	r, c := newTestReconciler(t, instance, clusterVersionWithUpdates("4.2.0"))
+
+	reconciled, result, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	failed := findPreview(t, reconciled, "4.2.0")
+	if failed.State != kniv1beta1.UpgradePreviewFailed || failed.PreviewTime == nil {
+		t.Fatalf("preview of 4.2.0 is %s: %s, want Failed", failed.State, failed.Message)
+	}
+	if result.RequeueAfter <= previewRetryAfter-time.Minute || result.RequeueAfter > previewRetryAfter {
+		t.Errorf("requeued after %v, want about %v", result.RequeueAfter, previewRetryAfter)
+	}
+	upgradeable := conditionsv1.FindStatusCondition(reconciled.Status.Conditions, conditionsv1.ConditionUpgradeable)
+	if upgradeable == nil || upgradeable.Status != corev1.ConditionUnknown {
+		t.Errorf("Upgradeable condition is %v, want Unknown", upgradeable)
+	}
+
+	// the failure is kept until previewRetryAfter has passed, even once the
+	// registry recovers
+	registry.setStatus("4.2.0", http.StatusOK)
+	reconciled, _, err = reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if kept := findPreview(t, reconciled, "4.2.0"); kept.State != kniv1beta1.UpgradePreviewFailed {
+		t.Errorf("preview of 4.2.0 is %s before the retry, want Failed", kept.State)
+	}
+
+	updateTestKNICluster(t, c, func(instance *kniv1beta1.KNICluster) {
+		expired := metav1.NewTime(time.Now().Add(-previewRetryAfter - time.Second))
+		instance.Status.UpgradePreview[0].PreviewTime = &expired
+	})
+	reconciled, _, err = reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if retried := findPreview(t, reconciled, "4.2.0"); retried.State != kniv1beta1.UpgradePreviewPending {
+		t.Errorf("preview of 4.2.0 is %s: %s after the retry, want Pending", retried.State, retried.Message)
+	}
+}
+
+func TestPreviewKeptUntilImageChanges(t *testing.T) {
+	registry := newTestRegistry(map[string]int{})
+	defer registry.Close()
+	instance := newImagePreviewTestKNICluster(registry)
+	r, c := newTestReconciler(t, instance, clusterVersionWithUpdates("4.2.0"))
+
+	reconciled, _, err := reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	missing := findPreview(t, reconciled, "4.2.0")
+	if missing.State != kniv1beta1.UpgradePreviewCatalogMissing {
+		t.Fatalf("preview of 4.2.0 is %s, want CatalogMissing", missing.State)
+	}
+
+	// the image is published, but the preview of the same image is kept
+	registry.setStatus("4.2.0", http.StatusOK)
+	reconciled, _, err = reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if kept := findPreview(t, reconciled, "4.2.0"); kept.State != kniv1beta1.UpgradePreviewCatalogMissing || !kept.PreviewTime.Equal(missing.PreviewTime) {
+		t.Errorf("preview of 4.2.0 is %s at %v, want it kept", kept.State, kept.PreviewTime)
+	}
+
+	// a resync computes it again
+	updateTestKNICluster(t, c, func(instance *kniv1beta1.KNICluster) {
+		instance.Annotations = map[string]string{kniv1beta1.ReconcileAtAnnotation: "now"}
+	})
+	reconciled, _, err = reconcileTestKey(t, r)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if recomputed := findPreview(t, reconciled, "4.2.0"); recomputed.State != kniv1beta1.UpgradePreviewPending {
+		t.Errorf("preview of 4.2.0 is %s after a resync, want Pending", recomputed.State)
+	}
+}
@@ -0,0 +1,318 @@
+// Package diagnostics gathers the objects and logs that are needed to debug a
+// KNICluster into a single tarball.
+package diagnostics
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	osconfigv1 "github.com/openshift/api/config/v1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// TailLinesDefault is the number of log lines collected per container
+	TailLinesDefault int64 = 1000
+
+	// catalogSourceLabel is set by OLM on the registry pods of a CatalogSource
+	catalogSourceLabel = "olm.catalogSource"
+	// ownerKindLabel is set by OLM on the objects it creates for a CSV
+	ownerKindLabel = "olm.owner.kind"
+
+	redacted = "<redacted>"
+)
+
+// Collector gathers diagnostics for a KNICluster. It reads directly from the
+// API server, so that collecting does not start informers for every kind it
+// looks at.
+type Collector struct {
+	client    client.Client
+	clientset kubernetes.Interface
+	scheme    *runtime.Scheme
+
+	// TailLines is the number of log lines collected per container
+	TailLines int64
+}
+
+// NewCollector returns a Collector for the cluster described by cfg. scheme
+// must include the KNI, OLM and OpenShift config types.
+func NewCollector(cfg *rest.Config, scheme *runtime.Scheme) (*Collector, error) {
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Collector{
+		client:    c,
+		clientset: clientset,
+		scheme:    scheme,
+		TailLines: TailLinesDefault,
+	}, nil
+}
+
+// BundleName returns the name of a bundle collected at the given time
+func BundleName(at time.Time) string {
+	return fmt.Sprintf("kni-diagnostics-%s", at.UTC().Format("20060102-150405"))
+}
+
+// Collect writes a gzipped tarball of diagnostics for the KNICluster kni to
+// w, with all files under the directory BundleName(at). Collection is best
+// effort: anything that cannot be gathered is listed in errors.txt, and only
+// failures to read the KNICluster or to write the bundle are returned.
+//
+// The bundle contains the KNICluster, ClusterVersions, its related objects,
+// and the CatalogSources, Subscriptions, InstallPlans and CSVs in every
+// namespace involved, along with logs of the catalog registry pods and of
+// the operator deployments that OLM created for CSVs. Secret data is
+// redacted.
+func (c *Collector) Collect(ctx context.Context, kni types.NamespacedName, at time.Time, w io.Writer) error {
+	instance := &kniv1beta1.KNICluster{}
+	if err := c.client.Get(ctx, kni, instance); err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	b := &bundle{
+		tw:     tar.NewWriter(gz),
+		dir:    BundleName(at),
+		at:     at,
+		scheme: c.scheme,
+	}
+	c.collect(ctx, b, instance)
+
+	if len(b.errs) > 0 {
+		b.addFile("errors.txt", []byte(strings.Join(b.errs, "\n")+"\n"))
+	}
+	if b.writeErr != nil {
+		return b.writeErr
+	}
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (c *Collector) collect(ctx context.Context, b *bundle, instance *kniv1beta1.KNICluster) {
+	b.addObject(instance)
+
+	cvs := &osconfigv1.ClusterVersionList{}
+	if err := c.client.List(ctx, &client.ListOptions{}, cvs); err != nil {
+		b.errorf("listing ClusterVersions: %v", err)
+	}
+	for i := range cvs.Items {
+		b.addObject(&cvs.Items[i])
+	}
+
+	namespaces := map[string]bool{
+		instance.Namespace:              true,
+		instance.Spec.Catalog.Namespace: true,
+	}
+	for _, ref := range instance.Status.RelatedObjects {
+		if ref.Namespace != "" {
+			namespaces[ref.Namespace] = true
+		}
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+		if err := c.client.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, obj); err != nil {
+			b.errorf("getting related %s %s/%s: %v", ref.Kind, ref.Namespace, ref.Name, err)
+			continue
+		}
+		b.addObject(obj)
+	}
+
+	sorted := make([]string, 0, len(namespaces))
+	for namespace := range namespaces {
+		if namespace != "" {
+			sorted = append(sorted, namespace)
+		}
+	}
+	sort.Strings(sorted)
+	for _, namespace := range sorted {
+		c.collectNamespace(ctx, b, namespace)
+	}
+}
+
+// collectNamespace gathers the OLM objects in namespace, along with the
+// registry pods of its CatalogSources and the deployments OLM created for
+// its CSVs
+func (c *Collector) collectNamespace(ctx context.Context, b *bundle, namespace string) {
+	catalogSources := &olm.CatalogSourceList{}
+	if err := c.client.List(ctx, client.InNamespace(namespace), catalogSources); err != nil {
+		b.errorf("listing CatalogSources in %s: %v", namespace, err)
+	}
+	for i := range catalogSources.Items {
+		catalogSource := &catalogSources.Items[i]
+		b.addObject(catalogSource)
+		c.collectPods(ctx, b, namespace, map[string]string{catalogSourceLabel: catalogSource.Name})
+	}
+
+	subscriptions := &olm.SubscriptionList{}
+	if err := c.client.List(ctx, client.InNamespace(namespace), subscriptions); err != nil {
+		b.errorf("listing Subscriptions in %s: %v", namespace, err)
+	}
+	for i := range subscriptions.Items {
+		b.addObject(&subscriptions.Items[i])
+	}
+
+	installPlans := &olm.InstallPlanList{}
+	if err := c.client.List(ctx, client.InNamespace(namespace), installPlans); err != nil {
+		b.errorf("listing InstallPlans in %s: %v", namespace, err)
+	}
+	for i := range installPlans.Items {
+		b.addObject(&installPlans.Items[i])
+	}
+
+	csvs := &olm.ClusterServiceVersionList{}
+	if err := c.client.List(ctx, client.InNamespace(namespace), csvs); err != nil {
+		b.errorf("listing ClusterServiceVersions in %s: %v", namespace, err)
+	}
+	for i := range csvs.Items {
+		b.addObject(&csvs.Items[i])
+	}
+
+	deployments := &appsv1.DeploymentList{}
+	err := c.client.List(ctx, client.InNamespace(namespace).MatchingLabels(map[string]string{
+		ownerKindLabel: olm.ClusterServiceVersionKind,
+	}), deployments)
+	if err != nil {
+		b.errorf("listing operator Deployments in %s: %v", namespace, err)
+	}
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+		b.addObject(deployment)
+		if deployment.Spec.Selector == nil {
+			continue
+		}
+		c.collectPods(ctx, b, namespace, deployment.Spec.Selector.MatchLabels)
+	}
+}
+
+// collectPods gathers the pods in namespace matching labels, along with the
+// logs of their containers
+func (c *Collector) collectPods(ctx context.Context, b *bundle, namespace string, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	pods := &corev1.PodList{}
+	if err := c.client.List(ctx, client.InNamespace(namespace).MatchingLabels(labels), pods); err != nil {
+		b.errorf("listing pods in %s: %v", namespace, err)
+		return
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		b.addObject(pod)
+		for _, container := range pod.Spec.Containers {
+			logs, err := c.clientset.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+				Container: container.Name,
+				TailLines: &c.TailLines,
+			}).Do().Raw()
+			if err != nil {
+				b.errorf("getting logs of %s/%s container %s: %v", namespace, pod.Name, container.Name, err)
+				continue
+			}
+			b.addFile(path.Join(namespace, "logs", pod.Name, container.Name+".log"), logs)
+		}
+	}
+}
+
+// bundle writes files into a tarball, remembering what could not be collected
+type bundle struct {
+	tw     *tar.Writer
+	dir    string
+	at     time.Time
+	scheme *runtime.Scheme
+
+	errs     []string
+	writeErr error
+}
+
+func (b *bundle) errorf(format string, args ...interface{}) {
+	b.errs = append(b.errs, fmt.Sprintf(format, args...))
+}
+
+// addObject writes obj as YAML to <namespace>/<kind>/<name>.yaml, or to
+// cluster/<kind>/<name>.yaml for cluster-scoped objects
+func (b *bundle) addObject(obj runtime.Object) {
+	gvk, err := apiutil.GVKForObject(obj, b.scheme)
+	if err != nil {
+		b.errorf("adding object: %v", err)
+		return
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		b.errorf("adding %s: %v", gvk.Kind, err)
+		return
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+	if gvk.Kind == "Secret" {
+		redactSecret(u)
+	}
+
+	data, err := yaml.Marshal(u.Object)
+	if err != nil {
+		b.errorf("adding %s %s/%s: %v", gvk.Kind, u.GetNamespace(), u.GetName(), err)
+		return
+	}
+	namespace := u.GetNamespace()
+	if namespace == "" {
+		namespace = "cluster"
+	}
+	b.addFile(path.Join(namespace, strings.ToLower(gvk.Kind), u.GetName()+".yaml"), data)
+}
+
+func (b *bundle) addFile(name string, data []byte) {
+	if b.writeErr != nil {
+		return
+	}
+	b.writeErr = b.tw.WriteHeader(&tar.Header{
+		Name:    path.Join(b.dir, name),
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: b.at,
+	})
+	if b.writeErr == nil {
+		_, b.writeErr = b.tw.Write(data)
+	}
+}
+
+// redactSecret replaces the values of a Secret, including any copy of them
+// in the last applied configuration
+func redactSecret(u *unstructured.Unstructured) {
+	for _, field := range []string{"data", "stringData"} {
+		values, ok := u.Object[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key := range values {
+			values[key] = redacted
+		}
+	}
+	annotations := u.GetAnnotations()
+	if _, ok := annotations[corev1.LastAppliedConfigAnnotation]; ok {
+		annotations[corev1.LastAppliedConfigAnnotation] = redacted
+		u.SetAnnotations(annotations)
+	}
+}
@@ -0,0 +1,86 @@
+// Package notify posts KNICluster lifecycle events to webhook and chat
+// endpoints.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/backoff"
+)
+
+const (
+	// timeout limits each delivery
+	timeout = 10 * time.Second
+	// attempts is the number of deliveries tried per call to Send
+	attempts = 3
+	// retryInterval is the wait before the first retry. It doubles for each
+	// retry.
+	retryInterval = time.Second
+)
+
+// Event is a lifecycle event of a KNICluster. It is the payload of the
+// Webhook format.
+type Event struct {
+	ID      string                       `json:"id"`
+	Event   kniv1beta1.NotificationEvent `json:"event"`
+	Cluster Cluster                      `json:"cluster"`
+	Version string                       `json:"version,omitempty"`
+	Message string                       `json:"message"`
+	Time    time.Time                    `json:"time"`
+}
+
+// Cluster identifies a KNICluster
+type Cluster struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// slackMessage is the payload of the Slack format
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// Send posts event to url in format, retrying when the endpoint fails or
+// answers with a server error
+func Send(ctx context.Context, url string, format kniv1beta1.NotificationFormat, event Event) error {
+	var payload interface{} = event
+	if format == kniv1beta1.NotificationFormatSlack {
+		payload = slackMessage{Text: fmt.Sprintf("KNICluster %s/%s: %s", event.Cluster.Namespace, event.Cluster.Name, event.Message)}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: timeout}
+	return backoff.Retry(ctx, attempts, retryInterval, func() (bool, error) {
+		return post(ctx, client, url, body)
+	})
+}
+
+// post makes one delivery, and returns whether a failure is worth retrying
+func post(ctx context.Context, client *http.Client, url string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests,
+			fmt.Errorf("notification endpoint answered %s", resp.Status)
+	}
+	return false, nil
+}
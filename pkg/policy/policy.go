@@ -0,0 +1,173 @@
+// Package policy asks an external HTTP endpoint to authorize changes the
+// operator is about to make.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/backoff"
+)
+
+const (
+	// TimeoutDefault limits each call when the spec sets no timeout
+	TimeoutDefault = 10 * time.Second
+	// DecisionTTLDefault is how long a decision is kept when the spec sets
+	// no decision TTL
+	DecisionTTLDefault = time.Hour
+	// attempts is the number of calls made before giving up on an endpoint
+	// that fails or answers with a server error
+	attempts = 3
+	// retryInterval is the wait before the first retry. It doubles for each
+	// retry.
+	retryInterval = time.Second
+)
+
+// Request describes a proposed change. It is the body POSTed to the
+// endpoint.
+type Request struct {
+	APIVersion string                  `json:"apiVersion"`
+	Kind       string                  `json:"kind"`
+	Action     kniv1beta1.PolicyAction `json:"action"`
+	// Cluster is the KNICluster proposing the change
+	Cluster Cluster `json:"cluster"`
+	// CatalogSwitch is set for catalog switches
+	CatalogSwitch *CatalogSwitch `json:"catalogSwitch,omitempty"`
+	// InstallPlan is set for InstallPlan approvals
+	InstallPlan *InstallPlan `json:"installPlan,omitempty"`
+}
+
+// Cluster identifies a KNICluster
+type Cluster struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// CatalogSwitch describes a switch of the catalog to a new cluster version
+type CatalogSwitch struct {
+	FromVersion  string `json:"fromVersion,omitempty"`
+	ToVersion    string `json:"toVersion"`
+	CatalogImage string `json:"catalogImage,omitempty"`
+}
+
+// InstallPlan describes an InstallPlan waiting for approval
+type InstallPlan struct {
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	Operator  string   `json:"operator"`
+	CSVs      []string `json:"csvs"`
+}
+
+// Response is the answer of the endpoint
+type Response struct {
+	Decision kniv1beta1.PolicyDecision `json:"decision"`
+	Reason   string                    `json:"reason,omitempty"`
+}
+
+// Client calls a decision endpoint
+type Client struct {
+	URL string
+	// Token is sent as a bearer token, if set
+	Token string
+	// Username and Password are sent as basic authentication, if set
+	Username string
+	Password string
+
+	httpClient *http.Client
+}
+
+// NewClient returns a Client for the endpoint at url, verified with
+// caBundle if it is not empty
+func NewClient(url string, caBundle []byte, timeout time.Duration) (*Client, error) {
+	if timeout == 0 {
+		timeout = TimeoutDefault
+	}
+	transport := http.DefaultTransport
+	if len(caBundle) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("the CA bundle has no PEM-encoded certificates")
+		}
+		transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}
+	}
+	return &Client{
+		URL:        url,
+		httpClient: &http.Client{Transport: transport, Timeout: timeout},
+	}, nil
+}
+
+// Decide asks the endpoint about req, retrying when the endpoint fails or
+// answers with a server error
+func (c *Client) Decide(ctx context.Context, req Request) (*Response, error) {
+	req.APIVersion = kniv1beta1.SchemeGroupVersion.String()
+	req.Kind = "DecisionRequest"
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *Response
+	err = backoff.Retry(ctx, attempts, retryInterval, func() (bool, error) {
+		var retry bool
+		var err error
+		resp, retry, err = c.decide(ctx, body)
+		return retry, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// decide makes one call, and returns whether a failure is worth retrying
+func (c *Client) decide(ctx context.Context, body []byte) (*Response, bool, error) {
+	httpReq, err := http.NewRequest(http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	switch {
+	case c.Token != "":
+		httpReq.Header.Set("Authorization", "Bearer "+c.Token)
+	case c.Username != "":
+		httpReq.SetBasicAuth(c.Username, c.Password)
+	}
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, true, err
+	}
+	defer httpResp.Body.Close()
+	content, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, httpResp.StatusCode >= http.StatusInternalServerError,
+			fmt.Errorf("policy endpoint answered %s", httpResp.Status)
+	}
+
+	resp := &Response{}
+	if err := json.Unmarshal(content, resp); err != nil {
+		return nil, false, fmt.Errorf("invalid policy response: %v", err)
+	}
+	switch resp.Decision {
+	case kniv1beta1.PolicyAllow, kniv1beta1.PolicyDeny, kniv1beta1.PolicyDefer:
+	default:
+		return nil, false, fmt.Errorf("invalid policy decision %q", resp.Decision)
+	}
+	return resp, false, nil
+}
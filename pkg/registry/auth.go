@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Credential authenticates to a registry
+type Credential struct {
+	Username string
+	Password string
+}
+
+// Credentials are the credentials of registries, by registry host
+type Credentials map[string]Credential
+
+// dockerConfigEntry is the entry of a registry in a docker config
+type dockerConfigEntry struct {
+	Auth     string `json:"auth"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ParseDockerConfig parses the registry credentials of a docker config, in
+// either the format of the .dockerconfigjson key of a
+// kubernetes.io/dockerconfigjson Secret, or that of the .dockercfg key of a
+// kubernetes.io/dockercfg Secret
+func ParseDockerConfig(data []byte) (Credentials, error) {
+	config := struct {
+		Auths map[string]dockerConfigEntry `json:"auths"`
+	}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	entries := config.Auths
+	if entries == nil {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, err
+		}
+	}
+
+	credentials := Credentials{}
+	for host, entry := range entries {
+		credential := Credential{Username: entry.Username, Password: entry.Password}
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("auth of %s: %v", host, err)
+			}
+			parts := strings.SplitN(string(decoded), ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("auth of %s is not username:password", host)
+			}
+			credential = Credential{Username: parts[0], Password: parts[1]}
+		}
+		credentials[registryHost(host)] = credential
+	}
+	return credentials, nil
+}
+
+// registryHost returns the registry host of a docker config key, which may
+// be a URL, with Docker Hub's hosts all named like in image references
+func registryHost(key string) string {
+	host := key
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	switch host {
+	case "index.docker.io", dockerHubAPI:
+		return dockerHub
+	}
+	return host
+}
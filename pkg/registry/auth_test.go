@@ -0,0 +1,49 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDockerConfig(t *testing.T) {
+	want := Credentials{
+		"registry.example.com:5000": {Username: "user", Password: "pass:word"},
+		"quay.io":                   {Username: "robot", Password: "token"},
+		"docker.io":                 {Username: "hub", Password: "secret"},
+	}
+	for name, data := range map[string]string{
+		// the .dockerconfigjson key of a kubernetes.io/dockerconfigjson Secret
+		"dockerconfigjson": `{"auths": {
+			"registry.example.com:5000": {"auth": "dXNlcjpwYXNzOndvcmQ="},
+			"quay.io": {"username": "robot", "password": "token"},
+			"https://index.docker.io/v1/": {"auth": "aHViOnNlY3JldA=="}
+		}}`,
+		// the .dockercfg key of a kubernetes.io/dockercfg Secret
+		"dockercfg": `{
+			"registry.example.com:5000": {"auth": "dXNlcjpwYXNzOndvcmQ="},
+			"quay.io": {"username": "robot", "password": "token"},
+			"registry-1.docker.io": {"auth": "aHViOnNlY3JldA=="}
+		}`,
+	} {
+		got, err := ParseDockerConfig([]byte(data))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestParseDockerConfigInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"not json":         `auths`,
+		"invalid base64":   `{"auths": {"quay.io": {"auth": "!"}}}`,
+		"no password part": `{"auths": {"quay.io": {"auth": "dXNlcg=="}}}`,
+	} {
+		if _, err := ParseDockerConfig([]byte(data)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package registry
+
+import (
+	"context"
+	"io"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+)
+
+// The messages and methods below mirror the api.Registry service of
+// operator-registry, which serves the content of a catalog. Only the fields
+// that are needed to check packages and channels are declared; protobuf
+// skips the others. operator-registry is not among the vendored
+// dependencies, so its generated api package cannot be used here; once it
+// is vendored, these mirrors should be replaced by api.RegistryClient.
+
+// listPackageRequest is api.ListPackageRequest
+type listPackageRequest struct{}
+
+func (m *listPackageRequest) Reset()         { *m = listPackageRequest{} }
+func (m *listPackageRequest) String() string { return proto.CompactTextString(m) }
+func (*listPackageRequest) ProtoMessage()    {}
+
+// packageName is api.PackageName
+type packageName struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (m *packageName) Reset()         { *m = packageName{} }
+func (m *packageName) String() string { return proto.CompactTextString(m) }
+func (*packageName) ProtoMessage()    {}
+
+// getPackageRequest is api.GetPackageRequest
+type getPackageRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (m *getPackageRequest) Reset()         { *m = getPackageRequest{} }
+func (m *getPackageRequest) String() string { return proto.CompactTextString(m) }
+func (*getPackageRequest) ProtoMessage()    {}
+
+// Package is api.Package, a package in a catalog
+type Package struct {
+	Name               string     `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Channels           []*Channel `protobuf:"bytes,2,rep,name=channels,proto3" json:"channels,omitempty"`
+	DefaultChannelName string     `protobuf:"bytes,3,opt,name=defaultChannelName,proto3" json:"defaultChannelName,omitempty"`
+}
+
+func (m *Package) Reset()         { *m = Package{} }
+func (m *Package) String() string { return proto.CompactTextString(m) }
+func (*Package) ProtoMessage()    {}
+
+// Channel is api.Channel, a channel of a package and the CSV at its head
+type Channel struct {
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	CsvName string `protobuf:"bytes,2,opt,name=csvName,proto3" json:"csvName,omitempty"`
+}
+
+func (m *Channel) Reset()         { *m = Channel{} }
+func (m *Channel) String() string { return proto.CompactTextString(m) }
+func (*Channel) ProtoMessage()    {}
+
+// CatalogClient queries the registry service of a catalog through the
+// operator-registry gRPC API
+type CatalogClient struct {
+	conn *grpc.ClientConn
+}
+
+// DialCatalog connects to the registry service at address, which is a
+// host:port
+func DialCatalog(ctx context.Context, address string) (*CatalogClient, error) {
+	conn, err := grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		return nil, err
+	}
+	return &CatalogClient{conn: conn}, nil
+}
+
+// Close closes the connection to the registry service
+func (c *CatalogClient) Close() error {
+	return c.conn.Close()
+}
+
+// ListPackages returns the names of the packages in the catalog
+func (c *CatalogClient) ListPackages(ctx context.Context) ([]string, error) {
+	stream, err := c.conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, "/api.Registry/ListPackages")
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(&listPackageRequest{}); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for {
+		name := &packageName{}
+		err := stream.RecvMsg(name)
+		if err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name.Name)
+	}
+}
+
+// GetPackage returns a package of the catalog, with its channels
+func (c *CatalogClient) GetPackage(ctx context.Context, name string) (*Package, error) {
+	pkg := &Package{}
+	err := c.conn.Invoke(ctx, "/api.Registry/GetPackage", &getPackageRequest{Name: name}, pkg)
+	if err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
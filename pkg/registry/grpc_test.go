@@ -0,0 +1,59 @@
+package registry_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mhrivnak/kni-operator/pkg/registry"
+	"github.com/mhrivnak/kni-operator/pkg/registry/registrytest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCatalogClient(t *testing.T) {
+	etcd := &registry.Package{
+		Name:               "etcd",
+		DefaultChannelName: "singlenamespace-alpha",
+		Channels: []*registry.Channel{
+			{Name: "singlenamespace-alpha", CsvName: "etcdoperator.v0.9.4"},
+			{Name: "clusterwide-alpha", CsvName: "etcdoperator.v0.9.4-clusterwide"},
+		},
+	}
+	server, err := registrytest.NewServer(etcd, &registry.Package{Name: "prometheus"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := registry.DialCatalog(ctx, server.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	names, err := client.ListPackages(ctx)
+	if err != nil {
+		t.Fatalf("listing packages: %v", err)
+	}
+	if want := []string{"etcd", "prometheus"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("packages are %v, want %v", names, want)
+	}
+
+	pkg, err := client.GetPackage(ctx, "etcd")
+	if err != nil {
+		t.Fatalf("getting a package: %v", err)
+	}
+	if pkg.Name != etcd.Name || pkg.DefaultChannelName != etcd.DefaultChannelName || len(pkg.Channels) != 2 ||
+		*pkg.Channels[1] != *etcd.Channels[1] {
+		t.Errorf("package is %v, want %v", pkg, etcd)
+	}
+
+	_, err = client.GetPackage(ctx, "missing")
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("getting a missing package returned %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,95 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// dockerHub is the registry of references without a registry host
+	dockerHub = "docker.io"
+	// dockerHubAPI is where the Docker Hub registry API is served
+	dockerHubAPI = "registry-1.docker.io"
+)
+
+// Reference is a parsed image reference
+type Reference struct {
+	// Registry is the registry host, with a port if it has one
+	Registry string
+	// Repository is the repository within the registry
+	Repository string
+	// Tag is empty if the reference has none
+	Tag string
+	// Digest is empty if the reference has none
+	Digest string
+}
+
+// ParseReference parses an image reference such as
+// quay.io/mhrivnak/demo-operator-registry:1.1. References without a tag or
+// digest get the "latest" tag.
+func ParseReference(image string) (Reference, error) {
+	ref := Reference{}
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Registry = parts[0]
+		ref.Repository = parts[1]
+	} else {
+		ref.Registry = dockerHub
+		ref.Repository = name
+		if len(parts) == 1 {
+			ref.Repository = "library/" + name
+		}
+	}
+	if ref.Repository == "" {
+		return ref, fmt.Errorf("invalid image reference %q", image)
+	}
+	return ref, nil
+}
+
+// Name returns the reference without a tag or digest
+func (r Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the reference, with its digest if it has one and its tag
+// otherwise
+func (r Reference) String() string {
+	if r.Digest != "" {
+		return r.Name() + "@" + r.Digest
+	}
+	return r.Name() + ":" + r.Tag
+}
+
+// apiHost returns the host that serves the registry API
+func (r Reference) apiHost() string {
+	if r.Registry == dockerHub {
+		return dockerHubAPI
+	}
+	return r.Registry
+}
+
+// PinDigest returns image qualified by digest instead of its tag, keeping
+// the registry as written in image
+func PinDigest(image, digest string) string {
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	return name + "@" + digest
+}
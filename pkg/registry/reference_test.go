@@ -0,0 +1,83 @@
+package registry
+
+import "testing"
+
+func TestParseReference(t *testing.T) {
+	for _, tc := range []struct {
+		image string
+		want  Reference
+	}{
+		{
+			image: "quay.io/mhrivnak/demo-operator-registry:1.1",
+			want:  Reference{Registry: "quay.io", Repository: "mhrivnak/demo-operator-registry", Tag: "1.1"},
+		},
+		{
+			image: "registry.example.com:5000/catalog",
+			want:  Reference{Registry: "registry.example.com:5000", Repository: "catalog", Tag: "latest"},
+		},
+		{
+			image: "localhost/catalog:4.1.0",
+			want:  Reference{Registry: "localhost", Repository: "catalog", Tag: "4.1.0"},
+		},
+		{
+			image: "mhrivnak/catalog:1.0",
+			want:  Reference{Registry: "docker.io", Repository: "mhrivnak/catalog", Tag: "1.0"},
+		},
+		{
+			image: "busybox",
+			want:  Reference{Registry: "docker.io", Repository: "library/busybox", Tag: "latest"},
+		},
+		{
+			image: "quay.io/mhrivnak/catalog:1.1@sha256:abc",
+			want:  Reference{Registry: "quay.io", Repository: "mhrivnak/catalog", Tag: "1.1", Digest: "sha256:abc"},
+		},
+		{
+			image: "quay.io/mhrivnak/catalog@sha256:abc",
+			want:  Reference{Registry: "quay.io", Repository: "mhrivnak/catalog", Digest: "sha256:abc"},
+		},
+	} {
+		got, err := ParseReference(tc.image)
+		if err != nil {
+			t.Errorf("ParseReference(%q): %v", tc.image, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseReference(%q) = %+v, want %+v", tc.image, got, tc.want)
+		}
+	}
+}
+
+func TestParseReferenceInvalid(t *testing.T) {
+	if _, err := ParseReference("quay.io/"); err == nil {
+		t.Error("ParseReference accepted a reference without a repository")
+	}
+}
+
+func TestReferenceString(t *testing.T) {
+	for image, want := range map[string]string{
+		"busybox":                      "docker.io/library/busybox:latest",
+		"quay.io/mhrivnak/catalog:1.1": "quay.io/mhrivnak/catalog:1.1",
+		"quay.io/mhrivnak/catalog:1.1@sha256:abc": "quay.io/mhrivnak/catalog@sha256:abc",
+	} {
+		ref, err := ParseReference(image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ref.String(); got != want {
+			t.Errorf("String() of %q = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestPinDigest(t *testing.T) {
+	for image, want := range map[string]string{
+		"quay.io/mhrivnak/catalog:1.1":            "quay.io/mhrivnak/catalog@sha256:new",
+		"registry.example.com:5000/catalog":       "registry.example.com:5000/catalog@sha256:new",
+		"quay.io/mhrivnak/catalog:1.1@sha256:old": "quay.io/mhrivnak/catalog@sha256:new",
+		"busybox": "busybox@sha256:new",
+	} {
+		if got := PinDigest(image, "sha256:new"); got != want {
+			t.Errorf("PinDigest(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package registrytest serves catalogs through the operator-registry gRPC
+// API, for tests of the clients of a catalog.
+package registrytest
+
+import (
+	"context"
+	"net"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/mhrivnak/kni-operator/pkg/registry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server serves a catalog of packages at a local address
+type Server struct {
+	// Address is the host:port of the registry service
+	Address string
+
+	server *grpc.Server
+}
+
+// NewServer starts serving a catalog of packages
+func NewServer(packages ...*registry.Package) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := map[string]*registry.Package{}
+	var names []string
+	for _, pkg := range packages {
+		catalog[pkg.Name] = pkg
+		names = append(names, pkg.Name)
+	}
+	sort.Strings(names)
+
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "api.Registry",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "GetPackage",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := &getPackageRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				pkg, ok := catalog[req.Name]
+				if !ok {
+					return nil, status.Errorf(codes.NotFound, "package %s not found", req.Name)
+				}
+				return pkg, nil
+			},
+		}},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "ListPackages",
+			ServerStreams: true,
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				if err := stream.RecvMsg(&listPackageRequest{}); err != nil {
+					return err
+				}
+				for _, name := range names {
+					if err := stream.SendMsg(&packageName{Name: name}); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}},
+	}, struct{}{})
+	go server.Serve(listener)
+
+	return &Server{Address: listener.Addr().String(), server: server}, nil
+}
+
+// Stop stops serving the catalog
+func (s *Server) Stop() {
+	s.server.Stop()
+}
+
+// listPackageRequest is api.ListPackageRequest
+type listPackageRequest struct{}
+
+func (m *listPackageRequest) Reset()         { *m = listPackageRequest{} }
+func (m *listPackageRequest) String() string { return proto.CompactTextString(m) }
+func (*listPackageRequest) ProtoMessage()    {}
+
+// packageName is api.PackageName
+type packageName struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (m *packageName) Reset()         { *m = packageName{} }
+func (m *packageName) String() string { return proto.CompactTextString(m) }
+func (*packageName) ProtoMessage()    {}
+
+// getPackageRequest is api.GetPackageRequest
+type getPackageRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (m *getPackageRequest) Reset()         { *m = getPackageRequest{} }
+func (m *getPackageRequest) String() string { return proto.CompactTextString(m) }
+func (*getPackageRequest) ProtoMessage()    {}
@@ -0,0 +1,299 @@
+// Package registry talks to container image registries through the registry
+// v2 HTTP API.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// manifestMediaTypes are the manifest types accepted from registries, with
+// manifest lists first so that the digest of a multi-arch image is the one of
+// its list
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// Client accesses registries, with the credentials it has for them or
+// anonymously otherwise, obtaining bearer tokens when a registry asks for
+// them.
+type Client struct {
+	// Insecure allows registries that serve plain HTTP or TLS with an
+	// unverifiable certificate, such as local ones. Plain HTTP is only tried
+	// when it is set.
+	Insecure bool
+	// Timeout limits each request. Zero means no limit.
+	Timeout time.Duration
+	// Credentials authenticate to the registries they name
+	Credentials Credentials
+}
+
+// ResolveDigest returns the digest of the manifest image refers to. Images
+// that are already digest-qualified are returned as they are.
+func (c *Client) ResolveDigest(ctx context.Context, image string) (string, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+
+	path := fmt.Sprintf("/v2/%s/manifests/%s", ref.Repository, ref.Tag)
+	header := http.Header{"Accept": []string{strings.Join(manifestMediaTypes, ", ")}}
+
+	resp, err := c.do(ctx, http.MethodHead, ref, path, header)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	// not every registry sends the digest in response to HEAD, so compute it
+	// from the manifest
+	body, err := c.Get(ctx, ref, path, header)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(body)), nil
+}
+
+// Get returns the body of a GET request for path, which is relative to the
+// registry of ref
+func (c *Client) Get(ctx context.Context, ref Reference, path string, header http.Header) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, ref, path, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// do makes a request to the registry of ref, authenticating if necessary,
+// and returns the response if it was successful
+func (c *Client) do(ctx context.Context, method string, ref Reference, path string, header http.Header) (*http.Response, error) {
+	httpClient := c.httpClient()
+	scheme := "https"
+
+	req, err := newRequest(ctx, method, scheme, ref, path, header)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil && c.Insecure {
+		// local registries often only serve plain HTTP
+		scheme = "http"
+		req, err = newRequest(ctx, method, scheme, ref, path, header)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = httpClient.Do(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		credential, hasCredential := c.Credentials[ref.Registry]
+		req, err = newRequest(ctx, method, scheme, ref, path, header)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case strings.HasPrefix(strings.ToLower(challenge), "basic"):
+			if !hasCredential {
+				return nil, fmt.Errorf("authenticating to %s: the registry requires credentials, and there are none for it", ref.Registry)
+			}
+			req.SetBasicAuth(credential.Username, credential.Password)
+		default:
+			var cred *Credential
+			if hasCredential {
+				cred = &credential
+			}
+			token, err := c.token(ctx, challenge, cred)
+			if err != nil {
+				return nil, fmt.Errorf("authenticating to %s: %v", ref.Registry, err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err = httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			err:        fmt.Errorf("%s %s from %s: %s", method, path, ref.Registry, resp.Status),
+		}
+	}
+	return resp, nil
+}
+
+// StatusError is returned when a registry answers a request with an
+// unsuccessful status
+type StatusError struct {
+	StatusCode int
+	err        error
+}
+
+func (e *StatusError) Error() string {
+	return e.err.Error()
+}
+
+// IsNotFound returns whether err reports that a registry does not have the
+// requested manifest or blob
+func IsNotFound(err error) bool {
+	statusErr, ok := err.(*StatusError)
+	return ok && statusErr.StatusCode == http.StatusNotFound
+}
+
+// ImageExists returns whether the registry of image has a manifest for it
+func (c *Client) ImageExists(ctx context.Context, image string) (bool, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return false, err
+	}
+	reference := ref.Tag
+	if ref.Digest != "" {
+		reference = ref.Digest
+	}
+
+	path := fmt.Sprintf("/v2/%s/manifests/%s", ref.Repository, reference)
+	header := http.Header{"Accept": []string{strings.Join(manifestMediaTypes, ", ")}}
+	resp, err := c.do(ctx, http.MethodHead, ref, path, header)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+func newRequest(ctx context.Context, method, scheme string, ref Reference, path string, header http.Header) (*http.Request, error) {
+	u := url.URL{Scheme: scheme, Host: ref.apiHost(), Path: path}
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	return req.WithContext(ctx), nil
+}
+
+// token obtains a bearer token as described by the challenge of a registry,
+// with credential if there is one and anonymously otherwise
+func (c *Client) token(ctx context.Context, challenge string, credential *Credential) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+	params := parseChallenge(challenge[len("bearer "):])
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid realm in authentication challenge %q", challenge)
+	}
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if value, ok := params[key]; ok {
+			query.Set(key, value)
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if credential != nil {
+		req.SetBasicAuth(credential.Username, credential.Password)
+	}
+	resp, err := c.httpClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request: %s", resp.Status)
+	}
+
+	body := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("token response has no token")
+}
+
+// parseChallenge parses the comma-separated key="value" parameters of an
+// authentication challenge
+func parseChallenge(params string) map[string]string {
+	parsed := map[string]string{}
+	for params != "" {
+		i := strings.Index(params, "=")
+		if i < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(params[:i]))
+		params = params[i+1:]
+
+		var value string
+		if strings.HasPrefix(params, `"`) {
+			end := strings.Index(params[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value = params[1 : end+1]
+			params = params[end+2:]
+		} else {
+			end := strings.Index(params, ",")
+			if end < 0 {
+				end = len(params)
+			}
+			value = params[:end]
+			params = params[end:]
+		}
+		parsed[key] = value
+		params = strings.TrimLeft(params, ", ")
+	}
+	return parsed
+}
+
+func (c *Client) httpClient() *http.Client {
+	transport := http.DefaultTransport
+	if c.Insecure {
+		transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}
+	}
+	return &http.Client{Transport: transport, Timeout: c.Timeout}
+}
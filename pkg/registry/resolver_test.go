@@ -0,0 +1,193 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDigest = "sha256:0123456789abcdef"
+
+// newTestRegistry returns a registry that serves the manifest of
+// catalog:1.0, behind auth if it is set
+func newTestRegistry(auth func(w http.ResponseWriter, r *http.Request) bool) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/catalog/manifests/1.0", func(w http.ResponseWriter, r *http.Request) {
+		if auth != nil && !auth(w, r) {
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", testDigest)
+	})
+	return httptest.NewTLSServer(mux)
+}
+
+func registryHostOf(server *httptest.Server) string {
+	return strings.TrimPrefix(server.URL, "https://")
+}
+
+func TestResolveDigestAnonymous(t *testing.T) {
+	server := newTestRegistry(nil)
+	defer server.Close()
+
+	client := &Client{Insecure: true}
+	digest, err := client.ResolveDigest(context.TODO(), registryHostOf(server)+"/catalog:1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != testDigest {
+		t.Errorf("digest is %s, want %s", digest, testDigest)
+	}
+}
+
+func TestResolveDigestPinned(t *testing.T) {
+	client := &Client{}
+	digest, err := client.ResolveDigest(context.TODO(), "registry.invalid/catalog@"+testDigest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != testDigest {
+		t.Errorf("digest is %s, want %s", digest, testDigest)
+	}
+}
+
+func TestResolveDigestFromManifest(t *testing.T) {
+	manifest := []byte(`{"schemaVersion": 2}`)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write(manifest)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{Insecure: true}
+	digest, err := client.ResolveDigest(context.TODO(), registryHostOf(server)+"/catalog:1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("sha256:%x", sha256.Sum256(manifest)); digest != want {
+		t.Errorf("digest is %s, want %s", digest, want)
+	}
+}
+
+func TestResolveDigestBasicAuth(t *testing.T) {
+	server := newTestRegistry(func(w http.ResponseWriter, r *http.Request) bool {
+		if username, password, ok := r.BasicAuth(); ok && username == "user" && password == "pass" {
+			return true
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	})
+	defer server.Close()
+	image := registryHostOf(server) + "/catalog:1.0"
+
+	client := &Client{Insecure: true}
+	if _, err := client.ResolveDigest(context.TODO(), image); err == nil {
+		t.Error("resolved without credentials")
+	}
+
+	client.Credentials = Credentials{registryHostOf(server): {Username: "user", Password: "pass"}}
+	digest, err := client.ResolveDigest(context.TODO(), image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != testDigest {
+		t.Errorf("digest is %s, want %s", digest, testDigest)
+	}
+}
+
+func TestResolveDigestBearerAuth(t *testing.T) {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("scope") != "repository:catalog:pull" || r.URL.Query().Get("service") != "registry" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"token": "secret-token"}`))
+	})
+	mux.HandleFunc("/v2/catalog/manifests/1.0", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:catalog:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", testDigest)
+	})
+	server = httptest.NewTLSServer(mux)
+	defer server.Close()
+	image := registryHostOf(server) + "/catalog:1.0"
+
+	client := &Client{Insecure: true}
+	if _, err := client.ResolveDigest(context.TODO(), image); err == nil {
+		t.Error("resolved with an anonymous token")
+	}
+
+	client.Credentials = Credentials{registryHostOf(server): {Username: "user", Password: "pass"}}
+	digest, err := client.ResolveDigest(context.TODO(), image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != testDigest {
+		t.Errorf("digest is %s, want %s", digest, testDigest)
+	}
+}
+
+func TestPlainHTTPRequiresInsecure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Content-Digest", testDigest)
+	}))
+	defer server.Close()
+	image := strings.TrimPrefix(server.URL, "http://") + "/catalog:1.0"
+
+	client := &Client{}
+	if _, err := client.ResolveDigest(context.TODO(), image); err == nil {
+		t.Error("fell back to plain HTTP without Insecure")
+	}
+
+	client.Insecure = true
+	if _, err := client.ResolveDigest(context.TODO(), image); err != nil {
+		t.Errorf("did not fall back to plain HTTP with Insecure: %v", err)
+	}
+}
+
+func TestImageExists(t *testing.T) {
+	server := newTestRegistry(nil)
+	defer server.Close()
+	client := &Client{Insecure: true}
+
+	for image, want := range map[string]bool{
+		"/catalog:1.0": true,
+		"/catalog:2.0": false,
+	} {
+		exists, err := client.ImageExists(context.TODO(), registryHostOf(server)+image)
+		if err != nil {
+			t.Errorf("%s: %v", image, err)
+			continue
+		}
+		if exists != want {
+			t.Errorf("%s exists is %t, want %t", image, exists, want)
+		}
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	got := parseChallenge(`realm="https://auth.example.com/token",service=registry, scope="repository:a/b:pull,push"`)
+	want := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry",
+		"scope":   "repository:a/b:pull,push",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+package registry
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// signatureAnnotation holds the signature of a cosign signature layer
+	signatureAnnotation = "dev.cosignproject.cosign/signature"
+	// maxSignatureLayers limits how many signatures of an image are checked
+	maxSignatureLayers = 20
+)
+
+// simpleSigningPayload is the part of a signed payload that identifies the
+// signed image
+type simpleSigningPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// signatureManifest is the part of a signature manifest that is needed to
+// find the signatures
+type signatureManifest struct {
+	Layers []struct {
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
+
+// ParsePublicKeys parses the PEM-encoded ECDSA and RSA public keys in data
+func ParsePublicKeys(data []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case *ecdsa.PublicKey, *rsa.PublicKey:
+			keys = append(keys, key)
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", key)
+		}
+	}
+	return keys, nil
+}
+
+// VerifySignature verifies that the image with the given manifest digest has
+// a cosign-style signature, stored in the registry next to the image, made
+// by one of keys. The signed payload uses the simple signing format and must
+// name the digest.
+func (c *Client) VerifySignature(ctx context.Context, image, digest string, keys []crypto.PublicKey) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("no public keys to verify %s with", image)
+	}
+	ref, err := ParseReference(image)
+	if err != nil {
+		return err
+	}
+
+	// cosign stores signatures under a tag derived from the image digest
+	tag := strings.Replace(digest, ":", "-", 1) + ".sig"
+	body, err := c.Get(ctx, ref, fmt.Sprintf("/v2/%s/manifests/%s", ref.Repository, tag), http.Header{
+		"Accept": []string{"application/vnd.oci.image.manifest.v1+json, application/vnd.docker.distribution.manifest.v2+json"},
+	})
+	if err != nil {
+		return fmt.Errorf("%s is not signed: %v", image, err)
+	}
+	manifest := signatureManifest{}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return fmt.Errorf("invalid signature manifest for %s: %v", image, err)
+	}
+
+	var failures []string
+	for i, layer := range manifest.Layers {
+		if i == maxSignatureLayers {
+			break
+		}
+		signature, ok := layer.Annotations[signatureAnnotation]
+		if !ok {
+			continue
+		}
+		err := c.verifyLayer(ctx, ref, layer.Digest, signature, digest, keys)
+		if err == nil {
+			return nil
+		}
+		failures = append(failures, err.Error())
+	}
+	if len(failures) == 0 {
+		return fmt.Errorf("%s is not signed", image)
+	}
+	return fmt.Errorf("no valid signature for %s: %s", image, strings.Join(failures, "; "))
+}
+
+// verifyLayer verifies one signature and the payload it signs
+func (c *Client) verifyLayer(ctx context.Context, ref Reference, layerDigest, encodedSignature, digest string, keys []crypto.PublicKey) error {
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %v", err)
+	}
+	payload, err := c.Get(ctx, ref, fmt.Sprintf("/v2/%s/blobs/%s", ref.Repository, layerDigest), nil)
+	if err != nil {
+		return err
+	}
+	if fmt.Sprintf("sha256:%x", sha256.Sum256(payload)) != layerDigest {
+		return fmt.Errorf("payload does not match its digest %s", layerDigest)
+	}
+
+	hashed := sha256.Sum256(payload)
+	if !verifyAny(keys, hashed[:], signature) {
+		return fmt.Errorf("signature does not match any public key")
+	}
+
+	// the signature is valid, but it must be for this image
+	signed := simpleSigningPayload{}
+	if err := json.Unmarshal(payload, &signed); err != nil {
+		return fmt.Errorf("invalid signed payload: %v", err)
+	}
+	if signed.Critical.Image.DockerManifestDigest != digest {
+		return fmt.Errorf("signature is for %s", signed.Critical.Image.DockerManifestDigest)
+	}
+	return nil
+}
+
+// verifyAny returns whether signature is a valid signature of hashed by one
+// of keys
+func verifyAny(keys []crypto.PublicKey, hashed, signature []byte) bool {
+	for _, key := range keys {
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, hashed, signature) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, hashed, signature) == nil {
+				return true
+			}
+			if rsa.VerifyPSS(k, crypto.SHA256, hashed, signature, nil) == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package registry
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newSignedRegistry returns a registry with a cosign-style signature, made
+// by key, of a payload that names signedDigest. The signature is stored for
+// the image with imageDigest.
+func newSignedRegistry(t *testing.T, key *ecdsa.PrivateKey, imageDigest, signedDigest string) *httptest.Server {
+	payload := []byte(fmt.Sprintf(`{"critical": {"image": {"docker-manifest-digest": %q}, "type": "cosign container image signature"}}`, signedDigest))
+	payloadDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(payload))
+	hashed := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := signatureManifest{}
+	manifest.Layers = append(manifest.Layers, struct {
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+	}{
+		Digest:      payloadDigest,
+		Annotations: map[string]string{signatureAnnotation: base64.StdEncoding.EncodeToString(signature)},
+	})
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/catalog/manifests/"+strings.Replace(imageDigest, ":", "-", 1)+".sig", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(manifestJSON)
+	})
+	mux.HandleFunc("/v2/catalog/blobs/"+payloadDigest, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(payload)
+	})
+	return httptest.NewTLSServer(mux)
+}
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestVerifySignature(t *testing.T) {
+	key := newTestKey(t)
+	server := newSignedRegistry(t, key, testDigest, testDigest)
+	defer server.Close()
+
+	client := &Client{Insecure: true}
+	err := client.VerifySignature(context.TODO(), registryHostOf(server)+"/catalog@"+testDigest, testDigest, []crypto.PublicKey{&key.PublicKey})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerifySignatureUntrustedKey(t *testing.T) {
+	server := newSignedRegistry(t, newTestKey(t), testDigest, testDigest)
+	defer server.Close()
+
+	client := &Client{Insecure: true}
+	trusted := newTestKey(t)
+	err := client.VerifySignature(context.TODO(), registryHostOf(server)+"/catalog@"+testDigest, testDigest, []crypto.PublicKey{&trusted.PublicKey})
+	if err == nil {
+		t.Error("accepted a signature by an untrusted key")
+	}
+}
+
+func TestVerifySignatureOfOtherImage(t *testing.T) {
+	key := newTestKey(t)
+	// a valid signature of another image, copied next to this one
+	server := newSignedRegistry(t, key, testDigest, "sha256:other")
+	defer server.Close()
+
+	client := &Client{Insecure: true}
+	err := client.VerifySignature(context.TODO(), registryHostOf(server)+"/catalog@"+testDigest, testDigest, []crypto.PublicKey{&key.PublicKey})
+	if err == nil {
+		t.Error("accepted the signature of another image")
+	}
+}
+
+func TestVerifySignatureUnsigned(t *testing.T) {
+	server := newSignedRegistry(t, newTestKey(t), "sha256:other", "sha256:other")
+	defer server.Close()
+
+	client := &Client{Insecure: true}
+	key := newTestKey(t)
+	err := client.VerifySignature(context.TODO(), registryHostOf(server)+"/catalog@"+testDigest, testDigest, []crypto.PublicKey{&key.PublicKey})
+	if err == nil {
+		t.Error("accepted an unsigned image")
+	}
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	key := newTestKey(t)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	// other blocks, such as certificates, are skipped
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("ignored")})...)
+
+	keys, err := ParsePublicKeys(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("parsed %d keys, want 1", len(keys))
+	}
+	if parsed, ok := keys[0].(*ecdsa.PublicKey); !ok || parsed.X.Cmp(key.X) != 0 || parsed.Y.Cmp(key.Y) != 0 {
+		t.Errorf("parsed key %v, want %v", keys[0], key.PublicKey)
+	}
+}
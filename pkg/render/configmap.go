@@ -0,0 +1,193 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Keys of the ConfigMap that backs a configmap CatalogSource, as read by
+// OLM's configmap-server
+const (
+	configMapCRDsKey     = "customResourceDefinitions"
+	configMapCSVsKey     = "clusterServiceVersions"
+	configMapPackagesKey = "packages"
+)
+
+// maxConfigMapSize leaves room in a ConfigMap, which is limited to 1MiB, for
+// its metadata
+const maxConfigMapSize = 900 * 1024
+
+// CatalogConfigMap returns the ConfigMap that backs a ConfigMap catalog when
+// the cluster is at version. It is built from the manifests in the version's
+// subdirectory of catalog.ConfigMap.Directory. Files are sorted into CRDs,
+// CSVs and packages by their content, so the subdirectory can use the
+// operator-registry manifests layout or any other.
+func CatalogConfigMap(catalog kniv1beta1.CatalogSpec, version string) (*corev1.ConfigMap, error) {
+	dir := filepath.Join(catalog.ConfigMap.Directory, version)
+	manifests, err := loadManifests(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(manifests.packages) == 0 {
+		return nil, fmt.Errorf("no package manifests found in %s", dir)
+	}
+
+	data := map[string]string{}
+	size := 0
+	for key, list := range map[string][]interface{}{
+		configMapCRDsKey:     manifests.crds,
+		configMapCSVsKey:     manifests.csvs,
+		configMapPackagesKey: manifests.packages,
+	} {
+		out, err := yaml.Marshal(list)
+		if err != nil {
+			return nil, err
+		}
+		data[key] = string(out)
+		size += len(out)
+	}
+	if size > maxConfigMapSize {
+		return nil, fmt.Errorf("the manifests in %s add up to %d bytes, which is too large for a ConfigMap", dir, size)
+	}
+
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      catalog.Name,
+			Namespace: catalog.Namespace,
+			Labels:    map[string]string{kniv1beta1.CatalogVersionLabel: version},
+		},
+		Data: data,
+	}, nil
+}
+
+// CatalogManifestsExist returns whether a ConfigMap catalog has manifests for
+// version
+func CatalogManifestsExist(catalog kniv1beta1.CatalogSpec, version string) bool {
+	info, err := os.Stat(filepath.Join(catalog.ConfigMap.Directory, version))
+	return err == nil && info.IsDir()
+}
+
+// CatalogPackages returns the packages a ConfigMap catalog serves when the
+// cluster is at version, keyed by package name
+func CatalogPackages(catalog kniv1beta1.CatalogSpec, version string) (map[string]*registry.Package, error) {
+	dir := filepath.Join(catalog.ConfigMap.Directory, version)
+	manifests, err := loadManifests(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	packages := map[string]*registry.Package{}
+	for _, manifest := range manifests.packages {
+		out, err := yaml.Marshal(manifest)
+		if err != nil {
+			return nil, err
+		}
+		pkg := &packageManifest{}
+		if err := yaml.Unmarshal(out, pkg); err != nil {
+			return nil, fmt.Errorf("package manifest in %s: %v", dir, err)
+		}
+		converted := &registry.Package{Name: pkg.PackageName, DefaultChannelName: pkg.DefaultChannel}
+		for _, channel := range pkg.Channels {
+			converted.Channels = append(converted.Channels, &registry.Channel{Name: channel.Name, CsvName: channel.CurrentCSV})
+		}
+		packages[pkg.PackageName] = converted
+	}
+	return packages, nil
+}
+
+// packageManifest is an operator-registry package manifest
+type packageManifest struct {
+	PackageName    string `json:"packageName"`
+	DefaultChannel string `json:"defaultChannel,omitempty"`
+	Channels       []struct {
+		Name       string `json:"name"`
+		CurrentCSV string `json:"currentCSV"`
+	} `json:"channels"`
+}
+
+// manifests are the contents of a ConfigMap catalog
+type manifests struct {
+	crds     []interface{}
+	csvs     []interface{}
+	packages []interface{}
+}
+
+// loadManifests reads every YAML file under dir
+func loadManifests(dir string) (*manifests, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("no manifests for this version: %v", err)
+	}
+
+	m := &manifests{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, doc := range splitYAMLDocuments(content) {
+			manifest := map[string]interface{}{}
+			if err := yaml.Unmarshal(doc, &manifest); err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+			switch {
+			case len(manifest) == 0:
+			case manifest["kind"] == "CustomResourceDefinition":
+				m.crds = append(m.crds, manifest)
+			case manifest["kind"] == "ClusterServiceVersion":
+				m.csvs = append(m.csvs, manifest)
+			case manifest["packageName"] != nil:
+				m.packages = append(m.packages, manifest)
+			default:
+				return fmt.Errorf("%s: not a CRD, CSV or package manifest", path)
+			}
+		}
+		return nil
+	})
+	return m, err
+}
+
+// splitYAMLDocuments splits a multi-document YAML file on "---" lines
+func splitYAMLDocuments(content []byte) [][]byte {
+	var docs [][]byte
+	var doc []byte
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		if strings.TrimSpace(string(line)) == "---" {
+			docs = appendDocument(docs, doc)
+			doc = nil
+			continue
+		}
+		doc = append(doc, line...)
+	}
+	return appendDocument(docs, doc)
+}
+
+func appendDocument(docs [][]byte, doc []byte) [][]byte {
+	if len(bytes.TrimSpace(doc)) == 0 {
+		return docs
+	}
+	return append(docs, doc)
+}
@@ -0,0 +1,102 @@
+package render
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+)
+
+const testManifests = `apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: etcdclusters.etcd.database.coreos.com
+---
+apiVersion: operators.coreos.com/v1alpha1
+kind: ClusterServiceVersion
+metadata:
+  name: etcdoperator.v0.9.4
+---
+packageName: etcd
+defaultChannel: alpha
+channels:
+- name: alpha
+  currentCSV: etcdoperator.v0.9.4
+`
+
+// newTestCatalog returns a ConfigMap catalog with manifests for 4.1.0
+func newTestCatalog(t *testing.T) (kniv1beta1.CatalogSpec, func()) {
+	dir, err := ioutil.TempDir("", "catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "4.1.0", "etcd"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "4.1.0", "etcd", "etcd.yaml"), []byte(testManifests), 0644); err != nil {
+		t.Fatal(err)
+	}
+	catalog := kniv1beta1.CatalogSpec{
+		Name:      "demo-catalog",
+		Namespace: "olm",
+		Type:      kniv1beta1.CatalogTypeConfigMap,
+		ConfigMap: kniv1beta1.CatalogConfigMapSpec{Directory: dir},
+	}
+	return catalog, func() { os.RemoveAll(dir) }
+}
+
+func TestCatalogConfigMap(t *testing.T) {
+	catalog, cleanup := newTestCatalog(t)
+	defer cleanup()
+
+	configMap, err := CatalogConfigMap(catalog, "4.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configMap.Name != catalog.Name || configMap.Namespace != catalog.Namespace {
+		t.Errorf("ConfigMap is %s/%s", configMap.Namespace, configMap.Name)
+	}
+	for key, want := range map[string]string{
+		configMapCRDsKey:     "etcdclusters.etcd.database.coreos.com",
+		configMapCSVsKey:     "etcdoperator.v0.9.4",
+		configMapPackagesKey: "packageName: etcd",
+	} {
+		if !strings.Contains(configMap.Data[key], want) {
+			t.Errorf("%s does not contain %q:\n%s", key, want, configMap.Data[key])
+		}
+	}
+
+	if _, err := CatalogConfigMap(catalog, "4.2.0"); err == nil {
+		t.Error("rendered a version without manifests")
+	}
+}
+
+func TestCatalogPackages(t *testing.T) {
+	catalog, cleanup := newTestCatalog(t)
+	defer cleanup()
+
+	if !CatalogManifestsExist(catalog, "4.1.0") || CatalogManifestsExist(catalog, "4.2.0") {
+		t.Error("CatalogManifestsExist does not match the versions with manifests")
+	}
+	packages, err := CatalogPackages(catalog, "4.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	etcd, ok := packages["etcd"]
+	if !ok {
+		t.Fatalf("packages are %v, want etcd", packages)
+	}
+	if etcd.DefaultChannelName != "alpha" || len(etcd.Channels) != 1 || etcd.Channels[0].CsvName != "etcdoperator.v0.9.4" {
+		t.Errorf("etcd package is %v", etcd)
+	}
+}
+
+func TestSplitYAMLDocuments(t *testing.T) {
+	docs := splitYAMLDocuments([]byte("---\na: 1\n---\n\n---\nb: 2\n"))
+	if len(docs) != 2 || string(docs[0]) != "a: 1\n" || string(docs[1]) != "b: 2\n" {
+		t.Errorf("got %q", docs)
+	}
+}
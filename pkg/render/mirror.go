@@ -0,0 +1,102 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ConsumerCatalog is the consumer of the catalog image in image statuses
+const ConsumerCatalog = "catalog"
+
+// Mirrors returns the mirrors that apply to instance. Mirrors from the spec
+// come first, so that they take precedence over policyMirrors, which usually
+// come from ImageContentSourcePolicies.
+func Mirrors(instance *kniv1beta1.KNICluster, policyMirrors []kniv1beta1.ImageMirror) []kniv1beta1.ImageMirror {
+	mirrors := append([]kniv1beta1.ImageMirror{}, instance.Spec.ImageMirrors...)
+	return append(mirrors, policyMirrors...)
+}
+
+// MirrorImage rewrites image to use the mirror whose source is the longest
+// match, and reports whether it was rewritten. When sources are equally
+// long, the first one wins. Trailing slashes of sources and mirrors are
+// ignored.
+func MirrorImage(mirrors []kniv1beta1.ImageMirror, image string) (string, bool) {
+	var best *kniv1beta1.ImageMirror
+	bestSource := ""
+	for i := range mirrors {
+		mirror := &mirrors[i]
+		source := strings.TrimSuffix(mirror.Source, "/")
+		if !matchesSource(image, source) {
+			continue
+		}
+		if best == nil || len(source) > len(bestSource) {
+			best = mirror
+			bestSource = source
+		}
+	}
+	if best == nil {
+		return image, false
+	}
+	return strings.TrimSuffix(best.Mirror, "/") + strings.TrimPrefix(image, bestSource), true
+}
+
+// matchesSource returns whether image is in source, which is a repository or
+// a prefix of repositories without a trailing slash
+func matchesSource(image, source string) bool {
+	if source == "" || !strings.HasPrefix(image, source) {
+		return false
+	}
+	rest := image[len(source):]
+	return rest == "" || strings.ContainsAny(rest[:1], "/:@")
+}
+
+// MirrorOperand rewrites the image references in the spec of operand to use mirrors,
+// and returns the images it references. Image references are the string
+// values of fields whose names end in "image" or "images", such as image or
+// baseImage.
+func MirrorOperand(mirrors []kniv1beta1.ImageMirror, operand *unstructured.Unstructured) []kniv1beta1.ImageStatus {
+	consumer := fmt.Sprintf("%s %s/%s", operand.GetKind(), operand.GetNamespace(), operand.GetName())
+	var images []kniv1beta1.ImageStatus
+	if spec, ok := operand.Object["spec"]; ok {
+		operand.Object["spec"] = mirrorValue(mirrors, consumer, "spec", spec, false, &images)
+	}
+	return images
+}
+
+func mirrorValue(mirrors []kniv1beta1.ImageMirror, consumer, path string, value interface{}, isImage bool, images *[]kniv1beta1.ImageStatus) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		// sorted, so that images are listed in a stable order
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := v[key]
+			lower := strings.ToLower(key)
+			childIsImage := strings.HasSuffix(lower, "image") || strings.HasSuffix(lower, "images")
+			v[key] = mirrorValue(mirrors, consumer, path+"."+key, child, childIsImage, images)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = mirrorValue(mirrors, consumer, fmt.Sprintf("%s[%d]", path, i), child, isImage, images)
+		}
+	case string:
+		if !isImage || v == "" {
+			return v
+		}
+		status := kniv1beta1.ImageStatus{Consumer: consumer + " " + path, Image: v}
+		if mirrored, ok := MirrorImage(mirrors, v); ok {
+			status.Image = mirrored
+			status.Source = v
+		}
+		*images = append(*images, status)
+		return status.Image
+	}
+	return value
+}
@@ -0,0 +1,67 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestMirrorImage(t *testing.T) {
+	mirrors := []kniv1beta1.ImageMirror{
+		{Source: "quay.io", Mirror: "mirror.example.com"},
+		{Source: "quay.io/mhrivnak/", Mirror: "mirror.example.com/kni"},
+		{Source: "quay.io/mhrivnak", Mirror: "ignored.example.com/kni"},
+	}
+	for image, want := range map[string]string{
+		// the longest source wins, and the first of equally long ones
+		"quay.io/mhrivnak/catalog:1.0": "mirror.example.com/kni/catalog:1.0",
+		"quay.io/coreos/etcd@sha256:0": "mirror.example.com/coreos/etcd@sha256:0",
+		// sources only match whole path components
+		"quay.io.example.com/etcd:1.0": "",
+		"docker.io/library/busybox":    "",
+	} {
+		got, ok := MirrorImage(mirrors, image)
+		if want == "" {
+			if ok {
+				t.Errorf("%s was mirrored to %s", image, got)
+			}
+			continue
+		}
+		if !ok || got != want {
+			t.Errorf("%s was mirrored to %s, want %s", image, got, want)
+		}
+	}
+}
+
+func TestMirrorOperand(t *testing.T) {
+	operand := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Example",
+		"metadata":   map[string]interface{}{"name": "example", "namespace": "kniops"},
+		"spec": map[string]interface{}{
+			"baseImage":     "quay.io/coreos/etcd:3.3",
+			"repository":    "quay.io/coreos/etcd",
+			"sidecarImages": []interface{}{"docker.io/library/busybox"},
+		},
+	}}
+	mirrors := []kniv1beta1.ImageMirror{{Source: "quay.io/coreos", Mirror: "mirror.example.com/coreos"}}
+
+	images := MirrorOperand(mirrors, operand)
+
+	spec := operand.Object["spec"].(map[string]interface{})
+	if spec["baseImage"] != "mirror.example.com/coreos/etcd:3.3" {
+		t.Errorf("baseImage is %v", spec["baseImage"])
+	}
+	if spec["repository"] != "quay.io/coreos/etcd" {
+		t.Errorf("repository, which is not an image field, is %v", spec["repository"])
+	}
+	want := []kniv1beta1.ImageStatus{
+		{Consumer: "Example kniops/example spec.baseImage", Image: "mirror.example.com/coreos/etcd:3.3", Source: "quay.io/coreos/etcd:3.3"},
+		{Consumer: "Example kniops/example spec.sidecarImages[0]", Image: "docker.io/library/busybox"},
+	}
+	if !reflect.DeepEqual(images, want) {
+		t.Errorf("got images %+v, want %+v", images, want)
+	}
+}
@@ -0,0 +1,275 @@
+// Package render builds the objects the operator manages for a KNICluster.
+// It needs no cluster, so that the same objects can be reviewed or committed
+// ahead of time.
+package render
+
+import (
+	"fmt"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	olmv1 "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/json"
+)
+
+// OperatorGroupName is the name of the OperatorGroup in each namespace that
+// operators are installed in
+const OperatorGroupName = "kni"
+
+// Objects returns every object the operator manages for instance when the
+// cluster is at version, in the order the operator reconciles them. Omitted
+// spec fields take their defaults. Image references are rewritten to use the
+// mirrors in the spec and policyMirrors. Objects outside the namespace of
+// instance get the owner labels, as the operator sets them. Owner references
+// are not set, since they require the KNICluster to exist, and the catalog
+// image is not pinned to a digest, since that requires the registry.
+func Objects(instance *kniv1beta1.KNICluster, version string, policyMirrors []kniv1beta1.ImageMirror) ([]runtime.Object, error) {
+	if instance.Namespace == "" {
+		return nil, fmt.Errorf("the KNICluster namespace is required")
+	}
+	instance = instance.DeepCopy()
+	instance.SetDefaults()
+
+	var objects []runtime.Object
+	for _, spec := range instance.Spec.Namespaces {
+		namespace := Namespace(spec)
+		SetOwnerLabels(instance, namespace)
+		objects = append(objects, namespace)
+	}
+	if instance.Spec.Catalog.Type == kniv1beta1.CatalogTypeConfigMap {
+		configMap, err := CatalogConfigMap(instance.Spec.Catalog, version)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, configMap)
+	}
+	mirrors := Mirrors(instance, policyMirrors)
+	catalogSource := CatalogSource(instance.Spec.Catalog, version)
+	if catalogSource.Spec.Image != "" {
+		catalogSource.Spec.Image, _ = MirrorImage(mirrors, catalogSource.Spec.Image)
+	}
+	SetOwnerLabels(instance, catalogSource)
+	objects = append(objects, catalogSource)
+	for _, namespace := range OperatorNamespaces(instance) {
+		operatorGroup := OperatorGroup(namespace, OperatorGroupConfig(instance, namespace))
+		SetOwnerLabels(instance, operatorGroup)
+		objects = append(objects, operatorGroup)
+	}
+	for _, operator := range instance.Spec.Operators {
+		subscription := Subscription(OperatorNamespace(instance, operator), instance.Spec.Catalog, operator, InstallPlanApproval(instance))
+		SetOwnerLabels(instance, subscription)
+		objects = append(objects, subscription)
+	}
+	for _, operator := range instance.Spec.Operators {
+		for i, raw := range operator.Operands {
+			operand, err := Operand(OperatorNamespace(instance, operator), raw)
+			if err != nil {
+				return nil, fmt.Errorf("operator %s operand %d: %v", operator.Name, i, err)
+			}
+			MirrorOperand(mirrors, operand)
+			SetOwnerLabels(instance, operand)
+			objects = append(objects, operand)
+		}
+	}
+	return objects, nil
+}
+
+// OwnerLabels returns the labels that identify instance as the owner of
+// objects that cannot have an owner reference to it
+func OwnerLabels(instance *kniv1beta1.KNICluster) map[string]string {
+	return map[string]string{
+		kniv1beta1.OwnerNameLabel:      instance.Name,
+		kniv1beta1.OwnerNamespaceLabel: instance.Namespace,
+	}
+}
+
+// SetOwnerLabels gives obj the owner labels of instance if obj is outside its
+// namespace. Owner references cannot cross namespaces, so those objects are
+// identified by the labels instead.
+func SetOwnerLabels(instance *kniv1beta1.KNICluster, obj metav1.Object) {
+	if obj.GetNamespace() == instance.Namespace {
+		return
+	}
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for key, value := range OwnerLabels(instance) {
+		labels[key] = value
+	}
+	obj.SetLabels(labels)
+}
+
+// CatalogSource returns the CatalogSource for catalog when the cluster is at
+// version. A ConfigMap catalog is backed by the ConfigMap from
+// CatalogConfigMap.
+func CatalogSource(catalog kniv1beta1.CatalogSpec, version string) *olm.CatalogSource {
+	catalogSource := &olm.CatalogSource{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: olm.SchemeGroupVersion.String(),
+			Kind:       olm.CatalogSourceKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      catalog.Name,
+			Namespace: catalog.Namespace,
+			Labels:    map[string]string{kniv1beta1.CatalogVersionLabel: version},
+		},
+		Spec: olm.CatalogSourceSpec{
+			Publisher:   "kni.openshift.com",
+			DisplayName: "KNI Operators",
+		},
+	}
+	if catalog.Type == kniv1beta1.CatalogTypeConfigMap {
+		catalogSource.Spec.SourceType = olm.SourceTypeConfigmap
+		catalogSource.Spec.ConfigMap = catalog.Name
+	} else {
+		catalogSource.Spec.SourceType = olm.SourceTypeGrpc
+		catalogSource.Spec.Image = fmt.Sprintf("%s:%s", catalog.Image.Repository, version)
+	}
+	return catalogSource
+}
+
+// Namespace returns the namespace described by spec
+func Namespace(spec kniv1beta1.NamespaceSpec) *corev1.Namespace {
+	namespace := &corev1.Namespace{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "Namespace",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: spec.Name,
+		},
+	}
+	if len(spec.Labels) > 0 {
+		namespace.Labels = map[string]string{}
+		for key, value := range spec.Labels {
+			namespace.Labels[key] = value
+		}
+	}
+	if len(spec.Annotations) > 0 {
+		namespace.Annotations = map[string]string{}
+		for key, value := range spec.Annotations {
+			namespace.Annotations[key] = value
+		}
+	}
+	return namespace
+}
+
+// OperatorNamespace returns the namespace operator is installed in
+func OperatorNamespace(instance *kniv1beta1.KNICluster, operator kniv1beta1.OperatorSpec) string {
+	if operator.Namespace != "" {
+		return operator.Namespace
+	}
+	return instance.Namespace
+}
+
+// OperatorNamespaces returns the namespaces the operators of instance are
+// installed in, in the order they first appear in the spec. Each of them gets
+// an OperatorGroup.
+func OperatorNamespaces(instance *kniv1beta1.KNICluster) []string {
+	var namespaces []string
+	seen := map[string]bool{}
+	for _, operator := range instance.Spec.Operators {
+		namespace := OperatorNamespace(instance, operator)
+		if !seen[namespace] {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// OperatorGroupConfig returns the configuration of the OperatorGroup in
+// namespace, or nil if it targets only its own namespace
+func OperatorGroupConfig(instance *kniv1beta1.KNICluster, namespace string) *kniv1beta1.OperatorGroupSpec {
+	for i := range instance.Spec.OperatorGroups {
+		if instance.Spec.OperatorGroups[i].Namespace == namespace {
+			return &instance.Spec.OperatorGroups[i]
+		}
+	}
+	return nil
+}
+
+// OperatorGroup returns the OperatorGroup in namespace. It targets the
+// namespaces of config, or only namespace when config is nil.
+func OperatorGroup(namespace string, config *kniv1beta1.OperatorGroupSpec) *olmv1.OperatorGroup {
+	operatorGroup := &olmv1.OperatorGroup{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: olmv1.SchemeGroupVersion.String(),
+			Kind:       olmv1.OperatorGroupKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      OperatorGroupName,
+			Namespace: namespace,
+		},
+	}
+	switch {
+	case config == nil:
+		operatorGroup.Spec.TargetNamespaces = []string{namespace}
+	case config.AllNamespaces:
+		// OLM targets all namespaces when neither targets nor a selector
+		// are set
+	case len(config.TargetNamespaces) > 0:
+		operatorGroup.Spec.TargetNamespaces = append([]string(nil), config.TargetNamespaces...)
+	case config.Selector != nil:
+		operatorGroup.Spec.Selector = config.Selector.DeepCopy()
+	default:
+		operatorGroup.Spec.TargetNamespaces = []string{namespace}
+	}
+	return operatorGroup
+}
+
+// InstallPlanApproval returns the InstallPlan approval of the Subscriptions
+// of instance. It is Manual when a policy endpoint decides on InstallPlans,
+// and otherwise empty, which OLM treats as Automatic.
+func InstallPlanApproval(instance *kniv1beta1.KNICluster) olm.Approval {
+	if instance.Spec.Policy != nil && instance.Spec.Policy.InstallPlans {
+		return olm.ApprovalManual
+	}
+	return ""
+}
+
+// Subscription returns the Subscription for operator from catalog
+func Subscription(namespace string, catalog kniv1beta1.CatalogSpec, operator kniv1beta1.OperatorSpec, approval olm.Approval) *olm.Subscription {
+	return &olm.Subscription{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: olm.SchemeGroupVersion.String(),
+			Kind:       olm.SubscriptionKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      operator.Name,
+			Namespace: namespace,
+		},
+		Spec: &olm.SubscriptionSpec{
+			Channel:                operator.Channel,
+			Package:                operator.Package,
+			CatalogSource:          catalog.Name,
+			CatalogSourceNamespace: catalog.Namespace,
+			InstallPlanApproval:    approval,
+		},
+	}
+}
+
+// Operand decodes an operand from the KNICluster spec. Operands without a
+// namespace are placed in namespace. Integers are decoded as int64, as they
+// are in objects read from the API server, so that the two can be compared.
+func Operand(namespace string, raw runtime.RawExtension) (*unstructured.Unstructured, error) {
+	operand := &unstructured.Unstructured{}
+	if err := json.Unmarshal(raw.Raw, &operand.Object); err != nil {
+		return nil, err
+	}
+	if operand.GetKind() == "" || operand.GetAPIVersion() == "" {
+		return nil, fmt.Errorf("apiVersion and kind are required")
+	}
+	if operand.GetName() == "" {
+		return nil, fmt.Errorf("metadata.name is required")
+	}
+	if operand.GetNamespace() == "" {
+		operand.SetNamespace(namespace)
+	}
+	return operand, nil
+}
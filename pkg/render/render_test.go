@@ -0,0 +1,248 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	olmv1 "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newTestKNICluster() *kniv1beta1.KNICluster {
+	return &kniv1beta1.KNICluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "example-knicluster", Namespace: "kniops"},
+		Spec: kniv1beta1.KNIClusterSpec{
+			Catalog: kniv1beta1.CatalogSpec{
+				Name:      "demo-catalog",
+				Namespace: "olm",
+				Image:     kniv1beta1.CatalogImageSpec{Repository: "quay.io/mhrivnak/demo-operator-registry"},
+			},
+			Namespaces: []kniv1beta1.NamespaceSpec{{Name: "kni-operators"}},
+			Operators: []kniv1beta1.OperatorSpec{
+				{
+					Name:    "etcd",
+					Package: "etcd",
+					Channel: "singlenamespace-alpha",
+					Catalog: "demo-catalog",
+					Operands: []runtime.RawExtension{
+						{Raw: []byte(`{"apiVersion": "etcd.database.coreos.com/v1beta2", "kind": "EtcdCluster", "metadata": {"name": "example"}, "spec": {"size": 3, "repository": "quay.io/coreos/etcd"}}`)},
+					},
+				},
+				{
+					Name:      "prometheus",
+					Namespace: "kni-operators",
+					Package:   "prometheus",
+					Channel:   "preview",
+					Catalog:   "demo-catalog",
+				},
+			},
+		},
+	}
+}
+
+func TestObjects(t *testing.T) {
+	objects, err := Objects(newTestKNICluster(), "4.1.0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, obj := range objects {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, obj.GetObjectKind().GroupVersionKind().Kind+" "+accessor.GetNamespace()+"/"+accessor.GetName())
+	}
+	want := []string{
+		"Namespace /kni-operators",
+		"CatalogSource olm/demo-catalog",
+		"OperatorGroup kniops/kni",
+		"OperatorGroup kni-operators/kni",
+		"Subscription kniops/etcd",
+		"Subscription kni-operators/prometheus",
+		"EtcdCluster kniops/example",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got objects\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestObjectsRequiresNamespace(t *testing.T) {
+	instance := newTestKNICluster()
+	instance.Namespace = ""
+	if _, err := Objects(instance, "4.1.0", nil); err == nil {
+		t.Error("rendered a KNICluster without a namespace")
+	}
+}
+
+func TestObjectsMirrorsCatalogImage(t *testing.T) {
+	policyMirrors := []kniv1beta1.ImageMirror{{Source: "quay.io/mhrivnak", Mirror: "mirror.example.com/mhrivnak"}}
+	objects, err := Objects(newTestKNICluster(), "4.1.0", policyMirrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range objects {
+		if catalogSource, ok := obj.(*olm.CatalogSource); ok {
+			if want := "mirror.example.com/mhrivnak/demo-operator-registry:4.1.0"; catalogSource.Spec.Image != want {
+				t.Errorf("catalog image is %s, want %s", catalogSource.Spec.Image, want)
+			}
+			return
+		}
+	}
+	t.Error("no CatalogSource")
+}
+
+func TestObjectsOwnerLabels(t *testing.T) {
+	objects, err := Objects(newTestKNICluster(), "4.1.0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range objects {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// objects in the KNICluster's namespace get owner references instead
+		want := accessor.GetNamespace() != "kniops"
+		labels := accessor.GetLabels()
+		labelled := labels[kniv1beta1.OwnerNameLabel] == "example-knicluster" && labels[kniv1beta1.OwnerNamespaceLabel] == "kniops"
+		if labelled != want {
+			t.Errorf("%s %s/%s has labels %v, want owner labels %t", obj.GetObjectKind().GroupVersionKind().Kind, accessor.GetNamespace(), accessor.GetName(), labels, want)
+		}
+	}
+}
+
+func TestObjectsDoesNotPinDigest(t *testing.T) {
+	instance := newTestKNICluster()
+	instance.Spec.Catalog.Image.PinDigest = true
+	objects, err := Objects(instance, "4.1.0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range objects {
+		if catalogSource, ok := obj.(*olm.CatalogSource); ok {
+			// the operator resolves the tag through the registry when it applies
+			if want := "quay.io/mhrivnak/demo-operator-registry:4.1.0"; catalogSource.Spec.Image != want {
+				t.Errorf("catalog image is %s, want the tag %s", catalogSource.Spec.Image, want)
+			}
+			return
+		}
+	}
+	t.Error("no CatalogSource")
+}
+
+func TestCatalogSource(t *testing.T) {
+	catalog := newTestKNICluster().Spec.Catalog
+	catalogSource := CatalogSource(catalog, "4.1.0")
+	if catalogSource.Spec.SourceType != olm.SourceTypeGrpc || catalogSource.Spec.Image != "quay.io/mhrivnak/demo-operator-registry:4.1.0" {
+		t.Errorf("image catalog has source type %s and image %s", catalogSource.Spec.SourceType, catalogSource.Spec.Image)
+	}
+	if catalogSource.Labels[kniv1beta1.CatalogVersionLabel] != "4.1.0" {
+		t.Errorf("catalog version label is %q", catalogSource.Labels[kniv1beta1.CatalogVersionLabel])
+	}
+
+	catalog.Type = kniv1beta1.CatalogTypeConfigMap
+	catalogSource = CatalogSource(catalog, "4.1.0")
+	if catalogSource.Spec.SourceType != olm.SourceTypeConfigmap || catalogSource.Spec.ConfigMap != catalog.Name || catalogSource.Spec.Image != "" {
+		t.Errorf("ConfigMap catalog has source type %s, ConfigMap %s and image %s", catalogSource.Spec.SourceType, catalogSource.Spec.ConfigMap, catalogSource.Spec.Image)
+	}
+}
+
+func TestOperatorGroup(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"kni": "true"}}
+	for name, tc := range map[string]struct {
+		config *kniv1beta1.OperatorGroupSpec
+		want   olmv1.OperatorGroupSpec
+	}{
+		"own namespace": {
+			want: olmv1.OperatorGroupSpec{TargetNamespaces: []string{"kniops"}},
+		},
+		"all namespaces": {
+			config: &kniv1beta1.OperatorGroupSpec{Namespace: "kniops", AllNamespaces: true},
+			want:   olmv1.OperatorGroupSpec{},
+		},
+		"target namespaces": {
+			config: &kniv1beta1.OperatorGroupSpec{Namespace: "kniops", TargetNamespaces: []string{"a", "b"}},
+			want:   olmv1.OperatorGroupSpec{TargetNamespaces: []string{"a", "b"}},
+		},
+		"selector": {
+			config: &kniv1beta1.OperatorGroupSpec{Namespace: "kniops", Selector: selector},
+			want:   olmv1.OperatorGroupSpec{Selector: selector},
+		},
+	} {
+		operatorGroup := OperatorGroup("kniops", tc.config)
+		if !reflect.DeepEqual(operatorGroup.Spec, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", name, operatorGroup.Spec, tc.want)
+		}
+	}
+}
+
+func TestInstallPlanApproval(t *testing.T) {
+	instance := newTestKNICluster()
+	if approval := InstallPlanApproval(instance); approval != "" {
+		t.Errorf("approval without a policy is %q", approval)
+	}
+	instance.Spec.Policy = &kniv1beta1.PolicySpec{InstallPlans: true}
+	if approval := InstallPlanApproval(instance); approval != olm.ApprovalManual {
+		t.Errorf("approval with an InstallPlan policy is %q, want Manual", approval)
+	}
+}
+
+func TestOperandDecodesInt64(t *testing.T) {
+	operand, err := Operand("kniops", runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "Example", "metadata": {"name": "example"}, "spec": {"size": 3, "ratio": 0.5}}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := operand.Object["spec"].(map[string]interface{})
+	// objects read from the API server hold int64, so a float64 would
+	// always look changed
+	if size, ok := spec["size"].(int64); !ok || size != 3 {
+		t.Errorf("size is %#v, want int64 3", spec["size"])
+	}
+	if ratio, ok := spec["ratio"].(float64); !ok || ratio != 0.5 {
+		t.Errorf("ratio is %#v, want float64 0.5", spec["ratio"])
+	}
+	if operand.GetNamespace() != "kniops" {
+		t.Errorf("namespace is %q, want kniops", operand.GetNamespace())
+	}
+}
+
+func TestOperandKeepsNamespace(t *testing.T) {
+	operand, err := Operand("kniops", runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "Example", "metadata": {"name": "example", "namespace": "other"}}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if operand.GetNamespace() != "other" {
+		t.Errorf("namespace is %q, want other", operand.GetNamespace())
+	}
+}
+
+func TestOperandInvalid(t *testing.T) {
+	for name, raw := range map[string]string{
+		"not json":   `{`,
+		"no kind":    `{"apiVersion": "v1", "metadata": {"name": "example"}}`,
+		"no version": `{"kind": "Example", "metadata": {"name": "example"}}`,
+		"no name":    `{"apiVersion": "v1", "kind": "Example"}`,
+	} {
+		if _, err := Operand("kniops", runtime.RawExtension{Raw: []byte(raw)}); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	namespace := Namespace(kniv1beta1.NamespaceSpec{Name: "kni", Labels: map[string]string{"a": "b"}})
+	want := &corev1.Namespace{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{Name: "kni", Labels: map[string]string{"a": "b"}},
+	}
+	if !reflect.DeepEqual(namespace, want) {
+		t.Errorf("got %+v, want %+v", namespace, want)
+	}
+}
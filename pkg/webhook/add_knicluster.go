@@ -0,0 +1,11 @@
+package webhook
+
+import (
+	"github.com/mhrivnak/kni-operator/pkg/webhook/knicluster"
+)
+
+func init() {
+	// WebhookFuncs is a list of functions to create webhooks and add them to a server.
+	WebhookFuncs = append(WebhookFuncs, knicluster.NewMutatingWebhook, knicluster.NewValidatingWebhook)
+	ConversionFuncs = append(ConversionFuncs, knicluster.NewConversionWebhook)
+}
@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/cert"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	certFileName = "tls.crt"
+	keyFileName  = "tls.key"
+	caFileName   = "ca.crt"
+
+	// certRenewBefore is how long before it expires a generated certificate
+	// is replaced
+	certRenewBefore = 30 * 24 * time.Hour
+)
+
+// servingCertificate is a serving certificate and key, and the CA bundle that
+// clients should use to verify them. A nil CA bundle means that a certificate
+// was provided without a ca.crt, in which case the bundle is expected to be
+// managed by whoever provided the certificate.
+type servingCertificate struct {
+	certPEM  []byte
+	keyPEM   []byte
+	caBundle []byte
+}
+
+// loadCertificate returns the serving certificate and key in dir, or nil if
+// dir does not contain them
+func loadCertificate(dir string) (*servingCertificate, error) {
+	exists, err := cert.CanReadCertAndKey(certPath(dir), keyPath(dir))
+	if err != nil || !exists {
+		return nil, err
+	}
+	serving := &servingCertificate{}
+	if serving.certPEM, err = ioutil.ReadFile(certPath(dir)); err != nil {
+		return nil, err
+	}
+	if serving.keyPEM, err = ioutil.ReadFile(keyPath(dir)); err != nil {
+		return nil, err
+	}
+	serving.caBundle, err = ioutil.ReadFile(filepath.Join(dir, caFileName))
+	if os.IsNotExist(err) {
+		return serving, nil
+	}
+	return serving, err
+}
+
+// ensureCertificateSecret returns the self-signed serving certificate kept in
+// the Secret key, so that every start of the operator serves the same one and
+// the CA bundles registered with the API server stay valid. A certificate is
+// generated for dnsNames, and stored, when the Secret does not exist, or its
+// certificate does not cover dnsNames or is about to expire.
+func ensureCertificateSecret(c client.Client, key types.NamespacedName, dnsNames []string) (*servingCertificate, error) {
+	found := &corev1.Secret{}
+	err := c.Get(context.TODO(), key, found)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	exists := err == nil
+	if exists && certificateValid(found.Data[corev1.TLSCertKey], dnsNames) {
+		return secretCertificate(found), nil
+	}
+
+	if len(dnsNames) == 0 {
+		return nil, fmt.Errorf("at least one DNS name is required to generate a certificate")
+	}
+	log.Info("Generating a self-signed certificate", "Secret.Namespace", key.Namespace, "Secret.Name", key.Name)
+	certPEM, keyPEM, err := cert.GenerateSelfSignedCertKey(dnsNames[0], nil, dnsNames[1:])
+	if err != nil {
+		return nil, err
+	}
+	data := map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+	}
+
+	if exists {
+		found.Data = data
+		if err := c.Update(context.TODO(), found); err != nil {
+			return nil, err
+		}
+		return secretCertificate(found), nil
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+		Type:       corev1.SecretTypeTLS,
+		Data:       data,
+	}
+	err = c.Create(context.TODO(), secret)
+	if errors.IsAlreadyExists(err) {
+		// another replica stored one first
+		if err := c.Get(context.TODO(), key, found); err != nil {
+			return nil, err
+		}
+		return secretCertificate(found), nil
+	} else if err != nil {
+		return nil, err
+	}
+	return secretCertificate(secret), nil
+}
+
+// secretCertificate returns the certificate stored in secret. The generated
+// certificate contains the serving certificate followed by the CA that
+// signed it, so it is also the CA bundle.
+func secretCertificate(secret *corev1.Secret) *servingCertificate {
+	return &servingCertificate{
+		certPEM:  secret.Data[corev1.TLSCertKey],
+		keyPEM:   secret.Data[corev1.TLSPrivateKeyKey],
+		caBundle: secret.Data[corev1.TLSCertKey],
+	}
+}
+
+// certificateValid returns whether the serving certificate in certPEM is
+// valid for every name in dnsNames, and does not expire within
+// certRenewBefore
+func certificateValid(certPEM []byte, dnsNames []string) bool {
+	certs, err := cert.ParseCertsPEM(certPEM)
+	if err != nil || len(certs) == 0 {
+		return false
+	}
+	serving := certs[0]
+	if time.Now().Add(certRenewBefore).After(serving.NotAfter) {
+		return false
+	}
+	for _, name := range dnsNames {
+		if serving.VerifyHostname(name) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func certPath(dir string) string {
+	return filepath.Join(dir, certFileName)
+}
+
+func keyPath(dir string) string {
+	return filepath.Join(dir, keyFileName)
+}
@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/cert"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	testSecretKey = types.NamespacedName{Namespace: "kniops", Name: "kni-operator-webhook-cert"}
+	testDNSNames  = []string{"kni-operator-webhook.kniops.svc", "kni-operator-webhook.kniops.svc.cluster.local"}
+)
+
+// objectClient is a client that only holds Secrets and an unstructured
+// CustomResourceDefinition, and counts the writes made through it
+type objectClient struct {
+	client.Client
+	secrets map[types.NamespacedName]*corev1.Secret
+	crd     *unstructured.Unstructured
+	writes  int
+}
+
+func newObjectClient() *objectClient {
+	return &objectClient{secrets: map[types.NamespacedName]*corev1.Secret{}}
+}
+
+func (c *objectClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	switch obj := obj.(type) {
+	case *corev1.Secret:
+		secret, ok := c.secrets[key]
+		if !ok {
+			return errors.NewNotFound(corev1.Resource("secrets"), key.Name)
+		}
+		secret.DeepCopyInto(obj)
+	case *unstructured.Unstructured:
+		if c.crd == nil || c.crd.GetName() != key.Name {
+			return errors.NewNotFound(apiextensionsv1beta1.Resource("customresourcedefinitions"), key.Name)
+		}
+		c.crd.DeepCopyInto(obj)
+	}
+	return nil
+}
+
+func (c *objectClient) Create(ctx context.Context, obj runtime.Object) error {
+	secret := obj.(*corev1.Secret)
+	key := types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}
+	if _, ok := c.secrets[key]; ok {
+		return errors.NewAlreadyExists(corev1.Resource("secrets"), key.Name)
+	}
+	c.writes++
+	c.secrets[key] = secret.DeepCopy()
+	return nil
+}
+
+func (c *objectClient) Update(ctx context.Context, obj runtime.Object) error {
+	c.writes++
+	switch obj := obj.(type) {
+	case *corev1.Secret:
+		c.secrets[types.NamespacedName{Namespace: obj.Namespace, Name: obj.Name}] = obj.DeepCopy()
+	case *unstructured.Unstructured:
+		c.crd = obj.DeepCopy()
+	}
+	return nil
+}
+
+func TestEnsureCertificateSecretKeepsCertificate(t *testing.T) {
+	c := newObjectClient()
+
+	generated, err := ensureCertificateSecret(c, testSecretKey, testDNSNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !certificateValid(generated.certPEM, testDNSNames) || !bytes.Equal(generated.caBundle, generated.certPEM) {
+		t.Fatal("generated certificate is not valid for the Service")
+	}
+	secret := c.secrets[testSecretKey]
+	if secret == nil || secret.Type != corev1.SecretTypeTLS || !bytes.Equal(secret.Data[corev1.TLSPrivateKeyKey], generated.keyPEM) {
+		t.Fatalf("Secret is %v, want the generated certificate stored", secret)
+	}
+
+	// a restart serves the same certificate
+	kept, err := ensureCertificateSecret(c, testSecretKey, testDNSNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(kept.certPEM, generated.certPEM) || !bytes.Equal(kept.keyPEM, generated.keyPEM) {
+		t.Error("certificate was generated again")
+	}
+	if c.writes != 1 {
+		t.Errorf("made %d writes, want only the Secret created", c.writes)
+	}
+}
+
+func TestEnsureCertificateSecretReplacesCertificate(t *testing.T) {
+	otherService, _, err := cert.GenerateSelfSignedCertKey("other.kniops.svc", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, certPEM := range map[string][]byte{
+		"other service": otherService,
+		"invalid":       []byte("not a certificate"),
+	} {
+		c := newObjectClient()
+		c.secrets[testSecretKey] = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testSecretKey.Namespace, Name: testSecretKey.Name},
+			Data:       map[string][]byte{corev1.TLSCertKey: certPEM},
+		}
+
+		replaced, err := ensureCertificateSecret(c, testSecretKey, testDNSNames)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !certificateValid(replaced.certPEM, testDNSNames) {
+			t.Errorf("%s: replacement is not valid for the Service", name)
+		}
+		if !bytes.Equal(c.secrets[testSecretKey].Data[corev1.TLSCertKey], replaced.certPEM) {
+			t.Errorf("%s: replacement was not stored", name)
+		}
+	}
+}
+
+func TestLoadCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serving-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	serving, err := loadCertificate(dir)
+	if err != nil || serving != nil {
+		t.Fatalf("loading from an empty directory returned %v, %v, want nothing", serving, err)
+	}
+
+	certPEM, keyPEM, err := cert.GenerateSelfSignedCertKey(testDNSNames[0], nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.WriteCert(certPath(dir), certPEM); err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.WriteKey(keyPath(dir), keyPEM); err != nil {
+		t.Fatal(err)
+	}
+	serving, err = loadCertificate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(serving.certPEM, certPEM) || serving.caBundle != nil {
+		t.Errorf("loaded %v, want the mounted certificate without a CA bundle", serving)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, caFileName), certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	serving, err = loadCertificate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(serving.caBundle, certPEM) {
+		t.Error("mounted CA bundle was not loaded")
+	}
+}
@@ -0,0 +1,73 @@
+package conversion
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("conversion")
+
+// ConvertFunc converts a single object to the desired API version
+type ConvertFunc func(object runtime.RawExtension, desiredAPIVersion string) (runtime.RawExtension, error)
+
+// Webhook serves ConversionReview requests for a CustomResourceDefinition
+type Webhook struct {
+	// CRDName is the name of the CustomResourceDefinition whose conversion
+	// strategy should point to this webhook
+	CRDName string
+	// Path is the path this webhook is served on
+	Path string
+	// Convert converts each object of a ConversionReview
+	Convert ConvertFunc
+}
+
+var _ http.Handler = &Webhook{}
+
+// ServeHTTP handles a ConversionReview
+func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review := &apiextensionsv1beta1.ConversionReview{}
+	if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+		log.Error(err, "Failed to decode ConversionReview")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "ConversionReview has no request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = wh.convert(review.Request)
+	review.Request = nil
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		log.Error(err, "Failed to encode ConversionReview")
+	}
+}
+
+func (wh *Webhook) convert(req *apiextensionsv1beta1.ConversionRequest) *apiextensionsv1beta1.ConversionResponse {
+	resp := &apiextensionsv1beta1.ConversionResponse{
+		UID: req.UID,
+	}
+	for i, object := range req.Objects {
+		converted, err := wh.Convert(object, req.DesiredAPIVersion)
+		if err != nil {
+			log.Error(err, "Failed to convert object", "DesiredAPIVersion", req.DesiredAPIVersion)
+			resp.ConvertedObjects = nil
+			resp.Result = metav1.Status{
+				Status:  metav1.StatusFailure,
+				Message: fmt.Sprintf("failed to convert object %d to %s: %v", i, req.DesiredAPIVersion, err),
+			}
+			return resp
+		}
+		resp.ConvertedObjects = append(resp.ConvertedObjects, converted)
+	}
+	resp.Result = metav1.Status{
+		Status: metav1.StatusSuccess,
+	}
+	return resp
+}
@@ -0,0 +1,57 @@
+package knicluster
+
+import (
+	"encoding/json"
+	"fmt"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	"github.com/mhrivnak/kni-operator/pkg/webhook/conversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// NewConversionWebhook returns a webhook that converts KNIClusters between
+// API versions
+func NewConversionWebhook(mgr manager.Manager) (*conversion.Webhook, error) {
+	return &conversion.Webhook{
+		CRDName: "kniclusters.kni.openshift.com",
+		Path:    "/convert-kniclusters",
+		Convert: convert,
+	}, nil
+}
+
+// convert converts a KNICluster to the desired API version through the hub
+// version
+func convert(object runtime.RawExtension, desiredAPIVersion string) (runtime.RawExtension, error) {
+	typeMeta := metav1.TypeMeta{}
+	if err := json.Unmarshal(object.Raw, &typeMeta); err != nil {
+		return runtime.RawExtension{}, err
+	}
+	gv, err := schema.ParseGroupVersion(typeMeta.APIVersion)
+	if err != nil {
+		return runtime.RawExtension{}, err
+	}
+	desired, err := schema.ParseGroupVersion(desiredAPIVersion)
+	if err != nil {
+		return runtime.RawExtension{}, err
+	}
+	if gv.Group != kniv1beta1.SchemeGroupVersion.Group || desired.Group != gv.Group {
+		return runtime.RawExtension{}, fmt.Errorf("cannot convert %s to %s", typeMeta.APIVersion, desiredAPIVersion)
+	}
+
+	instance, err := decode(object.Raw, metav1.GroupVersionKind{Group: gv.Group, Version: gv.Version, Kind: typeMeta.Kind})
+	if err != nil {
+		return runtime.RawExtension{}, err
+	}
+	out, err := encode(instance, desired.Version)
+	if err != nil {
+		return runtime.RawExtension{}, err
+	}
+	raw, err := json.Marshal(out)
+	if err != nil {
+		return runtime.RawExtension{}, err
+	}
+	return runtime.RawExtension{Raw: raw}, nil
+}
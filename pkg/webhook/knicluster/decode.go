@@ -0,0 +1,64 @@
+package knicluster
+
+import (
+	"encoding/json"
+	"fmt"
+
+	kniv1alpha1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1alpha1"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// servedVersions are the versions of KNICluster the webhooks handle
+var servedVersions = []string{
+	kniv1beta1.SchemeGroupVersion.Version,
+	kniv1alpha1.SchemeGroupVersion.Version,
+}
+
+// rule returns the webhook rule for KNIClusters with the given operations
+func rule(operations ...admissionregistrationv1beta1.OperationType) admissionregistrationv1beta1.RuleWithOperations {
+	return admissionregistrationv1beta1.RuleWithOperations{
+		Operations: operations,
+		Rule: admissionregistrationv1beta1.Rule{
+			APIGroups:   []string{kniv1beta1.SchemeGroupVersion.Group},
+			APIVersions: servedVersions,
+			Resources:   []string{"kniclusters"},
+		},
+	}
+}
+
+// decode returns the hub version of a KNICluster submitted in the version
+// described by gvk
+func decode(raw []byte, gvk metav1.GroupVersionKind) (*kniv1beta1.KNICluster, error) {
+	instance := &kniv1beta1.KNICluster{}
+	switch gvk.Version {
+	case kniv1beta1.SchemeGroupVersion.Version:
+		if err := json.Unmarshal(raw, instance); err != nil {
+			return nil, err
+		}
+		return instance, nil
+	case kniv1alpha1.SchemeGroupVersion.Version:
+		alpha := &kniv1alpha1.KNICluster{}
+		if err := json.Unmarshal(raw, alpha); err != nil {
+			return nil, err
+		}
+		return instance, alpha.ConvertTo(instance)
+	}
+	return nil, fmt.Errorf("unsupported KNICluster version %s", gvk.Version)
+}
+
+// encode returns instance converted to the given version
+func encode(instance *kniv1beta1.KNICluster, version string) (runtime.Object, error) {
+	switch version {
+	case kniv1beta1.SchemeGroupVersion.Version:
+		out := instance.DeepCopy()
+		out.SetGroupVersionKind(kniv1beta1.SchemeGroupVersion.WithKind("KNICluster"))
+		return out, nil
+	case kniv1alpha1.SchemeGroupVersion.Version:
+		out := &kniv1alpha1.KNICluster{}
+		return out, out.ConvertFrom(instance)
+	}
+	return nil, fmt.Errorf("unsupported KNICluster version %s", version)
+}
@@ -0,0 +1,55 @@
+package knicluster
+
+import (
+	"context"
+	"net/http"
+
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	atypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+	webhooktypes "sigs.k8s.io/controller-runtime/pkg/webhook/types"
+)
+
+// NewMutatingWebhook returns a webhook that fills in the defaults of new
+// KNIClusters
+func NewMutatingWebhook(mgr manager.Manager) (*admission.Webhook, error) {
+	failurePolicy := admissionregistrationv1beta1.Fail
+	return &admission.Webhook{
+		Name: "mutating.kniclusters.kni.openshift.com",
+		Type: webhooktypes.WebhookTypeMutating,
+		Path: "/mutate-kniclusters",
+		Rules: []admissionregistrationv1beta1.RuleWithOperations{
+			rule(admissionregistrationv1beta1.Create),
+		},
+		FailurePolicy: &failurePolicy,
+		Handlers: []admission.Handler{
+			&defaulter{},
+		},
+	}, nil
+}
+
+// defaulter sets defaults on KNIClusters
+type defaulter struct{}
+
+var _ admission.Handler = &defaulter{}
+
+// Handle patches a KNICluster with defaults for its omitted fields
+func (d *defaulter) Handle(ctx context.Context, req atypes.Request) atypes.Response {
+	version := req.AdmissionRequest.Kind.Version
+	instance, err := decode(req.AdmissionRequest.Object.Raw, req.AdmissionRequest.Kind)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+	original, err := encode(instance, version)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	instance.SetDefaults()
+	defaulted, err := encode(instance, version)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponse(original, defaulted)
+}
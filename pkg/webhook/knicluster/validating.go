@@ -0,0 +1,139 @@
+package knicluster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	atypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+	webhooktypes "sigs.k8s.io/controller-runtime/pkg/webhook/types"
+)
+
+// NewValidatingWebhook returns a webhook that rejects invalid KNIClusters
+func NewValidatingWebhook(mgr manager.Manager) (*admission.Webhook, error) {
+	failurePolicy := admissionregistrationv1beta1.Fail
+	return &admission.Webhook{
+		Name: "validating.kniclusters.kni.openshift.com",
+		Type: webhooktypes.WebhookTypeValidating,
+		Path: "/validate-kniclusters",
+		Rules: []admissionregistrationv1beta1.RuleWithOperations{
+			rule(admissionregistrationv1beta1.Create, admissionregistrationv1beta1.Update),
+		},
+		FailurePolicy: &failurePolicy,
+		Handlers: []admission.Handler{
+			&validator{},
+		},
+	}, nil
+}
+
+// validator validates KNIClusters
+type validator struct {
+	client client.Client
+}
+
+var _ admission.Handler = &validator{}
+var _ inject.Client = &validator{}
+
+// Handle admits a KNICluster only if it is valid
+func (v *validator) Handle(ctx context.Context, req atypes.Request) atypes.Response {
+	instance, err := decode(req.AdmissionRequest.Object.Raw, req.AdmissionRequest.Kind)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+
+	switch req.AdmissionRequest.Operation {
+	case admissionv1beta1.Create:
+		existing := &kniv1beta1.KNIClusterList{}
+		if err := v.client.List(ctx, &client.ListOptions{}, existing); err != nil {
+			return admission.ErrorResponse(http.StatusInternalServerError, err)
+		}
+		for i := range existing.Items {
+			item := &existing.Items[i]
+			if item.Name == instance.Name && item.Namespace == instance.Namespace {
+				continue
+			}
+			// the OperatorGroup of a namespace can only have one owner
+			if item.Namespace == instance.Namespace {
+				return admission.ValidationResponse(false, fmt.Sprintf(
+					"KNICluster %s already exists in namespace %s; only one is allowed per namespace", item.Name, item.Namespace))
+			}
+			if effectiveCatalogNamespace(item) == effectiveCatalogNamespace(instance) && effectiveCatalogName(item) == effectiveCatalogName(instance) {
+				return admission.ValidationResponse(false, fmt.Sprintf(
+					"the catalog %s/%s is already managed by KNICluster %s/%s", effectiveCatalogNamespace(instance), effectiveCatalogName(instance),
+					item.Namespace, item.Name))
+			}
+		}
+
+		if errs := validateKNICluster(instance); len(errs) > 0 {
+			return admission.ValidationResponse(false, errs.ToAggregate().Error())
+		}
+	case admissionv1beta1.Update:
+		// never block finalizer removal
+		if instance.DeletionTimestamp != nil {
+			break
+		}
+		old, err := decode(req.AdmissionRequest.OldObject.Raw, req.AdmissionRequest.Kind)
+		if err != nil {
+			return admission.ErrorResponse(http.StatusBadRequest, err)
+		}
+		if errs := validateKNIClusterUpdate(instance, old); len(errs) > 0 {
+			return admission.ValidationResponse(false, errs.ToAggregate().Error())
+		}
+
+		// KNIClusters created at the same time, or while the webhook was
+		// down, can share a catalog; only the one managing it can be updated
+		manager, err := v.catalogManager(ctx, instance)
+		if err != nil {
+			return admission.ErrorResponse(http.StatusInternalServerError, err)
+		}
+		if manager != "" {
+			return admission.ValidationResponse(false, fmt.Sprintf(
+				"the catalog %s/%s is already managed by %s", effectiveCatalogNamespace(instance), effectiveCatalogName(instance), manager))
+		}
+	}
+
+	return admission.ValidationResponse(true, "")
+}
+
+// catalogManager describes the other KNICluster that manages the CatalogSource
+// of instance, from its controller reference or its owner labels, or returns
+// an empty string if there is none
+func (v *validator) catalogManager(ctx context.Context, instance *kniv1beta1.KNICluster) (string, error) {
+	catalogSource := &olm.CatalogSource{}
+	err := v.client.Get(ctx, types.NamespacedName{Namespace: effectiveCatalogNamespace(instance), Name: effectiveCatalogName(instance)}, catalogSource)
+	if errors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	if owner := metav1.GetControllerOf(catalogSource); owner != nil {
+		if owner.Kind != "KNICluster" || owner.UID == instance.UID {
+			return "", nil
+		}
+		return fmt.Sprintf("KNICluster %s/%s", catalogSource.Namespace, owner.Name), nil
+	}
+	labels := catalogSource.Labels
+	name, namespace := labels[kniv1beta1.OwnerNameLabel], labels[kniv1beta1.OwnerNamespaceLabel]
+	if name == "" || (name == instance.Name && namespace == instance.Namespace) {
+		return "", nil
+	}
+	return fmt.Sprintf("KNICluster %s/%s", namespace, name), nil
+}
+
+// InjectClient injects the client
+func (v *validator) InjectClient(c client.Client) error {
+	v.client = c
+	return nil
+}
@@ -0,0 +1,462 @@
+package knicluster
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// channelRegexp matches valid catalog channel names such as "stable" or
+// "singlenamespace-alpha"
+var channelRegexp = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`)
+
+// validateKNICluster validates the spec of a KNICluster
+func validateKNICluster(instance *kniv1beta1.KNICluster) field.ErrorList {
+	specPath := field.NewPath("spec")
+	allErrs := validateCatalog(instance.Spec.Catalog, specPath.Child("catalog"))
+	allErrs = append(allErrs, validateOperators(instance.Spec.Operators, effectiveCatalogName(instance), specPath.Child("operators"))...)
+	allErrs = append(allErrs, validateNamespaces(instance.Spec.Namespaces, specPath.Child("namespaces"))...)
+	switch instance.Spec.NamespaceDeletionPolicy {
+	case "", kniv1beta1.NamespaceDeletionRetain, kniv1beta1.NamespaceDeletionDelete:
+	default:
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("namespaceDeletionPolicy"), instance.Spec.NamespaceDeletionPolicy,
+			[]string{string(kniv1beta1.NamespaceDeletionRetain), string(kniv1beta1.NamespaceDeletionDelete)}))
+	}
+	allErrs = append(allErrs, validateOperatorGroups(instance.Spec.OperatorGroups, specPath.Child("operatorGroups"))...)
+	if adoption := instance.Spec.Adoption; adoption != nil {
+		adoptionPath := specPath.Child("adoption")
+		allErrs = append(allErrs, validateAdoptionPolicy(adoption.Subscriptions, adoptionPath.Child("subscriptions"))...)
+		allErrs = append(allErrs, validateAdoptionPolicy(adoption.OperatorGroups, adoptionPath.Child("operatorGroups"))...)
+		allErrs = append(allErrs, validateAdoptionPolicy(adoption.CatalogSources, adoptionPath.Child("catalogSources"))...)
+	}
+	allErrs = append(allErrs, validateImageMirrors(instance.Spec.ImageMirrors, specPath.Child("imageMirrors"))...)
+	switch instance.Spec.Approval {
+	case "", kniv1beta1.ApprovalAutomatic, kniv1beta1.ApprovalManual:
+	default:
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("approval"), instance.Spec.Approval,
+			[]string{string(kniv1beta1.ApprovalAutomatic), string(kniv1beta1.ApprovalManual)}))
+	}
+	allErrs = append(allErrs, validateNotifications(instance.Spec.Notifications, specPath.Child("notifications"))...)
+	if instance.Spec.Policy != nil {
+		allErrs = append(allErrs, validatePolicy(instance.Spec.Policy, specPath.Child("policy"))...)
+	}
+	if instance.Spec.Hooks != nil {
+		allErrs = append(allErrs, validateHooks(instance.Spec.Hooks, specPath.Child("hooks"))...)
+	}
+	switch instance.Spec.Mode {
+	case "", kniv1beta1.ModeApply, kniv1beta1.ModePlan:
+	default:
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("mode"), instance.Spec.Mode,
+			[]string{string(kniv1beta1.ModeApply), string(kniv1beta1.ModePlan)}))
+	}
+	return allErrs
+}
+
+// validateKNIClusterUpdate validates a KNICluster and the transition from old
+func validateKNIClusterUpdate(instance, old *kniv1beta1.KNICluster) field.ErrorList {
+	allErrs := validateKNICluster(instance)
+
+	catalogPath := field.NewPath("spec", "catalog")
+	if effectiveCatalogNamespace(instance) != effectiveCatalogNamespace(old) {
+		allErrs = append(allErrs, field.Forbidden(catalogPath.Child("namespace"), "the catalog namespace cannot be changed after creation"))
+	}
+	if effectiveCatalogName(instance) != effectiveCatalogName(old) {
+		allErrs = append(allErrs, field.Forbidden(catalogPath.Child("name"), "the catalog name cannot be changed after creation"))
+	}
+
+	oldPackages := map[string]string{}
+	for _, operator := range old.Spec.Operators {
+		oldPackages[operator.Name] = operator.Package
+	}
+	operatorsPath := field.NewPath("spec", "operators")
+	for i, operator := range instance.Spec.Operators {
+		if pkg, ok := oldPackages[operator.Name]; ok && pkg != operator.Package {
+			allErrs = append(allErrs, field.Forbidden(operatorsPath.Index(i).Child("package"),
+				"the package of an existing operator cannot be changed; remove it and add a new operator instead"))
+		}
+	}
+	return allErrs
+}
+
+func validateCatalog(catalog kniv1beta1.CatalogSpec, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if catalog.Name != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(catalog.Name) {
+			allErrs = append(allErrs, field.Invalid(path.Child("name"), catalog.Name, msg))
+		}
+	}
+	if catalog.Namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(catalog.Namespace) {
+			allErrs = append(allErrs, field.Invalid(path.Child("namespace"), catalog.Namespace, msg))
+		}
+	}
+	switch catalog.Type {
+	case "", kniv1beta1.CatalogTypeImage, kniv1beta1.CatalogTypeConfigMap:
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("type"), catalog.Type,
+			[]string{string(kniv1beta1.CatalogTypeImage), string(kniv1beta1.CatalogTypeConfigMap)}))
+	}
+	if interval := catalog.Image.ResolveInterval; interval != nil {
+		switch {
+		case !catalog.Image.PinDigest && catalog.Image.Verification == nil:
+			allErrs = append(allErrs, field.Forbidden(path.Child("image", "resolveInterval"), "only applies when pinDigest or verification is set"))
+		case interval.Duration < time.Minute:
+			allErrs = append(allErrs, field.Invalid(path.Child("image", "resolveInterval"), interval.Duration.String(), "must be at least one minute"))
+		}
+	}
+	if timeout := catalog.Image.PrePullTimeout; timeout != nil && timeout.Duration < time.Minute {
+		allErrs = append(allErrs, field.Invalid(path.Child("image", "prePullTimeout"), timeout.Duration.String(), "must be at least one minute"))
+	}
+	for key, value := range catalog.Image.PrePullNodeSelector {
+		for _, msg := range validation.IsQualifiedName(key) {
+			allErrs = append(allErrs, field.Invalid(path.Child("image", "prePullNodeSelector"), key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			allErrs = append(allErrs, field.Invalid(path.Child("image", "prePullNodeSelector").Key(key), value, msg))
+		}
+	}
+	if pullSecret := catalog.Image.PullSecret; pullSecret != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(pullSecret) {
+			allErrs = append(allErrs, field.Invalid(path.Child("image", "pullSecret"), pullSecret, msg))
+		}
+	}
+	if verification := catalog.Image.Verification; verification != nil {
+		secretPath := path.Child("image", "verification", "publicKeysSecret")
+		if verification.PublicKeysSecret == "" {
+			allErrs = append(allErrs, field.Required(secretPath, ""))
+		} else {
+			for _, msg := range validation.IsDNS1123Subdomain(verification.PublicKeysSecret) {
+				allErrs = append(allErrs, field.Invalid(secretPath, verification.PublicKeysSecret, msg))
+			}
+		}
+	}
+	if dir := catalog.ConfigMap.Directory; dir != "" && !filepath.IsAbs(dir) {
+		allErrs = append(allErrs, field.Invalid(path.Child("configMap", "directory"), dir, "must be an absolute path"))
+	}
+	if repo := catalog.Image.Repository; repo != "" {
+		lastComponent := repo[strings.LastIndex(repo, "/")+1:]
+		switch {
+		case strings.ContainsAny(repo, " \t\n"):
+			allErrs = append(allErrs, field.Invalid(path.Child("image", "repository"), repo, "must not contain whitespace"))
+		case strings.Contains(repo, "@") || strings.Contains(lastComponent, ":"):
+			allErrs = append(allErrs, field.Invalid(path.Child("image", "repository"), repo, "must not include a tag or digest"))
+		}
+	}
+	return allErrs
+}
+
+// notificationEvents are the events a notification can send
+var notificationEvents = []string{
+	string(kniv1beta1.EventUpgradeStarted),
+	string(kniv1beta1.EventUpgradeCompleted),
+	string(kniv1beta1.EventRolledBack),
+	string(kniv1beta1.EventDegraded),
+}
+
+func validateNotifications(notifications []kniv1beta1.NotificationSpec, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	names := map[string]bool{}
+	for i, notification := range notifications {
+		idxPath := path.Index(i)
+		if notification.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		} else if names[notification.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), notification.Name))
+		}
+		names[notification.Name] = true
+
+		switch notification.Format {
+		case "", kniv1beta1.NotificationFormatWebhook, kniv1beta1.NotificationFormatSlack:
+		default:
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("format"), notification.Format,
+				[]string{string(kniv1beta1.NotificationFormatWebhook), string(kniv1beta1.NotificationFormatSlack)}))
+		}
+
+		if notification.URLSecret == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("urlSecret"), ""))
+		} else {
+			for _, msg := range validation.IsDNS1123Subdomain(notification.URLSecret) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("urlSecret"), notification.URLSecret, msg))
+			}
+		}
+
+		for j, event := range notification.Events {
+			if !containsEvent(notificationEvents, string(event)) {
+				allErrs = append(allErrs, field.NotSupported(idxPath.Child("events").Index(j), event, notificationEvents))
+			}
+		}
+	}
+	return allErrs
+}
+
+func containsEvent(events []string, event string) bool {
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func validatePolicy(policy *kniv1beta1.PolicySpec, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if policy.URL == "" {
+		allErrs = append(allErrs, field.Required(path.Child("url"), ""))
+	} else if u, err := url.Parse(policy.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		allErrs = append(allErrs, field.Invalid(path.Child("url"), policy.URL, "must be an absolute http or https URL"))
+	}
+	if policy.AuthSecret != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(policy.AuthSecret) {
+			allErrs = append(allErrs, field.Invalid(path.Child("authSecret"), policy.AuthSecret, msg))
+		}
+	}
+	if policy.Timeout != nil && policy.Timeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("timeout"), policy.Timeout.Duration.String(), "must be positive"))
+	}
+	if policy.DecisionTTL != nil && policy.DecisionTTL.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("decisionTTL"), policy.DecisionTTL.Duration.String(), "must be positive"))
+	}
+	return allErrs
+}
+
+// maxHookNameLength leaves room in the Job name for the phase and a hash
+const maxHookNameLength = 40
+
+func validateHooks(hooks *kniv1beta1.HooksSpec, path *field.Path) field.ErrorList {
+	allErrs := validateHookList(hooks.PreSwitch, path.Child("preSwitch"))
+	allErrs = append(allErrs, validateHookList(hooks.PostSwitch, path.Child("postSwitch"))...)
+	switch hooks.PostSwitchFailurePolicy {
+	case "", kniv1beta1.HookFailureDegrade, kniv1beta1.HookFailureRollback:
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("postSwitchFailurePolicy"), hooks.PostSwitchFailurePolicy,
+			[]string{string(kniv1beta1.HookFailureDegrade), string(kniv1beta1.HookFailureRollback)}))
+	}
+	return allErrs
+}
+
+func validateHookList(hooks []kniv1beta1.HookSpec, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	names := map[string]bool{}
+	for i, hook := range hooks {
+		idxPath := path.Index(i)
+		switch {
+		case hook.Name == "":
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		case len(hook.Name) > maxHookNameLength:
+			allErrs = append(allErrs, field.TooLong(idxPath.Child("name"), hook.Name, maxHookNameLength))
+		default:
+			for _, msg := range validation.IsDNS1123Label(hook.Name) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), hook.Name, msg))
+			}
+		}
+		if names[hook.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), hook.Name))
+		}
+		names[hook.Name] = true
+		if hook.Image == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("image"), ""))
+		}
+	}
+	return allErrs
+}
+
+func validateNamespaces(namespaces []kniv1beta1.NamespaceSpec, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	names := map[string]bool{}
+	for i, namespace := range namespaces {
+		idxPath := path.Index(i)
+		if namespace.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(namespace.Name) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), namespace.Name, msg))
+			}
+		}
+		if names[namespace.Name] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), namespace.Name))
+		}
+		names[namespace.Name] = true
+
+		for key, value := range namespace.Labels {
+			for _, msg := range validation.IsQualifiedName(key) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("labels"), key, msg))
+			}
+			for _, msg := range validation.IsValidLabelValue(value) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("labels").Key(key), value, msg))
+			}
+			if key == kniv1beta1.OwnerNameLabel || key == kniv1beta1.OwnerNamespaceLabel {
+				allErrs = append(allErrs, field.Forbidden(idxPath.Child("labels").Key(key), "the label is set by the operator"))
+			}
+		}
+		for key := range namespace.Annotations {
+			for _, msg := range validation.IsQualifiedName(strings.ToLower(key)) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("annotations"), key, msg))
+			}
+		}
+	}
+	return allErrs
+}
+
+func validateAdoptionPolicy(policy kniv1beta1.AdoptionPolicy, path *field.Path) field.ErrorList {
+	switch policy {
+	case "", kniv1beta1.AdoptionAdopt, kniv1beta1.AdoptionIgnore, kniv1beta1.AdoptionFail:
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(path, policy,
+		[]string{string(kniv1beta1.AdoptionAdopt), string(kniv1beta1.AdoptionIgnore), string(kniv1beta1.AdoptionFail)})}
+}
+
+func validateOperatorGroups(operatorGroups []kniv1beta1.OperatorGroupSpec, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	namespaces := map[string]bool{}
+	for i, operatorGroup := range operatorGroups {
+		idxPath := path.Index(i)
+		if operatorGroup.Namespace == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("namespace"), ""))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(operatorGroup.Namespace) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("namespace"), operatorGroup.Namespace, msg))
+			}
+		}
+		if namespaces[operatorGroup.Namespace] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("namespace"), operatorGroup.Namespace))
+		}
+		namespaces[operatorGroup.Namespace] = true
+
+		targets := 0
+		if operatorGroup.AllNamespaces {
+			targets++
+		}
+		if len(operatorGroup.TargetNamespaces) > 0 {
+			targets++
+		}
+		if operatorGroup.Selector != nil {
+			targets++
+			if _, err := metav1.LabelSelectorAsSelector(operatorGroup.Selector); err != nil {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("selector"), operatorGroup.Selector, err.Error()))
+			}
+		}
+		if targets > 1 {
+			allErrs = append(allErrs, field.Invalid(idxPath, operatorGroup.Namespace, "at most one of allNamespaces, targetNamespaces and selector may be set"))
+		}
+		for j, namespace := range operatorGroup.TargetNamespaces {
+			for _, msg := range validation.IsDNS1123Label(namespace) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("targetNamespaces").Index(j), namespace, msg))
+			}
+		}
+	}
+	return allErrs
+}
+
+func validateImageMirrors(mirrors []kniv1beta1.ImageMirror, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	sources := map[string]bool{}
+	for i, mirror := range mirrors {
+		idxPath := path.Index(i)
+		allErrs = append(allErrs, validateMirrorLocation(mirror.Source, idxPath.Child("source"))...)
+		allErrs = append(allErrs, validateMirrorLocation(mirror.Mirror, idxPath.Child("mirror"))...)
+		if sources[mirror.Source] {
+			allErrs = append(allErrs, field.Duplicate(idxPath.Child("source"), mirror.Source))
+		}
+		sources[mirror.Source] = true
+	}
+	return allErrs
+}
+
+// validateMirrorLocation validates a repository or registry of an ImageMirror
+func validateMirrorLocation(location string, path *field.Path) field.ErrorList {
+	switch {
+	case location == "":
+		return field.ErrorList{field.Required(path, "")}
+	case strings.ContainsAny(location, " \t\n@"):
+		return field.ErrorList{field.Invalid(path, location, "must be a repository or registry without a digest")}
+	}
+	return nil
+}
+
+func validateOperators(operators []kniv1beta1.OperatorSpec, catalogName string, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	names := map[string]bool{}
+	for i, operator := range operators {
+		idxPath := path.Index(i)
+
+		if operator.Name == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		} else {
+			for _, msg := range validation.IsDNS1123Subdomain(operator.Name) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), operator.Name, msg))
+			}
+			if names[operator.Name] {
+				allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), operator.Name))
+			}
+			names[operator.Name] = true
+		}
+
+		if operator.Package == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("package"), ""))
+		}
+
+		if operator.Channel == "" {
+			allErrs = append(allErrs, field.Required(idxPath.Child("channel"), ""))
+		} else if !channelRegexp.MatchString(operator.Channel) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("channel"), operator.Channel,
+				"must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character"))
+		}
+
+		if operator.Namespace != "" {
+			for _, msg := range validation.IsDNS1123Label(operator.Namespace) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("namespace"), operator.Namespace, msg))
+			}
+		}
+
+		if operator.Catalog != "" && operator.Catalog != catalogName {
+			allErrs = append(allErrs, field.NotFound(idxPath.Child("catalog"), operator.Catalog))
+		}
+
+		for j, raw := range operator.Operands {
+			allErrs = append(allErrs, validateOperand(raw.Raw, idxPath.Child("operands").Index(j))...)
+		}
+	}
+	return allErrs
+}
+
+func validateOperand(raw []byte, path *field.Path) field.ErrorList {
+	operand := &unstructured.Unstructured{}
+	if err := json.Unmarshal(raw, &operand.Object); err != nil {
+		return field.ErrorList{field.Invalid(path, string(raw), err.Error())}
+	}
+	allErrs := field.ErrorList{}
+	if operand.GetAPIVersion() == "" {
+		allErrs = append(allErrs, field.Required(path.Child("apiVersion"), ""))
+	}
+	if operand.GetKind() == "" {
+		allErrs = append(allErrs, field.Required(path.Child("kind"), ""))
+	}
+	if operand.GetName() == "" {
+		allErrs = append(allErrs, field.Required(path.Child("metadata", "name"), ""))
+	}
+	return allErrs
+}
+
+func effectiveCatalogName(instance *kniv1beta1.KNICluster) string {
+	if instance.Spec.Catalog.Name == "" {
+		return kniv1beta1.CatalogNameDefault
+	}
+	return instance.Spec.Catalog.Name
+}
+
+func effectiveCatalogNamespace(instance *kniv1beta1.KNICluster) string {
+	if instance.Spec.Catalog.Namespace == "" {
+		return kniv1beta1.CatalogNamespaceDefault
+	}
+	return instance.Spec.Catalog.Namespace
+}
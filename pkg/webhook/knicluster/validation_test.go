@@ -0,0 +1,137 @@
+package knicluster
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func newTestKNICluster() *kniv1beta1.KNICluster {
+	return &kniv1beta1.KNICluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "example-knicluster", Namespace: "kniops"},
+		Spec: kniv1beta1.KNIClusterSpec{
+			Catalog: kniv1beta1.CatalogSpec{
+				Name:      "demo-catalog",
+				Namespace: "olm",
+				Image:     kniv1beta1.CatalogImageSpec{Repository: "quay.io/mhrivnak/demo-operator-registry"},
+			},
+			Operators: []kniv1beta1.OperatorSpec{
+				{Name: "etcd", Package: "etcd", Channel: "singlenamespace-alpha", Catalog: "demo-catalog"},
+			},
+		},
+	}
+}
+
+// errorFields returns the fields of errs
+func errorFields(errs field.ErrorList) []string {
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	return fields
+}
+
+func TestValidateKNICluster(t *testing.T) {
+	for _, instance := range []*kniv1beta1.KNICluster{
+		newTestKNICluster(),
+		{ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "kniops"}},
+	} {
+		if errs := validateKNICluster(instance); len(errs) > 0 {
+			t.Errorf("%s is invalid: %v", instance.Name, errs)
+		}
+		defaulted := instance.DeepCopy()
+		defaulted.SetDefaults()
+		if errs := validateKNICluster(defaulted); len(errs) > 0 {
+			t.Errorf("defaulted %s is invalid: %v", instance.Name, errs)
+		}
+	}
+}
+
+func TestValidateKNIClusterInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		field  string
+		modify func(*kniv1beta1.KNICluster)
+	}{
+		{"spec.catalog.name", func(k *kniv1beta1.KNICluster) { k.Spec.Catalog.Name = "Demo_Catalog" }},
+		{"spec.catalog.namespace", func(k *kniv1beta1.KNICluster) { k.Spec.Catalog.Namespace = "olm.system" }},
+		{"spec.catalog.type", func(k *kniv1beta1.KNICluster) { k.Spec.Catalog.Type = "Git" }},
+		{"spec.catalog.image.repository", func(k *kniv1beta1.KNICluster) { k.Spec.Catalog.Image.Repository += ":1.0" }},
+		{"spec.catalog.image.pullSecret", func(k *kniv1beta1.KNICluster) { k.Spec.Catalog.Image.PullSecret = "Pull_Secret" }},
+		{"spec.catalog.image.resolveInterval", func(k *kniv1beta1.KNICluster) {
+			k.Spec.Catalog.Image.ResolveInterval = &metav1.Duration{Duration: time.Hour}
+		}},
+		{"spec.catalog.image.resolveInterval", func(k *kniv1beta1.KNICluster) {
+			k.Spec.Catalog.Image.PinDigest = true
+			k.Spec.Catalog.Image.ResolveInterval = &metav1.Duration{Duration: time.Second}
+		}},
+		{"spec.catalog.image.prePullTimeout", func(k *kniv1beta1.KNICluster) {
+			k.Spec.Catalog.Image.PrePullTimeout = &metav1.Duration{Duration: time.Second}
+		}},
+		{"spec.catalog.image.prePullNodeSelector", func(k *kniv1beta1.KNICluster) {
+			k.Spec.Catalog.Image.PrePullNodeSelector = map[string]string{"node role": "worker"}
+		}},
+		{"spec.catalog.image.verification.publicKeysSecret", func(k *kniv1beta1.KNICluster) {
+			k.Spec.Catalog.Image.Verification = &kniv1beta1.SignatureVerificationSpec{}
+		}},
+		{"spec.catalog.configMap.directory", func(k *kniv1beta1.KNICluster) { k.Spec.Catalog.ConfigMap.Directory = "manifests" }},
+		{"spec.operators[1].name", func(k *kniv1beta1.KNICluster) { k.Spec.Operators = append(k.Spec.Operators, k.Spec.Operators[0]) }},
+		{"spec.mode", func(k *kniv1beta1.KNICluster) { k.Spec.Mode = "DryRun" }},
+		{"spec.approval", func(k *kniv1beta1.KNICluster) { k.Spec.Approval = "Sometimes" }},
+		{"spec.namespaceDeletionPolicy", func(k *kniv1beta1.KNICluster) { k.Spec.NamespaceDeletionPolicy = "Orphan" }},
+		{"spec.imageMirrors[0].mirror", func(k *kniv1beta1.KNICluster) {
+			k.Spec.ImageMirrors = []kniv1beta1.ImageMirror{{Source: "quay.io"}}
+		}},
+	} {
+		instance := newTestKNICluster()
+		tc.modify(instance)
+		fields := errorFields(validateKNICluster(instance))
+		found := false
+		for _, f := range fields {
+			found = found || f == tc.field
+		}
+		if !found {
+			t.Errorf("%s: got errors for %v", tc.field, fields)
+		}
+	}
+}
+
+func TestValidateKNIClusterUpdate(t *testing.T) {
+	old := newTestKNICluster()
+
+	updated := old.DeepCopy()
+	updated.Spec.Operators[0].Channel = "clusterwide-alpha"
+	updated.Spec.Operators = append(updated.Spec.Operators, kniv1beta1.OperatorSpec{Name: "vault", Package: "vault", Channel: "alpha"})
+	if errs := validateKNIClusterUpdate(updated, old); len(errs) > 0 {
+		t.Errorf("changing a channel and adding an operator is invalid: %v", errs)
+	}
+
+	// an omitted catalog namespace is the default one, which the old one is
+	updated = old.DeepCopy()
+	updated.Spec.Catalog.Namespace = ""
+	if errs := validateKNIClusterUpdate(updated, old); len(errs) > 0 {
+		t.Errorf("omitting the default catalog namespace is invalid: %v", errs)
+	}
+
+	for _, tc := range []struct {
+		field  string
+		modify func(*kniv1beta1.KNICluster)
+	}{
+		{"spec.catalog.namespace", func(k *kniv1beta1.KNICluster) { k.Spec.Catalog.Namespace = "other" }},
+		{"spec.catalog.name", func(k *kniv1beta1.KNICluster) {
+			k.Spec.Catalog.Name = "other"
+			k.Spec.Operators[0].Catalog = "other"
+		}},
+		{"spec.operators[0].package", func(k *kniv1beta1.KNICluster) { k.Spec.Operators[0].Package = "etcd-operator" }},
+	} {
+		updated := old.DeepCopy()
+		tc.modify(updated)
+		fields := errorFields(validateKNIClusterUpdate(updated, old))
+		if strings.Join(fields, ",") != tc.field {
+			t.Errorf("%s: got errors for %v", tc.field, fields)
+		}
+	}
+}
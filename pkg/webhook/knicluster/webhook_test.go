@@ -0,0 +1,269 @@
+package knicluster
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	kniv1alpha1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1alpha1"
+	kniv1beta1 "github.com/mhrivnak/kni-operator/pkg/apis/kni/v1beta1"
+	olm "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	atypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+var (
+	v1beta1Kind  = metav1.GroupVersionKind{Group: "kni.openshift.com", Version: "v1beta1", Kind: "KNICluster"}
+	v1alpha1Kind = metav1.GroupVersionKind{Group: "kni.openshift.com", Version: "v1alpha1", Kind: "KNICluster"}
+)
+
+// listClient is a client that only lists the given KNIClusters, and gets the
+// given CatalogSources
+type listClient struct {
+	client.Client
+	items          []kniv1beta1.KNICluster
+	catalogSources []olm.CatalogSource
+}
+
+func (c *listClient) List(ctx context.Context, opts *client.ListOptions, list runtime.Object) error {
+	list.(*kniv1beta1.KNIClusterList).Items = c.items
+	return nil
+}
+
+func (c *listClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	for _, catalogSource := range c.catalogSources {
+		if catalogSource.Namespace == key.Namespace && catalogSource.Name == key.Name {
+			catalogSource.DeepCopyInto(obj.(*olm.CatalogSource))
+			return nil
+		}
+	}
+	return errors.NewNotFound(olm.Resource("catalogsources"), key.Name)
+}
+
+func newRequest(t *testing.T, operation admissionv1beta1.Operation, kind metav1.GroupVersionKind, obj, old runtime.Object) atypes.Request {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &admissionv1beta1.AdmissionRequest{
+		Operation: operation,
+		Kind:      kind,
+		Object:    runtime.RawExtension{Raw: raw},
+	}
+	if old != nil {
+		req.OldObject.Raw, err = json.Marshal(old)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return atypes.Request{AdmissionRequest: req}
+}
+
+func TestValidatorCreate(t *testing.T) {
+	instance := newTestKNICluster()
+
+	otherNamespace := *newTestKNICluster()
+	otherNamespace.Namespace = "other"
+	otherNamespace.Spec.Catalog.Name = "other-catalog"
+	sameNamespace := *newTestKNICluster()
+	sameNamespace.Name = "other"
+	sameCatalog := *newTestKNICluster()
+	sameCatalog.Namespace = "other"
+	invalid := newTestKNICluster()
+	invalid.Spec.Mode = "DryRun"
+
+	for name, tc := range map[string]struct {
+		instance *kniv1beta1.KNICluster
+		existing []kniv1beta1.KNICluster
+		allowed  bool
+	}{
+		"first":                    {instance: instance, allowed: true},
+		"itself":                   {instance: instance, existing: []kniv1beta1.KNICluster{*instance}, allowed: true},
+		"other namespace":          {instance: instance, existing: []kniv1beta1.KNICluster{otherNamespace}, allowed: true},
+		"second in namespace":      {instance: instance, existing: []kniv1beta1.KNICluster{sameNamespace}},
+		"catalog managed by other": {instance: instance, existing: []kniv1beta1.KNICluster{sameCatalog}},
+		"invalid":                  {instance: invalid},
+	} {
+		v := &validator{client: &listClient{items: tc.existing}}
+		resp := v.Handle(context.TODO(), newRequest(t, admissionv1beta1.Create, v1beta1Kind, tc.instance, nil))
+		if resp.Response.Allowed != tc.allowed {
+			t.Errorf("%s: allowed is %t, want %t: %v", name, resp.Response.Allowed, tc.allowed, resp.Response.Result)
+		}
+	}
+}
+
+func TestValidatorUpdate(t *testing.T) {
+	old := newTestKNICluster()
+	updated := newTestKNICluster()
+	updated.Spec.Catalog.Namespace = "other"
+
+	v := &validator{client: &listClient{}}
+	resp := v.Handle(context.TODO(), newRequest(t, admissionv1beta1.Update, v1beta1Kind, updated, old))
+	if resp.Response.Allowed {
+		t.Error("allowed changing the catalog namespace")
+	}
+
+	// finalizer removal is never blocked
+	now := metav1.Now()
+	updated.DeletionTimestamp = &now
+	resp = v.Handle(context.TODO(), newRequest(t, admissionv1beta1.Update, v1beta1Kind, updated, old))
+	if !resp.Response.Allowed {
+		t.Errorf("blocked an update of a deleted KNICluster: %v", resp.Response.Result)
+	}
+}
+
+func TestValidatorUpdateCatalogManagedByOther(t *testing.T) {
+	instance := newTestKNICluster()
+	instance.UID = "first"
+	other := newTestKNICluster()
+	other.Namespace = "other"
+
+	// catalogSource returns the CatalogSource of instance, controlled by owner
+	// if it is set
+	catalogSource := func(owner *kniv1beta1.KNICluster, labels map[string]string) olm.CatalogSource {
+		catalogSource := olm.CatalogSource{ObjectMeta: metav1.ObjectMeta{
+			Namespace: effectiveCatalogNamespace(instance),
+			Name:      effectiveCatalogName(instance),
+			Labels:    labels,
+		}}
+		if owner != nil {
+			controller := true
+			catalogSource.OwnerReferences = []metav1.OwnerReference{{Kind: "KNICluster", Name: owner.Name, UID: owner.UID, Controller: &controller}}
+		}
+		return catalogSource
+	}
+	managedBy := func(owner *kniv1beta1.KNICluster) map[string]string {
+		return map[string]string{kniv1beta1.OwnerNameLabel: owner.Name, kniv1beta1.OwnerNamespaceLabel: owner.Namespace}
+	}
+	catalogNamespace := newTestKNICluster()
+	catalogNamespace.Namespace = effectiveCatalogNamespace(instance)
+	catalogNamespace.UID = "second"
+
+	for name, tc := range map[string]struct {
+		catalogSources []olm.CatalogSource
+		allowed        bool
+	}{
+		"not created yet":     {allowed: true},
+		"unmanaged":           {catalogSources: []olm.CatalogSource{catalogSource(nil, nil)}, allowed: true},
+		"managed by it":       {catalogSources: []olm.CatalogSource{catalogSource(nil, managedBy(instance))}, allowed: true},
+		"controlled by it":    {catalogSources: []olm.CatalogSource{catalogSource(instance, nil)}, allowed: true},
+		"managed by other":    {catalogSources: []olm.CatalogSource{catalogSource(nil, managedBy(other))}},
+		"controlled by other": {catalogSources: []olm.CatalogSource{catalogSource(catalogNamespace, nil)}},
+	} {
+		v := &validator{client: &listClient{items: []kniv1beta1.KNICluster{*instance, *other}, catalogSources: tc.catalogSources}}
+		resp := v.Handle(context.TODO(), newRequest(t, admissionv1beta1.Update, v1beta1Kind, instance, instance))
+		if resp.Response.Allowed != tc.allowed {
+			t.Errorf("%s: allowed is %t, want %t: %v", name, resp.Response.Allowed, tc.allowed, resp.Response.Result)
+		}
+	}
+}
+
+func TestValidatorV1alpha1(t *testing.T) {
+	alpha := &kniv1alpha1.KNICluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "example-knicluster", Namespace: "kniops"},
+		Spec: kniv1alpha1.KNIClusterSpec{
+			Operators: []kniv1alpha1.OperatorSpec{{Name: "Not_A_Name", Package: "etcd", Channel: "alpha"}},
+		},
+	}
+	v := &validator{client: &listClient{}}
+	resp := v.Handle(context.TODO(), newRequest(t, admissionv1beta1.Create, v1alpha1Kind, alpha, nil))
+	if resp.Response.Allowed {
+		t.Error("allowed an invalid v1alpha1 KNICluster")
+	}
+}
+
+// patchedPaths returns the paths that resp patches
+func patchedPaths(resp atypes.Response) map[string]interface{} {
+	paths := map[string]interface{}{}
+	for _, patch := range resp.Patches {
+		paths[patch.Path] = patch.Value
+	}
+	return paths
+}
+
+func TestDefaulter(t *testing.T) {
+	instance := &kniv1beta1.KNICluster{ObjectMeta: metav1.ObjectMeta{Name: "example-knicluster", Namespace: "kniops"}}
+
+	d := &defaulter{}
+	resp := d.Handle(context.TODO(), newRequest(t, admissionv1beta1.Create, v1beta1Kind, instance, nil))
+	if !resp.Response.Allowed {
+		t.Fatalf("defaulting was refused: %v", resp.Response.Result)
+	}
+	paths := patchedPaths(resp)
+	for _, path := range []string{"/spec/catalog/name", "/spec/catalog/namespace", "/spec/operators", "/spec/adoption", "/spec/namespaceDeletionPolicy"} {
+		if _, ok := paths[path]; !ok {
+			t.Errorf("%s is not defaulted, patches are %v", path, resp.Patches)
+		}
+	}
+
+	// adoption is opt-in
+	adoption, _ := json.Marshal(paths["/spec/adoption"])
+	want, _ := json.Marshal(map[string]string{
+		"subscriptions":  string(kniv1beta1.AdoptionFail),
+		"operatorGroups": string(kniv1beta1.AdoptionFail),
+		"catalogSources": string(kniv1beta1.AdoptionFail),
+	})
+	if string(adoption) != string(want) {
+		t.Errorf("adoption defaults to %s, want %s", adoption, want)
+	}
+}
+
+func TestDefaulterV1alpha1(t *testing.T) {
+	alpha := &kniv1alpha1.KNICluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "kni.openshift.com/v1alpha1", Kind: "KNICluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "example-knicluster", Namespace: "kniops"},
+	}
+
+	d := &defaulter{}
+	resp := d.Handle(context.TODO(), newRequest(t, admissionv1beta1.Create, v1alpha1Kind, alpha, nil))
+	if !resp.Response.Allowed {
+		t.Fatalf("defaulting was refused: %v", resp.Response.Result)
+	}
+	paths := patchedPaths(resp)
+	// v1alpha1 fields are defaulted in place, and the others are kept in
+	// the v1beta1 annotation
+	for _, path := range []string{"/spec/catalog/name", "/spec/catalog/imageRepository", "/spec/operators", "/metadata/annotations"} {
+		if _, ok := paths[path]; !ok {
+			t.Errorf("%s is not defaulted, patches are %v", path, resp.Patches)
+		}
+	}
+	for path := range paths {
+		if path == "/spec/adoption" || path == "/apiVersion" {
+			t.Errorf("patches %s, which is not part of v1alpha1", path)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	alpha := &kniv1alpha1.KNICluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "kni.openshift.com/v1alpha1", Kind: "KNICluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "example-knicluster", Namespace: "kniops"},
+		Spec: kniv1alpha1.KNIClusterSpec{
+			Catalog: kniv1alpha1.CatalogSpec{ImageRepository: "quay.io/mhrivnak/demo-operator-registry"},
+		},
+	}
+	raw, err := json.Marshal(alpha)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	converted, err := convert(runtime.RawExtension{Raw: raw}, "kni.openshift.com/v1beta1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	beta := &kniv1beta1.KNICluster{}
+	if err := json.Unmarshal(converted.Raw, beta); err != nil {
+		t.Fatal(err)
+	}
+	if beta.APIVersion != "kni.openshift.com/v1beta1" || beta.Spec.Catalog.Image.Repository != alpha.Spec.Catalog.ImageRepository {
+		t.Errorf("converted to %s with image repository %q", beta.APIVersion, beta.Spec.Catalog.Image.Repository)
+	}
+
+	if _, err := convert(runtime.RawExtension{Raw: raw}, "other.example.com/v1"); err == nil {
+		t.Error("converted to another group")
+	}
+}
@@ -0,0 +1,299 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/mhrivnak/kni-operator/pkg/webhook/conversion"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	webhooktypes "sigs.k8s.io/controller-runtime/pkg/webhook/types"
+)
+
+var log = logf.Log.WithName("webhook")
+
+// ServerOptions configure a webhook Server
+type ServerOptions struct {
+	// Port is the port the server listens on
+	Port int32
+	// CertDir is the directory containing tls.crt and tls.key, and optionally
+	// ca.crt, when a certificate is mounted
+	CertDir string
+	// CertSecretName is the name of the Secret, in ServiceNamespace, that
+	// keeps a self-signed certificate when none is mounted in CertDir
+	CertSecretName string
+	// ServiceName is the name of the Service that routes to the server
+	ServiceName string
+	// ServiceNamespace is the namespace of the Service that routes to the server
+	ServiceNamespace string
+	// ConfigurationName is the name of the webhook configurations that
+	// register the webhooks with the API server
+	ConfigurationName string
+}
+
+// Server serves admission webhooks over TLS and registers them with the API
+// server.
+type Server struct {
+	ServerOptions
+
+	// client is not backed by the manager's cache, so that the server does
+	// not need to watch webhook configurations.
+	client      client.Client
+	scheme      *runtime.Scheme
+	webhooks    []*admission.Webhook
+	conversions []*conversion.Webhook
+}
+
+var _ manager.Runnable = &Server{}
+
+// NewServer returns a Server that uses the Manager's config and scheme
+func NewServer(m manager.Manager, opts ServerOptions) (*Server, error) {
+	c, err := client.New(m.GetConfig(), client.Options{Scheme: m.GetScheme()})
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		ServerOptions: opts,
+		client:        c,
+		scheme:        m.GetScheme(),
+	}, nil
+}
+
+// Register validates a webhook, injects its dependencies and adds it to the
+// Server.
+func (s *Server) Register(wh *admission.Webhook) error {
+	if err := wh.Validate(); err != nil {
+		return err
+	}
+	if err := wh.InjectClient(s.client); err != nil {
+		return err
+	}
+	decoder, err := admission.NewDecoder(s.scheme)
+	if err != nil {
+		return err
+	}
+	if err := wh.InjectDecoder(decoder); err != nil {
+		return err
+	}
+	s.webhooks = append(s.webhooks, wh)
+	return nil
+}
+
+// RegisterConversion adds a conversion webhook to the Server
+func (s *Server) RegisterConversion(wh *conversion.Webhook) {
+	s.conversions = append(s.conversions, wh)
+}
+
+// Start ensures a serving certificate and the webhook configurations exist,
+// then serves the webhooks until stop is closed.
+func (s *Server) Start(stop <-chan struct{}) error {
+	serving, err := loadCertificate(s.CertDir)
+	if err != nil {
+		return err
+	}
+	if serving == nil {
+		host := fmt.Sprintf("%s.%s.svc", s.ServiceName, s.ServiceNamespace)
+		key := types.NamespacedName{Namespace: s.ServiceNamespace, Name: s.CertSecretName}
+		serving, err = ensureCertificateSecret(s.client, key, []string{host, host + ".cluster.local"})
+		if err != nil {
+			return err
+		}
+	}
+	keyPair, err := tls.X509KeyPair(serving.certPEM, serving.keyPEM)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ensureConfigurations(serving.caBundle); err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	for _, wh := range s.webhooks {
+		log.Info("Serving webhook", "Name", wh.GetName(), "Path", wh.GetPath())
+		mux.Handle(wh.GetPath(), wh.Handler())
+	}
+	for _, wh := range s.conversions {
+		log.Info("Serving conversion webhook", "CRD", wh.CRDName, "Path", wh.Path)
+		mux.Handle(wh.Path, wh)
+	}
+	srv := &http.Server{
+		Addr:      fmt.Sprintf(":%d", s.Port),
+		Handler:   mux,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{keyPair}},
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServeTLS("", "")
+	}()
+
+	select {
+	case <-stop:
+		return srv.Shutdown(context.TODO())
+	case err := <-errCh:
+		return err
+	}
+}
+
+// ensureConfigurations creates or updates the webhook configurations so that
+// the API server calls this Server.
+func (s *Server) ensureConfigurations(caBundle []byte) error {
+	var validating []admissionregistrationv1beta1.Webhook
+	var mutating []admissionregistrationv1beta1.Webhook
+	for _, wh := range s.webhooks {
+		path := wh.GetPath()
+		config := admissionregistrationv1beta1.Webhook{
+			Name:              wh.GetName(),
+			Rules:             wh.Rules,
+			FailurePolicy:     wh.FailurePolicy,
+			NamespaceSelector: wh.NamespaceSelector,
+			ClientConfig: admissionregistrationv1beta1.WebhookClientConfig{
+				Service: &admissionregistrationv1beta1.ServiceReference{
+					Name:      s.ServiceName,
+					Namespace: s.ServiceNamespace,
+					Path:      &path,
+				},
+				CABundle: caBundle,
+			},
+		}
+		switch wh.GetType() {
+		case webhooktypes.WebhookTypeValidating:
+			validating = append(validating, config)
+		case webhooktypes.WebhookTypeMutating:
+			mutating = append(mutating, config)
+		}
+	}
+
+	if len(validating) > 0 {
+		if err := s.ensureValidatingConfiguration(validating); err != nil {
+			return err
+		}
+	}
+	if len(mutating) > 0 {
+		if err := s.ensureMutatingConfiguration(mutating); err != nil {
+			return err
+		}
+	}
+	for _, wh := range s.conversions {
+		if err := s.ensureConversionCABundle(wh, caBundle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) ensureValidatingConfiguration(webhooks []admissionregistrationv1beta1.Webhook) error {
+	found := &admissionregistrationv1beta1.ValidatingWebhookConfiguration{}
+	err := s.client.Get(context.TODO(), types.NamespacedName{Name: s.ConfigurationName}, found)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new ValidatingWebhookConfiguration", "Name", s.ConfigurationName)
+		return s.client.Create(context.TODO(), &admissionregistrationv1beta1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: s.ConfigurationName},
+			Webhooks:   webhooks,
+		})
+	} else if err != nil {
+		return err
+	}
+
+	keepCABundles(found.Webhooks, webhooks)
+	if reflect.DeepEqual(found.Webhooks, webhooks) {
+		return nil
+	}
+	log.Info("Updating the ValidatingWebhookConfiguration", "Name", s.ConfigurationName)
+	found.Webhooks = webhooks
+	return s.client.Update(context.TODO(), found)
+}
+
+func (s *Server) ensureMutatingConfiguration(webhooks []admissionregistrationv1beta1.Webhook) error {
+	found := &admissionregistrationv1beta1.MutatingWebhookConfiguration{}
+	err := s.client.Get(context.TODO(), types.NamespacedName{Name: s.ConfigurationName}, found)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new MutatingWebhookConfiguration", "Name", s.ConfigurationName)
+		return s.client.Create(context.TODO(), &admissionregistrationv1beta1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: s.ConfigurationName},
+			Webhooks:   webhooks,
+		})
+	} else if err != nil {
+		return err
+	}
+
+	keepCABundles(found.Webhooks, webhooks)
+	if reflect.DeepEqual(found.Webhooks, webhooks) {
+		return nil
+	}
+	log.Info("Updating the MutatingWebhookConfiguration", "Name", s.ConfigurationName)
+	found.Webhooks = webhooks
+	return s.client.Update(context.TODO(), found)
+}
+
+// ensureConversionCABundle sets the CA bundle of the conversion webhook of a
+// CustomResourceDefinition, when the CRD as shipped points it at this Server.
+// The conversion strategy itself is left as shipped. The CRD is handled as
+// unstructured, since the vendored type predates fields such as
+// preserveUnknownFields, which a typed update would drop.
+func (s *Server) ensureConversionCABundle(wh *conversion.Webhook, caBundle []byte) error {
+	if len(caBundle) == 0 {
+		// managed by whoever provided the certificate
+		return nil
+	}
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(apiextensionsv1beta1.SchemeGroupVersion.WithKind("CustomResourceDefinition"))
+	err := s.client.Get(context.TODO(), types.NamespacedName{Name: wh.CRDName}, crd)
+	if err != nil {
+		return err
+	}
+	found, _, _ := unstructured.NestedMap(crd.Object, "spec", "conversion")
+	existing := &apiextensionsv1beta1.CustomResourceConversion{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(found, existing); err != nil {
+		return err
+	}
+
+	config := existing.WebhookClientConfig
+	if existing.Strategy != apiextensionsv1beta1.WebhookConverter || config == nil || config.Service == nil ||
+		config.Service.Name != s.ServiceName || config.Service.Namespace != s.ServiceNamespace ||
+		config.Service.Path == nil || *config.Service.Path != wh.Path {
+		log.Info("The conversion webhook of the CustomResourceDefinition is not this operator's, so KNIClusters cannot be converted by it",
+			"Name", wh.CRDName, "Service.Namespace", s.ServiceNamespace, "Service.Name", s.ServiceName, "Path", wh.Path)
+		return nil
+	}
+	if bytes.Equal(config.CABundle, caBundle) {
+		return nil
+	}
+
+	log.Info("Updating the CA bundle of the conversion webhook of CustomResourceDefinition", "Name", wh.CRDName)
+	if err := unstructured.SetNestedField(crd.Object, base64.StdEncoding.EncodeToString(caBundle),
+		"spec", "conversion", "webhookClientConfig", "caBundle"); err != nil {
+		return err
+	}
+	return s.client.Update(context.TODO(), crd)
+}
+
+// keepCABundles copies the CA bundle of existing webhooks to desired webhooks
+// that have none, so that bundles injected by someone else are preserved.
+func keepCABundles(existing, desired []admissionregistrationv1beta1.Webhook) {
+	for i := range desired {
+		if len(desired[i].ClientConfig.CABundle) > 0 {
+			continue
+		}
+		for _, wh := range existing {
+			if wh.Name == desired[i].Name {
+				desired[i].ClientConfig.CABundle = wh.ClientConfig.CABundle
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"testing"
+
+	"github.com/mhrivnak/kni-operator/pkg/webhook/conversion"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// shippedCRD returns the KNICluster CRD as shipped in deploy/crds
+func shippedCRD(t *testing.T) *unstructured.Unstructured {
+	t.Helper()
+	raw, err := ioutil.ReadFile("../../deploy/crds/kni_v1beta1_knicluster_crd.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	crd := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(raw, &crd.Object); err != nil {
+		t.Fatal(err)
+	}
+	return crd
+}
+
+// newTestServer returns a Server deployed as in deploy/operator.yaml, whose
+// client holds the shipped CRD
+func newTestServer(t *testing.T) (*Server, *objectClient) {
+	c := newObjectClient()
+	c.crd = shippedCRD(t)
+	s := &Server{
+		ServerOptions: ServerOptions{ServiceName: "kni-operator-webhook", ServiceNamespace: "kniops"},
+		client:        c,
+	}
+	return s, c
+}
+
+var testConversion = &conversion.Webhook{CRDName: "kniclusters.kni.openshift.com", Path: "/convert-kniclusters"}
+
+func TestEnsureConversionCABundle(t *testing.T) {
+	s, c := newTestServer(t)
+	caBundle := []byte("test CA bundle")
+
+	if err := s.ensureConversionCABundle(testConversion, caBundle); err != nil {
+		t.Fatal(err)
+	}
+
+	conversion, _, _ := unstructured.NestedMap(c.crd.Object, "spec", "conversion")
+	want, _, _ := unstructured.NestedMap(shippedCRD(t).Object, "spec", "conversion")
+	encoded, _, _ := unstructured.NestedString(conversion, "webhookClientConfig", "caBundle")
+	if decoded, err := base64.StdEncoding.DecodeString(encoded); err != nil || !bytes.Equal(decoded, caBundle) {
+		t.Errorf("CA bundle is %q, want the serving CA", encoded)
+	}
+	unstructured.RemoveNestedField(conversion, "webhookClientConfig", "caBundle")
+	if !equalMaps(conversion, want) {
+		t.Errorf("conversion is %v, want it as shipped: %v", conversion, want)
+	}
+
+	// the same bundle is not written again
+	if err := s.ensureConversionCABundle(testConversion, caBundle); err != nil {
+		t.Fatal(err)
+	}
+	if c.writes != 1 {
+		t.Errorf("made %d writes, want the CRD updated once", c.writes)
+	}
+}
+
+func TestEnsureConversionCABundleLeavesOtherWebhooks(t *testing.T) {
+	for name, tc := range map[string]struct {
+		caBundle  []byte
+		namespace string
+	}{
+		"deployed elsewhere": {caBundle: []byte("test CA bundle"), namespace: "other"},
+		"provided bundle":    {namespace: "kniops"},
+	} {
+		s, c := newTestServer(t)
+		s.ServiceNamespace = tc.namespace
+
+		if err := s.ensureConversionCABundle(testConversion, tc.caBundle); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if c.writes != 0 {
+			t.Errorf("%s: updated the CRD", name)
+		}
+	}
+}
+
+// equalMaps returns whether a and b hold the same JSON
+func equalMaps(a, b map[string]interface{}) bool {
+	rawA, errA := yaml.Marshal(a)
+	rawB, errB := yaml.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(rawA, rawB)
+}
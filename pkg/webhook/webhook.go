@@ -0,0 +1,38 @@
+package webhook
+
+import (
+	"github.com/mhrivnak/kni-operator/pkg/webhook/conversion"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// WebhookFuncs is a list of functions that create the admission webhooks served by the operator
+var WebhookFuncs []func(manager.Manager) (*admission.Webhook, error)
+
+// ConversionFuncs is a list of functions that create the conversion webhooks served by the operator
+var ConversionFuncs []func(manager.Manager) (*conversion.Webhook, error)
+
+// AddToManager creates a Server for all webhooks and adds it to the Manager
+func AddToManager(m manager.Manager, opts ServerOptions) error {
+	s, err := NewServer(m, opts)
+	if err != nil {
+		return err
+	}
+	for _, f := range WebhookFuncs {
+		wh, err := f(m)
+		if err != nil {
+			return err
+		}
+		if err := s.Register(wh); err != nil {
+			return err
+		}
+	}
+	for _, f := range ConversionFuncs {
+		wh, err := f(m)
+		if err != nil {
+			return err
+		}
+		s.RegisterConversion(wh)
+	}
+	return m.Add(s)
+}